@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uselagoon/build-deploy-tool/internal/apply"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+var applyLagoonServices = &cobra.Command{
+	Use:     "lagoon-services",
+	Aliases: []string{"ls"},
+	Short:   "Server-side apply the lagoon service resources for a Lagoon build",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		gen, err := generator.GenerateInput(*rootCmd, true)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return err
+		}
+		gen.ImageReferences = imageRefs.Images
+		restCfg, err := lagoon.GetConfig()
+		if err != nil {
+			return fmt.Errorf("unable to get kubernetes config: %v", err)
+		}
+		client, err := lagoon.GetK8sClient(restCfg)
+		if err != nil {
+			return fmt.Errorf("unable to create kubernetes client: %v", err)
+		}
+		return LagoonServiceApply(gen, client)
+	},
+}
+
+// LagoonServiceApply generates the lagoon service resources and server-side applies them into the environment namespace
+func LagoonServiceApply(g generator.GeneratorInput, client kubernetes.Interface) error {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return err
+	}
+	templates, err := generateLagoonServiceObjects(lagoonBuild.BuildValues)
+	if err != nil {
+		return err
+	}
+	objects := []runtime.Object{}
+	for _, tpl := range templates {
+		objects = append(objects, tpl.object)
+	}
+	ctx := context.TODO()
+	if err := checkStatefulSetVolumes(ctx, client, lagoonBuild.BuildValues.Namespace, objects); err != nil {
+		return err
+	}
+	if err := removeReplacedDeployments(ctx, client, lagoonBuild.BuildValues.Namespace, objects); err != nil {
+		return err
+	}
+	results, err := apply.NewApplier(client, lagoonBuild.BuildValues.Namespace).Apply(ctx, objects...)
+	// print the results of anything that was applied, even if there was an error part way through
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	return err
+}
+
+// checkStatefulSetVolumes refuses to apply a statefulset with volume claim templates while the persistent volume claim the
+// deployment of the service used still exists. the volume claim template would create a new empty volume for the statefulset,
+// so the data of an existing environment would be left behind in the old volume
+func checkStatefulSetVolumes(ctx context.Context, client kubernetes.Interface, namespace string, objects []runtime.Object) error {
+	for _, obj := range objects {
+		statefulset, ok := obj.(*appsv1.StatefulSet)
+		if !ok || len(statefulset.Spec.VolumeClaimTemplates) == 0 {
+			continue
+		}
+		_, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, statefulset.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to check for an existing persistent volume claim of %s: %v", statefulset.Name, err)
+		}
+		return fmt.Errorf("the persistent volume claim %s of service %s already exists, the statefulset would not use it. set LAGOON_FEATURE_FLAG_STATEFULSETS to migrate for existing environments", statefulset.Name, statefulset.Name)
+	}
+	return nil
+}
+
+// removeReplacedDeployments removes the deployment of any service that is now templated as a statefulset. the deployment
+// has to be removed before the statefulset is applied, as when migrating they both mount the same persistent volume claim
+// and the statefulset pods can't start while the deployment pods hold the volume
+func removeReplacedDeployments(ctx context.Context, client kubernetes.Interface, namespace string, objects []runtime.Object) error {
+	propagation := metav1.DeletePropagationForeground
+	for _, obj := range objects {
+		statefulset, ok := obj.(*appsv1.StatefulSet)
+		if !ok {
+			continue
+		}
+		_, err := client.AppsV1().Deployments(namespace).Get(ctx, statefulset.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to check for an existing deployment of %s: %v", statefulset.Name, err)
+		}
+		fmt.Printf(">> Removing deployment '%s', it is replaced by a statefulset\n", statefulset.Name)
+		err = client.AppsV1().Deployments(namespace).Delete(ctx, statefulset.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("unable to remove deployment %s: %v", statefulset.Name, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	applyCmd.AddCommand(applyLagoonServices)
+}
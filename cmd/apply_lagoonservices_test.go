@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/apply/applytest"
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestLagoonServiceApply(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             testdata.TestData
+		namespace        string
+		existing         []runtime.Object
+		wantDeployments  []string
+		wantStatefulSets []string
+		wantServices     []string
+		wantPVCs         []string
+		wantCronjobs     []string
+		wantErr          bool
+	}{
+		{
+			name: "test1 nginx-php with varnish and redis",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+					ImageReferences: map[string]string{
+						"nginx":   "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":     "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"cli":     "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis":   "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"varnish": "harbor.example/example-project/main/varnish@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			namespace:        "example-project-main",
+			wantDeployments:  []string{"cli", "nginx-php", "redis", "varnish"},
+			wantStatefulSets: []string{},
+			wantServices:     []string{"nginx-php", "redis", "varnish"},
+			wantPVCs:         []string{"nginx-php"},
+			wantCronjobs:     []string{"cronjob-cli-drush-cron2"},
+		},
+		{
+			name: "test2 services migrating from deployments to statefulsets",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.services.yml",
+					ImageReferences: map[string]string{
+						"web":          "harbor.example/example-project/main/web@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"mariadb-10-5": "harbor.example/example-project/main/mariadb-10-5@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"postgres-11":  "harbor.example/example-project/main/postgres-11@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"opensearch-2": "harbor.example/example-project/main/opensearch-2@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-6":      "harbor.example/example-project/main/redis-6@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-7":      "harbor.example/example-project/main/redis-7@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"solr-8":       "harbor.example/example-project/main/solr-8@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_STATEFULSETS",
+							Value: "migrate",
+							Scope: "build",
+						},
+					},
+				}, true),
+			namespace: "example-project-main",
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "mariadb-10-5", Namespace: "example-project-main"}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "solr-8", Namespace: "example-project-main"}},
+				&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "mariadb-10-5", Namespace: "example-project-main"}},
+			},
+			wantDeployments:  []string{"redis-6", "redis-7", "web"},
+			wantStatefulSets: []string{"mariadb-10-5", "opensearch-2", "postgres-11", "solr-8"},
+			wantServices: []string{
+				"mariadb-10-5", "mariadb-10-5-headless", "opensearch-2", "opensearch-2-headless", "postgres-11", "postgres-11-headless",
+				"redis-6", "redis-7", "solr-8", "solr-8-headless", "web",
+			},
+			wantPVCs:     []string{"mariadb-10-5", "opensearch-2", "postgres-11", "solr-8", "web"},
+			wantCronjobs: []string{},
+		},
+		{
+			name: "test3 statefulsets enabled with the persistent volume claim of an existing deployment",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.services.yml",
+					ImageReferences: map[string]string{
+						"web":          "harbor.example/example-project/main/web@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"mariadb-10-5": "harbor.example/example-project/main/mariadb-10-5@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"postgres-11":  "harbor.example/example-project/main/postgres-11@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"opensearch-2": "harbor.example/example-project/main/opensearch-2@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-6":      "harbor.example/example-project/main/redis-6@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-7":      "harbor.example/example-project/main/redis-7@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"solr-8":       "harbor.example/example-project/main/solr-8@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_STATEFULSETS",
+							Value: "enabled",
+							Scope: "build",
+						},
+					},
+				}, true),
+			namespace: "example-project-main",
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "mariadb-10-5", Namespace: "example-project-main"}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "solr-8", Namespace: "example-project-main"}},
+				&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "mariadb-10-5", Namespace: "example-project-main"}},
+			},
+			wantDeployments:  []string{"mariadb-10-5", "solr-8"},
+			wantStatefulSets: []string{},
+			wantServices:     []string{},
+			wantPVCs:         []string{"mariadb-10-5"},
+			wantCronjobs:     []string{},
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			client := applytest.NewFakeClient(tt.existing...)
+			// apply twice, the second apply should leave everything unchanged
+			for i := 0; i < 2; i++ {
+				if err := LagoonServiceApply(generator, client); (err != nil) != tt.wantErr {
+					t.Errorf("LagoonServiceApply() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+			}
+
+			ctx := context.Background()
+			deployments, err := client.AppsV1().Deployments(tt.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			names := []string{}
+			for _, d := range deployments.Items {
+				names = append(names, d.Name)
+			}
+			checkNames(t, "deployments", names, tt.wantDeployments)
+			statefulsets, err := client.AppsV1().StatefulSets(tt.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			names = []string{}
+			for _, s := range statefulsets.Items {
+				names = append(names, s.Name)
+			}
+			checkNames(t, "statefulsets", names, tt.wantStatefulSets)
+			services, err := client.CoreV1().Services(tt.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			names = []string{}
+			for _, d := range services.Items {
+				names = append(names, d.Name)
+			}
+			checkNames(t, "services", names, tt.wantServices)
+			pvcs, err := client.CoreV1().PersistentVolumeClaims(tt.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			names = []string{}
+			for _, d := range pvcs.Items {
+				names = append(names, d.Name)
+			}
+			checkNames(t, "pvcs", names, tt.wantPVCs)
+			cronjobs, err := client.BatchV1().CronJobs(tt.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			names = []string{}
+			for _, d := range cronjobs.Items {
+				names = append(names, d.Name)
+			}
+			checkNames(t, "cronjobs", names, tt.wantCronjobs)
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
+
+func checkNames(t *testing.T, kind string, got, want []string) {
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", kind, got, want)
+	}
+}
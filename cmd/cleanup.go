@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uselagoon/build-deploy-tool/internal/cleanup"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	dbaasTemplater "github.com/uselagoon/build-deploy-tool/internal/templating/dbaas"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:     "cleanup",
+	Aliases: []string{"clean"},
+	Short:   "Remove resources that are no longer templated by a Lagoon build",
+	Long: `Remove any deployments, statefulsets, services, ingress, cronjobs, and dbaas consumers that are managed by the
+build-deploy-tool but are no longer templated by the build.
+Persistent volume claims hold the data of a service, so they are only reported unless --remove-volumes is set`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("error reading dry-run flag: %v", err)
+		}
+		removeVolumes, err := cmd.Flags().GetBool("remove-volumes")
+		if err != nil {
+			return fmt.Errorf("error reading remove-volumes flag: %v", err)
+		}
+		gen, err := generator.GenerateInput(*rootCmd, true)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return err
+		}
+		gen.ImageReferences = imageRefs.Images
+		restCfg, err := lagoon.GetConfig()
+		if err != nil {
+			return fmt.Errorf("unable to get kubernetes config: %v", err)
+		}
+		client, err := lagoon.GetK8sClient(restCfg)
+		if err != nil {
+			return fmt.Errorf("unable to create kubernetes client: %v", err)
+		}
+		dynamicClient, err := dynamic.NewForConfig(restCfg)
+		if err != nil {
+			return fmt.Errorf("unable to create kubernetes client: %v", err)
+		}
+		_, err = CleanupResources(gen, client, dynamicClient, dryRun, removeVolumes)
+		return err
+	},
+}
+
+// CleanupResources removes any resources managed by the build-deploy-tool that the build no longer templates,
+// if dryRun is set, the resources that would be removed are only reported.
+// persistent volume claims are only removed if removeVolumes is set, a renamed service would otherwise lose its data
+func CleanupResources(
+	g generator.GeneratorInput,
+	client kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	dryRun, removeVolumes bool,
+) ([]cleanup.Object, error) {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := expectedResources(lagoonBuild)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.TODO()
+	cleaner := cleanup.NewCleaner(client, dynamicClient, lagoonBuild.BuildValues.Namespace)
+	stale, err := cleaner.FindStale(ctx, expected)
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) == 0 {
+		fmt.Println("No resource cleanup required")
+		return stale, nil
+	}
+	for _, obj := range stale {
+		if obj.Kind == cleanup.PersistentVolumeClaim && !removeVolumes {
+			fmt.Printf("> The %s '%s' is no longer used, it is kept to prevent data loss, use --remove-volumes to remove it\n", strings.ToLower(obj.Kind), obj.Name)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("> The %s '%s' would be removed\n", strings.ToLower(obj.Kind), obj.Name)
+			continue
+		}
+		fmt.Printf(">> Removing %s '%s'\n", strings.ToLower(obj.Kind), obj.Name)
+		if err := cleaner.Delete(ctx, obj); err != nil {
+			return stale, err
+		}
+	}
+	return stale, nil
+}
+
+// expectedResources collects the names of all the resources the build templates
+func expectedResources(lagoonBuild *generator.Generator) (cleanup.Expected, error) {
+	expected := cleanup.Expected{}
+	templates, err := generateLagoonServiceObjects(lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, err
+	}
+	for _, tpl := range templates {
+		accessor, err := meta.Accessor(tpl.object)
+		if err != nil {
+			return nil, err
+		}
+		expected.Add(tpl.object.GetObjectKind().GroupVersionKind().Kind, accessor.GetName())
+		// a service that moved from a deployment to a statefulset still has the persistent volume claim of the deployment,
+		// it has the same name as the volume claim template and holds the data of the service, so it is never stale
+		if statefulset, ok := tpl.object.(*appsv1.StatefulSet); ok {
+			for _, vct := range statefulset.Spec.VolumeClaimTemplates {
+				expected.Add(cleanup.PersistentVolumeClaim, vct.Name)
+			}
+		}
+	}
+	for _, route := range lagoonBuild.AutogeneratedRoutes.Routes {
+		expected.Add(cleanup.Ingress, route.IngressName)
+	}
+	for _, route := range lagoonBuild.MainRoutes.Routes {
+		expected.Add(cleanup.Ingress, route.IngressName)
+	}
+	if *lagoonBuild.ActiveEnvironment || *lagoonBuild.StandbyEnvironment {
+		for _, route := range lagoonBuild.ActiveStandbyRoutes.Routes {
+			expected.Add(cleanup.Ingress, route.IngressName)
+		}
+	}
+	dbaasYAML, err := dbaasTemplater.GenerateDBaaSTemplate(*lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	consumers, err := decodeManifestObjects(bytes.NewReader(dbaasYAML), "dbaas consumers")
+	if err != nil {
+		return nil, err
+	}
+	for _, consumer := range consumers {
+		expected.Add(consumer.Kind, consumer.Metadata.Name)
+	}
+	return expected, nil
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	cleanupCmd.Flags().Bool("dry-run", false, "Only report the resources that would be removed")
+	cleanupCmd.Flags().Bool("remove-volumes", false, "Also remove persistent volume claims that are no longer used, the data in them is lost")
+}
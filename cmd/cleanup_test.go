@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/cleanup"
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestCleanupResources(t *testing.T) {
+	namespace := "example-project-main"
+	managed := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "build-deploy-tool",
+			},
+		}
+	}
+	consumer := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("mariadb.amazee.io/v1")
+		u.SetKind(cleanup.MariaDBConsumer)
+		u.SetName(name)
+		u.SetNamespace(namespace)
+		u.SetLabels(map[string]string{
+			"app.kubernetes.io/managed-by": "build-deploy-tool",
+		})
+		return u
+	}
+	tests := []struct {
+		name          string
+		args          testdata.TestData
+		dryRun        bool
+		removeVolumes bool
+		existing      []runtime.Object
+		consumers     []runtime.Object
+		want          []cleanup.Object
+		wantRemained  bool
+		wantErr       bool
+	}{
+		{
+			name: "test1 nginx-php with mariadb-dbaas dry run",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			dryRun: true,
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: managed("cli")},
+				&appsv1.Deployment{ObjectMeta: managed("solr")},
+				&networkv1.Ingress{ObjectMeta: managed("old.example.com")},
+			},
+			consumers: []runtime.Object{
+				consumer("mariadb"),
+				consumer("mariadb-old"),
+			},
+			want: []cleanup.Object{
+				{Kind: cleanup.Deployment, Name: "solr"},
+				{Kind: cleanup.Ingress, Name: "old.example.com"},
+				{Kind: cleanup.MariaDBConsumer, Name: "mariadb-old"},
+			},
+			wantRemained: true,
+		},
+		{
+			name: "test2 nginx-php with mariadb-dbaas",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: managed("cli")},
+				&appsv1.Deployment{ObjectMeta: managed("solr")},
+				&networkv1.Ingress{ObjectMeta: managed("old.example.com")},
+			},
+			consumers: []runtime.Object{
+				consumer("mariadb"),
+				consumer("mariadb-old"),
+			},
+			want: []cleanup.Object{
+				{Kind: cleanup.Deployment, Name: "solr"},
+				{Kind: cleanup.Ingress, Name: "old.example.com"},
+				{Kind: cleanup.MariaDBConsumer, Name: "mariadb-old"},
+			},
+		},
+		{
+			name: "test3 nginx-php with a volume that is no longer used is kept",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			existing: []runtime.Object{
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("nginx-php")},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("nginx-old")},
+			},
+			want: []cleanup.Object{
+				{Kind: cleanup.PersistentVolumeClaim, Name: "nginx-old"},
+			},
+			wantRemained: true,
+		},
+		{
+			name: "test4 nginx-php with a volume that is no longer used is removed",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			removeVolumes: true,
+			existing: []runtime.Object{
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("nginx-php")},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("nginx-old")},
+			},
+			want: []cleanup.Object{
+				{Kind: cleanup.PersistentVolumeClaim, Name: "nginx-old"},
+			},
+		},
+		{
+			name: "test5 services moved to statefulsets keep the volumes of their deployments",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.services.yml",
+					ImageReferences: map[string]string{
+						"web":          "harbor.example/example-project/main/web@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"mariadb-10-5": "harbor.example/example-project/main/mariadb-10-5@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"postgres-11":  "harbor.example/example-project/main/postgres-11@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"opensearch-2": "harbor.example/example-project/main/opensearch-2@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-6":      "harbor.example/example-project/main/redis-6@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-7":      "harbor.example/example-project/main/redis-7@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"solr-8":       "harbor.example/example-project/main/solr-8@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_STATEFULSETS",
+							Value: "enabled",
+							Scope: "build",
+						},
+					},
+				}, true),
+			removeVolumes: true,
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: managed("mariadb-10-5")},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("mariadb-10-5")},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("web")},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managed("old-data")},
+			},
+			want: []cleanup.Object{
+				{Kind: cleanup.Deployment, Name: "mariadb-10-5"},
+				{Kind: cleanup.PersistentVolumeClaim, Name: "old-data"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			client := fake.NewSimpleClientset(tt.existing...)
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{
+					{Group: "mariadb.amazee.io", Version: "v1", Resource: "mariadbconsumers"}:     "MariaDBConsumerList",
+					{Group: "mongodb.amazee.io", Version: "v1", Resource: "mongodbconsumers"}:     "MongoDBConsumerList",
+					{Group: "postgres.amazee.io", Version: "v1", Resource: "postgresqlconsumers"}: "PostgreSQLConsumerList",
+				}, tt.consumers...)
+
+			got, err := CleanupResources(generator, client, dynamicClient, tt.dryRun, tt.removeVolumes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CleanupResources() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CleanupResources() = %v, want %v", got, tt.want)
+			}
+
+			// a dry run afterwards should find the same resources, or nothing once they have been removed
+			remaining, err := CleanupResources(generator, client, dynamicClient, true, tt.removeVolumes)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			if (len(remaining) != 0) != tt.wantRemained {
+				t.Errorf("remaining stale resources = %v, wantRemained %v", remaining, tt.wantRemained)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/plandiff"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the differences between the resources templated by two Lagoon build inputs",
+	Long: `Run the generator for a base and a target set of inputs and show the differences between all the resources
+they template, grouped by object and field. The base can be another .lagoon.yml file, or another directory such as a
+git worktree of a different commit, which is read using the same .lagoon.yml file name`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		baseLagoonYAML, err := cmd.Flags().GetString("base-lagoon-yml")
+		if err != nil {
+			return fmt.Errorf("error reading base-lagoon-yml flag: %v", err)
+		}
+		baseDir, err := cmd.Flags().GetString("base-dir")
+		if err != nil {
+			return fmt.Errorf("error reading base-dir flag: %v", err)
+		}
+		targetDir, err := cmd.Flags().GetString("target-dir")
+		if err != nil {
+			return fmt.Errorf("error reading target-dir flag: %v", err)
+		}
+		format, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return fmt.Errorf("error reading output-format flag: %v", err)
+		}
+		k8upVersion, err := cmd.Flags().GetString("version")
+		if err != nil {
+			return fmt.Errorf("error reading version flag: %v", err)
+		}
+		target, err := generator.GenerateInput(*rootCmd, false)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		if images != "" {
+			imageRefs, err := loadImagesFromFile(images)
+			if err != nil {
+				return err
+			}
+			target.ImageReferences = imageRefs.Images
+		}
+		target.BackupConfiguration.K8upVersion = k8upVersion
+		base := target
+		if baseLagoonYAML != "" {
+			base.LagoonYAML = baseLagoonYAML
+		}
+		if baseLagoonYAML == "" && baseDir == targetDir {
+			return fmt.Errorf("the base must be a different .lagoon.yml file or directory to the target")
+		}
+		diffs, err := BuildPlanDiff(base, target, baseDir, targetDir)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "json":
+			out, err := json.MarshalIndent(diffs, "", "  ")
+			if err != nil {
+				return fmt.Errorf("couldn't marshal output: %v", err)
+			}
+			fmt.Println(string(out))
+		case "text":
+			fmt.Print(plandiff.Format(diffs))
+		default:
+			return fmt.Errorf("unsupported output format %s, must be text or json", format)
+		}
+		return nil
+	},
+}
+
+// BuildPlanDiff runs the generator for the base and target inputs and returns the differences between the resources
+// each of them templates. The generator is run from the provided directories, or the current directory if they are empty
+func BuildPlanDiff(base, target generator.GeneratorInput, baseDir, targetDir string) ([]plandiff.ObjectDiff, error) {
+	baseObjects, err := buildPlanObjects(base, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate the base build plan: %v", err)
+	}
+	targetObjects, err := buildPlanObjects(target, targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate the target build plan: %v", err)
+	}
+	return plandiff.Diff(baseObjects, targetObjects), nil
+}
+
+// buildPlanObjects templates everything for a build into a temporary directory and reads back all the objects
+func buildPlanObjects(g generator.GeneratorInput, dir string) ([]plandiff.Object, error) {
+	savedTemplates, err := os.MkdirTemp("", "lagoon-build-plan-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(savedTemplates)
+	if dir != "" {
+		// the paths in the .lagoon.yml file are relative to the directory the build is run from
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chdir(dir); err != nil {
+			return nil, err
+		}
+		defer os.Chdir(cwd)
+	}
+	g.SavedTemplatesPath = savedTemplates
+	g.Debug = false
+	// both builds are templated without the dbaas credentials, so they don't show up as a difference
+	if err := TemplateAllGeneration(g, nil); err != nil {
+		return nil, err
+	}
+	objects := []plandiff.Object{}
+	err = filepath.WalkDir(savedTemplates, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".yaml" || d.Name() == manifestIndexFile {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("couldn't read file %v: %v", path, err)
+		}
+		defer f.Close()
+		fileObjects, err := plandiff.ReadObjects(f, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().String("base-lagoon-yml", "", "The .lagoon.yml file to use for the base, defaults to the same file as the target")
+	diffCmd.Flags().String("base-dir", "", "The directory to run the base from, defaults to the current directory")
+	diffCmd.Flags().String("target-dir", "", "The directory to run the target from, defaults to the current directory")
+	diffCmd.Flags().StringP("output-format", "o", "text", "The output format, text or json")
+	diffCmd.Flags().StringP("version", "", "v1", "The version of k8up used.")
+}
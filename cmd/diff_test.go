@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/plandiff"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestBuildPlanDiff(t *testing.T) {
+	images := map[string]string{
+		"nginx":   "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+		"php":     "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+		"cli":     "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+		"redis":   "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+		"varnish": "harbor.example/example-project/main/varnish@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+	}
+	tests := []struct {
+		name           string
+		args           testdata.TestData
+		baseLagoonYAML string
+		want           []plandiff.ObjectDiff
+		wantErr        bool
+	}{
+		{
+			name: "test1 no changes",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+					ImageReferences: images,
+				}, true),
+			baseLagoonYAML: "internal/testdata/complex/lagoon.varnish.yml",
+			want:           []plandiff.ObjectDiff{},
+		},
+		{
+			name: "test2 changed cronjob schedule and added route",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.varnish-changed.yml",
+					ImageReferences: images,
+				}, true),
+			baseLagoonYAML: "internal/testdata/complex/lagoon.varnish.yml",
+			want: []plandiff.ObjectDiff{
+				{
+					Kind:   "ConfigMap",
+					Name:   "lagoon-env",
+					Change: plandiff.Changed,
+				},
+				{
+					Kind:   "CronJob",
+					Name:   "cronjob-cli-drush-cron2",
+					Change: plandiff.Changed,
+				},
+				{
+					Kind:   "Ingress",
+					Name:   "www.example.com",
+					Change: plandiff.Added,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			target, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			base := target
+			base.LagoonYAML = tt.baseLagoonYAML
+
+			got, err := BuildPlanDiff(base, target, "", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BuildPlanDiff() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			// only the objects that changed are compared here, the field changes are tested in the plandiff package
+			for idx := range got {
+				got[idx].Fields = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildPlanDiff() = %v, want %v", got, tt.want)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"sigs.k8s.io/yaml"
+)
+
+var buildValuesIdentify = &cobra.Command{
+	Use:     "build-values",
+	Aliases: []string{"bv"},
+	Short:   "Identify the computed build values for a Lagoon build",
+	Long: `Output the fully computed build values for a Lagoon build with any secrets redacted,
+or the JSON Schema that describes the build values`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return fmt.Errorf("error reading output-format flag: %v", err)
+		}
+		schema, err := cmd.Flags().GetBool("schema")
+		if err != nil {
+			return fmt.Errorf("error reading schema flag: %v", err)
+		}
+		if schema {
+			out, err := BuildValuesSchemaIdentification(format)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		gen, err := generator.GenerateInput(*rootCmd, false)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		// the image references are only known after the image build phase, but the rest of the values are still useful without them
+		if images != "" {
+			imageRefs, err := loadImagesFromFile(images)
+			if err != nil {
+				return err
+			}
+			gen.ImageReferences = imageRefs.Images
+		}
+		out, err := BuildValuesIdentification(gen, format)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	},
+}
+
+// BuildValuesIdentification runs the generator and returns the computed build values with any secrets redacted
+func BuildValuesIdentification(g generator.GeneratorInput, format string) ([]byte, error) {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return marshalOutput(lagoonBuild.BuildValues.Redacted(), format)
+}
+
+// BuildValuesSchemaIdentification returns the JSON Schema of the build values
+func BuildValuesSchemaIdentification(format string) ([]byte, error) {
+	return marshalOutput(generator.BuildValuesSchema(), format)
+}
+
+func marshalOutput(v interface{}, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal output: %v", err)
+		}
+		return out, nil
+	case "yaml":
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal output: %v", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %s, must be json or yaml", format)
+}
+
+func init() {
+	identifyCmd.AddCommand(buildValuesIdentify)
+	buildValuesIdentify.Flags().StringP("output-format", "o", "json", "The output format, json or yaml")
+	buildValuesIdentify.Flags().Bool("schema", false, "Output the JSON Schema of the build values instead of the values")
+}
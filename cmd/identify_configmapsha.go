@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"k8s.io/client-go/dynamic"
+)
+
+var configMapShaIdentify = &cobra.Command{
+	Use:     "configmap-sha",
+	Aliases: []string{"cms"},
+	Short:   "Identify the sha of the lagoon-env configmap for a Lagoon build",
+	Long: `Identify the sha of the lagoon-env configmap for a Lagoon build, this is the value that is used as CONFIG_MAP_SHA
+to trigger a rollout of the services if only the configmap has changed`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		gen, err := generator.GenerateInput(*rootCmd, false)
+		if err != nil {
+			return err
+		}
+		dbaasClient, err := dbaasVariablesClient(cmd)
+		if err != nil {
+			return err
+		}
+		sha, err := IdentifyConfigMapSha(gen, dbaasClient)
+		if err != nil {
+			return err
+		}
+		fmt.Println(sha)
+		return nil
+	},
+}
+
+// IdentifyConfigMapSha calculates the sha of the lagoon-env configmap the same way `template lagoon-env` generates it
+func IdentifyConfigMapSha(g generator.GeneratorInput, dbaasClient dynamic.Interface) (string, error) {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return "", err
+	}
+	// a provided sha would just be returned, so always calculate it
+	lagoonBuild.BuildValues.ConfigMapSha = ""
+	if err := setDBaaSVariables(lagoonBuild.BuildValues, dbaasClient); err != nil {
+		return "", err
+	}
+	if err := setConfigMapSha(lagoonBuild.BuildValues); err != nil {
+		return "", err
+	}
+	return lagoonBuild.BuildValues.ConfigMapSha, nil
+}
+
+func init() {
+	identifyCmd.AddCommand(configMapShaIdentify)
+	configMapShaIdentify.Flags().Bool("dbaas-credentials", false,
+		"Add the credentials of the dbaas consumers in the environment to the configmap, this waits for the consumers to be provisioned")
+}
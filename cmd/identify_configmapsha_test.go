@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestIdentifyConfigMapSha(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         testdata.TestData
+		consumers    []runtime.Object
+		configMapSha string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name: "test1 - nginx-php with mariadb-dbaas without credentials",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			want: "3b464a12d0d9ecd529607c1cd30405e446e60c189928cdf6acaa4fd08b76e3fb",
+		},
+		{
+			name: "test2 - nginx-php with mariadb-dbaas credentials",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			consumers: []runtime.Object{
+				testMariaDBConsumer("example-project-main", "mariadb"),
+			},
+			want: "1d086daeb08e88c6874247420cf008cf31390f97e063b9be23d5f8e108089615",
+		},
+		{
+			name: "test3 - a provided sha is ignored",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			consumers: []runtime.Object{
+				testMariaDBConsumer("example-project-main", "mariadb"),
+			},
+			configMapSha: "abcdefg1234567890",
+			want:         "1d086daeb08e88c6874247420cf008cf31390f97e063b9be23d5f8e108089615",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			if tt.configMapSha != "" {
+				err = os.Setenv("CONFIG_MAP_SHA", tt.configMapSha)
+				if err != nil {
+					t.Errorf("%v", err)
+				}
+			}
+			var dbaasClient dynamic.Interface
+			if tt.consumers != nil {
+				dbaasClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.consumers...)
+			}
+			got, err := IdentifyConfigMapSha(generator, dbaasClient)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IdentifyConfigMapSha() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IdentifyConfigMapSha() = %v, want %v", got, tt.want)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
@@ -101,6 +102,24 @@ func TestIdentifyDBaaSConsumers(t *testing.T) {
 				"mariadb2:mariadb-dbaas",
 			},
 		},
+		{
+			name: "test6 - dbaas provider file instead of the dbaas-operator with a provider that isn't found",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					EnvironmentType: "development",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			vars: []helpers.EnvironmentVariable{
+				{Name: "DBAAS_PROVIDER_FILE", Value: "internal/testdata/simulate/dbaas-answers.yml"},
+			},
+			templatePath: "testdata/output",
+			want: []string{
+				"mariadb:mariadb-single",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -138,3 +157,252 @@ func TestIdentifyDBaaSConsumers(t *testing.T) {
 		})
 	}
 }
+
+func TestIdentifyDBaaSConsumersSimulated(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        testdata.TestData
+		answersFile string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name: "test1 - no answers file, every provider is found",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			want: []string{
+				"mariadb:mariadb-dbaas",
+			},
+		},
+		{
+			name: "test2 - production provider found in the answers file",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			answersFile: "internal/testdata/simulate/dbaas-answers.yml",
+			want: []string{
+				"mariadb:mariadb-dbaas",
+			},
+		},
+		{
+			name: "test3 - development provider not found in the answers file falls back to single",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					EnvironmentType: "development",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			answersFile: "internal/testdata/simulate/dbaas-answers.yml",
+			want: []string{
+				"mariadb:mariadb-single",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			// no dbaas server is started, and the namespace is generated from the project and environment
+			generator.Simulate = true
+			generator.Namespace = ""
+			var answers dbaasclient.ProviderAnswers
+			if tt.answersFile != "" {
+				answers, err = dbaasclient.LoadProviderAnswers(tt.answersFile)
+				if err != nil {
+					t.Errorf("%v", err)
+				}
+			}
+			generator.DBaaSClient = dbaasclient.NewStaticClient(answers)
+
+			got, err := IdentifyDBaaSConsumers(generator)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IdentifyDBaaSConsumers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IdentifyDBaaSConsumers() = %v, want %v", got, tt.want)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
+
+func TestIdentifyDBaaSConsumersFallbackPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          testdata.TestData
+		unhealthy     bool
+		want          []string
+		wantErr       bool
+		wantErrString string
+	}{
+		{
+			name: "test1 - fallback policy with no provider falls back to single",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_DBAAS_ENVIRONMENT_TYPES", Value: "mariadb:development2", Scope: "build"},
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "fallback", Scope: "build"},
+					},
+				}, true),
+			want: []string{
+				"mariadb:mariadb-single",
+			},
+		},
+		{
+			name: "test2 - fail policy with no provider fails the build",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_DBAAS_ENVIRONMENT_TYPES", Value: "mariadb:development2", Scope: "build"},
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "fail", Scope: "build"},
+					},
+				}, true),
+			wantErr:       true,
+			wantErrString: "for service mariadb with the development2 environment: no providers for dbaas environment development2",
+		},
+		{
+			name: "test3 - fail policy with a provider",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "disabled", Scope: "build"},
+					},
+				}, true),
+			want: []string{
+				"mariadb:mariadb-dbaas",
+			},
+		},
+		{
+			name: "test4 - fail production only policy in a development environment falls back to single",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					EnvironmentType: "development",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_DBAAS_ENVIRONMENT_TYPES", Value: "mariadb:development2", Scope: "build"},
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "fail-production-only", Scope: "build"},
+					},
+				}, true),
+			want: []string{
+				"mariadb:mariadb-single",
+			},
+		},
+		{
+			name: "test5 - fail production only policy in a production environment fails the build",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_DBAAS_ENVIRONMENT_TYPES", Value: "mariadb:development2", Scope: "build"},
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "fail-production-only", Scope: "build"},
+					},
+				}, true),
+			wantErr:       true,
+			wantErrString: "for service mariadb with the development2 environment: no providers for dbaas environment development2",
+		},
+		{
+			name: "test6 - fail policy with an unhealthy endpoint fails the build",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "fail", Scope: "build"},
+					},
+				}, true),
+			unhealthy:     true,
+			wantErr:       true,
+			wantErrString: "unable to check the DBaaS endpoint",
+		},
+		{
+			name: "test7 - unsupported policy",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", Value: "sometimes", Scope: "build"},
+					},
+				}, true),
+			wantErr:       true,
+			wantErrString: "unsupported dbaas fallback policy sometimes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			// setup the fake dbaas server, an unhealthy endpoint is a server that has been stopped
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			if tt.unhealthy {
+				ts.Close()
+			}
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			got, err := IdentifyDBaaSConsumers(generator)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IdentifyDBaaSConsumers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantErrString) {
+					t.Errorf("IdentifyDBaaSConsumers() error = %v, want %v", err, tt.wantErrString)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IdentifyDBaaSConsumers() = %v, want %v", got, tt.want)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -8,12 +8,21 @@ import (
 	"github.com/spf13/cobra"
 	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
 	servicestemplates "github.com/uselagoon/build-deploy-tool/internal/templating/services"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type identifyServices struct {
-	Name       string       `json:"name"`
-	Type       string       `json:"type"`
-	Containers []containers `json:"containers,omitempty"`
+	Name                string               `json:"name"`
+	Type                string               `json:"type"`
+	Containers          []containers         `json:"containers,omitempty"`
+	PodDisruptionBudget *podDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+}
+
+type podDisruptionBudget struct {
+	Name           string `json:"name"`
+	MaxUnavailable string `json:"maxUnavailable"`
 }
 
 type containers struct {
@@ -59,7 +68,7 @@ var lagoonServiceIdentify = &cobra.Command{
 }
 
 // LagoonServiceTemplateIdentification takes the output of the generator and returns a JSON payload that contains information
-// about the services that lagoon will be deploying (this will be kubernetes `kind: deployment`, but lagoon calls them services ¯\_(ツ)_/¯)
+// about the services that lagoon will be deploying (this will be kubernetes `kind: deployment` or `kind: statefulset`, but lagoon calls them services ¯\_(ツ)_/¯)
 // this command can be used to identify services that are deployed by the build, so that services that may remain in the environment can be identified
 // and eventually removed
 func LagoonServiceTemplateIdentification(g generator.GeneratorInput) ([]identifyServices, error) {
@@ -76,25 +85,51 @@ func LagoonServiceTemplateIdentification(g generator.GeneratorInput) ([]identify
 	if err != nil {
 		return nil, fmt.Errorf("couldn't generate template: %v", err)
 	}
+	pdbs, err := servicestemplates.GeneratePDBTemplate(*lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	statefulsets, err := servicestemplates.GenerateStatefulSetTemplate(*lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
 	for _, d := range deployments {
-		dcs := []containers{}
-		for _, dc := range d.Spec.Template.Spec.Containers {
-			dcp := []ports{}
-			for _, p := range dc.Ports {
-				dcp = append(dcp, ports{Port: p.ContainerPort})
-			}
-			dcs = append(dcs, containers{
-				Name:  dc.Name,
-				Ports: dcp,
-			})
+		lServices = append(lServices, identifyService(d.ObjectMeta, d.Spec.Template.Spec, pdbs))
+	}
+	for _, s := range statefulsets {
+		lServices = append(lServices, identifyService(s.ObjectMeta, s.Spec.Template.Spec, pdbs))
+	}
+	return lServices, nil
+}
+
+// identifyService returns the information about a service from its deployment or statefulset
+func identifyService(meta metav1.ObjectMeta, spec corev1.PodSpec, pdbs []policyv1.PodDisruptionBudget) identifyServices {
+	dcs := []containers{}
+	for _, dc := range spec.Containers {
+		dcp := []ports{}
+		for _, p := range dc.Ports {
+			dcp = append(dcp, ports{Port: p.ContainerPort})
 		}
-		lServices = append(lServices, identifyServices{
-			Name:       d.Name,
-			Type:       d.ObjectMeta.Labels["lagoon.sh/service-type"],
-			Containers: dcs,
+		dcs = append(dcs, containers{
+			Name:  dc.Name,
+			Ports: dcp,
 		})
 	}
-	return lServices, nil
+	service := identifyServices{
+		Name:       meta.Name,
+		Type:       meta.Labels["lagoon.sh/service-type"],
+		Containers: dcs,
+	}
+	// the disruption budget for a service has the same name as the service
+	for _, p := range pdbs {
+		if p.Name == meta.Name {
+			service.PodDisruptionBudget = &podDisruptionBudget{
+				Name:           p.Name,
+				MaxUnavailable: p.Spec.MaxUnavailable.String(),
+			}
+		}
+	}
+	return service
 }
 
 func init() {
@@ -307,6 +307,38 @@ func TestIdentifyLagoonServices(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "test5 - basic deployment autoscaled",
+			description: "an autoscaled service with a minimum of more than one replica gets a pod disruption budget",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/basic/lagoon.autoscaling.yml",
+					ImageReferences: map[string]string{
+						"node": "harbor.example/example-project/main/node@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			want: []identifyServices{
+				{
+					Name: "node",
+					Type: "node",
+					Containers: []containers{
+						{
+							Name: "node",
+							Ports: []ports{
+								{Port: 3000},
+							},
+						},
+					},
+					PodDisruptionBudget: &podDisruptionBudget{
+						Name:           "node",
+						MaxUnavailable: "1",
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/monitor"
+	servicestemplates "github.com/uselagoon/build-deploy-tool/internal/templating/services"
+	"k8s.io/client-go/kubernetes"
+)
+
+var monitorRollout = &cobra.Command{
+	Use:     "rollout",
+	Aliases: []string{"r"},
+	Short:   "Monitor the rollout of the deployments and statefulsets for a Lagoon build",
+	Long: `Wait for the rollout of every deployment and statefulset templated by a Lagoon build to complete, if any rollout fails or times out
+the failing containers, their logs, and any events are collected to help with debugging`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return fmt.Errorf("error reading timeout flag: %v", err)
+		}
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return fmt.Errorf("error reading poll-interval flag: %v", err)
+		}
+		gen, err := generator.GenerateInput(*rootCmd, true)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return err
+		}
+		gen.ImageReferences = imageRefs.Images
+		restCfg, err := lagoon.GetConfig()
+		if err != nil {
+			return fmt.Errorf("unable to get kubernetes config: %v", err)
+		}
+		client, err := lagoon.GetK8sClient(restCfg)
+		if err != nil {
+			return fmt.Errorf("unable to create kubernetes client: %v", err)
+		}
+		_, err = MonitorRollout(gen, client, timeout, pollInterval)
+		return err
+	},
+}
+
+// MonitorRollout waits for the rollout of all the deployments and statefulsets the build templates, and prints a diagnosis
+// for any service that fails to roll out
+func MonitorRollout(g generator.GeneratorInput, client kubernetes.Interface, timeout, pollInterval time.Duration) ([]monitor.Result, error) {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := servicestemplates.GenerateDeploymentTemplate(*lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	statefulsets, err := servicestemplates.GenerateStatefulSetTemplate(*lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	workloads := []monitor.Workload{}
+	for _, d := range deployments {
+		workloads = append(workloads, monitor.Workload{Kind: monitor.Deployment, Name: d.Name})
+	}
+	for _, s := range statefulsets {
+		workloads = append(workloads, monitor.Workload{Kind: monitor.StatefulSet, Name: s.Name})
+	}
+	m := monitor.NewMonitor(client, lagoonBuild.BuildValues.Namespace, timeout, pollInterval)
+	results, err := m.Rollout(context.TODO(), workloads...)
+	for _, result := range results {
+		if result.Status == monitor.Complete {
+			fmt.Println(result)
+		}
+	}
+	if err != nil {
+		fmt.Println("##############################################")
+		fmt.Printf("STEP Applying Deployments: Failed at %s\n", time.Now().Format("2006-01-02 15:04:05 (MST)"))
+		fmt.Println("The information below could be useful in helping debug what went wrong")
+		fmt.Println("##############################################")
+		for _, result := range results {
+			if result.Status != monitor.Complete && result.Status != "" {
+				fmt.Print(result.Diagnosis())
+				fmt.Println("##############################################")
+			}
+		}
+	}
+	return results, err
+}
+
+func init() {
+	monitorCmd.AddCommand(monitorRollout)
+	// default progressDeadlineSeconds is 600, doubling that here as a fallback for when the progress deadline doesn't trigger
+	monitorRollout.Flags().Duration("timeout", 20*time.Minute, "How long to wait for all the deployments and statefulsets to complete their rollout")
+	monitorRollout.Flags().Duration("poll-interval", 5*time.Second, "How often to check the status of the deployments and statefulsets")
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/monitor"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestMonitorRollout(t *testing.T) {
+	namespace := "example-project-main"
+	deployment := func(name string, available int32) *appsv1.Deployment {
+		replicas := int32(1)
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app.kubernetes.io/instance": name,
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				Replicas:          1,
+				UpdatedReplicas:   1,
+				AvailableReplicas: available,
+			},
+		}
+	}
+	statefulset := func(name string, ready int32) *appsv1.StatefulSet {
+		replicas := int32(1)
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app.kubernetes.io/instance": name,
+					},
+				},
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type: appsv1.RollingUpdateStatefulSetStrategyType,
+				},
+			},
+			Status: appsv1.StatefulSetStatus{
+				ObservedGeneration: 1,
+				Replicas:           1,
+				ReadyReplicas:      ready,
+				UpdatedReplicas:    1,
+			},
+		}
+	}
+	seed := testdata.GetSeedData(
+		testdata.TestData{
+			ProjectName:     "example-project",
+			EnvironmentName: "main",
+			Branch:          "main",
+			LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+			ImageReferences: map[string]string{
+				"nginx":   "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+				"php":     "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+				"cli":     "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+				"redis":   "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+				"varnish": "harbor.example/example-project/main/varnish@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+			},
+		}, true)
+	tests := []struct {
+		name     string
+		args     testdata.TestData
+		existing []runtime.Object
+		want     map[string]monitor.Status
+		wantErr  bool
+	}{
+		{
+			name: "test1 nginx-php with varnish and redis",
+			args: seed,
+			existing: []runtime.Object{
+				deployment("cli", 1),
+				deployment("nginx-php", 1),
+				deployment("redis", 1),
+				deployment("varnish", 1),
+			},
+			want: map[string]monitor.Status{
+				"cli":       monitor.Complete,
+				"nginx-php": monitor.Complete,
+				"redis":     monitor.Complete,
+				"varnish":   monitor.Complete,
+			},
+		},
+		{
+			name: "test2 nginx-php with crashlooping php container",
+			args: seed,
+			existing: []runtime.Object{
+				deployment("cli", 1),
+				deployment("nginx-php", 0),
+				deployment("redis", 1),
+				deployment("varnish", 1),
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "nginx-php-abcd1234-x1y2z",
+						Namespace: namespace,
+						Labels: map[string]string{
+							"app.kubernetes.io/instance": "nginx-php",
+						},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name:         "php",
+								RestartCount: 2,
+								State: corev1.ContainerState{
+									Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: map[string]monitor.Status{
+				"cli":       monitor.Complete,
+				"nginx-php": monitor.TimedOut,
+				"redis":     monitor.Complete,
+				"varnish":   monitor.Complete,
+			},
+			wantErr: true,
+		},
+		{
+			name: "test3 services with statefulsets",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.services.yml",
+					ImageReferences: map[string]string{
+						"web":          "harbor.example/example-project/main/web@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"mariadb-10-5": "harbor.example/example-project/main/mariadb-10-5@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"postgres-11":  "harbor.example/example-project/main/postgres-11@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"opensearch-2": "harbor.example/example-project/main/opensearch-2@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-6":      "harbor.example/example-project/main/redis-6@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis-7":      "harbor.example/example-project/main/redis-7@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"solr-8":       "harbor.example/example-project/main/solr-8@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_STATEFULSETS",
+							Value: "enabled",
+							Scope: "build",
+						},
+					},
+				}, true),
+			existing: []runtime.Object{
+				deployment("redis-6", 1),
+				deployment("redis-7", 1),
+				deployment("web", 1),
+				statefulset("mariadb-10-5", 1),
+				statefulset("opensearch-2", 1),
+				statefulset("postgres-11", 0),
+				statefulset("solr-8", 1),
+			},
+			want: map[string]monitor.Status{
+				"redis-6":      monitor.Complete,
+				"redis-7":      monitor.Complete,
+				"web":          monitor.Complete,
+				"mariadb-10-5": monitor.Complete,
+				"opensearch-2": monitor.Complete,
+				"postgres-11":  monitor.TimedOut,
+				"solr-8":       monitor.Complete,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			client := fake.NewSimpleClientset(tt.existing...)
+			results, err := MonitorRollout(generator, client, 50*time.Millisecond, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MonitorRollout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			got := map[string]monitor.Status{}
+			for _, r := range results {
+				got[r.Name] = r.Status
+				if r.Name == "nginx-php" && tt.wantErr && len(r.Containers) != 1 {
+					t.Errorf("MonitorRollout() containers = %v, want the crashlooping php container", r.Containers)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("MonitorRollout() = %v, want %v", got, tt.want)
+			}
+			for name, status := range tt.want {
+				if got[name] != status {
+					t.Errorf("MonitorRollout() %s = %v, want %v", name, got[name], status)
+				}
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
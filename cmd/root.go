@@ -60,6 +60,20 @@ var validateCmd = &cobra.Command{
 	Long:    `Validate resources for Lagoon builds`,
 }
 
+var applyCmd = &cobra.Command{
+	Use:     "apply",
+	Aliases: []string{"ap"},
+	Short:   "Apply resources",
+	Long:    `Server-side apply any resources for Lagoon builds`,
+}
+
+var monitorCmd = &cobra.Command{
+	Use:     "monitor",
+	Aliases: []string{"mon"},
+	Short:   "Monitor resources",
+	Long:    `Monitor the state of resources for Lagoon builds`,
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -99,6 +113,8 @@ func init() {
 	rootCmd.AddCommand(taskCmd)
 	rootCmd.AddCommand(identifyCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(monitorCmd)
 
 	rootCmd.PersistentFlags().StringP("lagoon-yml", "l", ".lagoon.yml",
 		"The .lagoon.yml file to read")
@@ -153,6 +169,14 @@ func init() {
 		"Ignore missing env_file files (true by default, subject to change).")
 	rootCmd.PersistentFlags().StringP("images", "", "",
 		"JSON representation of service:image reference")
+	rootCmd.PersistentFlags().StringP("dbaas-provider-file", "", "",
+		"The file of dbaas provider answers to use instead of the dbaas-operator, for clusters that run a different database operator.")
+	rootCmd.PersistentFlags().StringP("custom-service-types-file", "", "",
+		"The file of additional service type definitions to support, usually provided by the cluster admin from a mounted configmap.")
+	rootCmd.PersistentFlags().BoolP("simulate", "", false,
+		"Run without access to a cluster or the dbaas-operator, dbaas provider checks are answered from the simulate-dbaas-answers file.")
+	rootCmd.PersistentFlags().StringP("simulate-dbaas-answers", "", "",
+		"The file of dbaas provider answers to use when simulating, eg mariadb/production: found. If not provided, every provider is found.")
 }
 
 // initConfig reads in config file and ENV variables if set.
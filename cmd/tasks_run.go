@@ -10,6 +10,10 @@ import (
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"github.com/uselagoon/build-deploy-tool/internal/tasklib"
+	servicestemplates "github.com/uselagoon/build-deploy-tool/internal/templating/services"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
@@ -24,10 +28,20 @@ var taskCmd = &cobra.Command{
 	Long:    `Will run Pre/Post/etc. tasks defined in a .lagoon.yml`,
 }
 
-// unidleThenRun is a wrapper around 'runCleanTaskInEnvironment' used for pre-rollout tasks
+// unidleThenRun is a wrapper around a task runner used for pre-rollout tasks
 // We actually want to unidle the namespace before running pre-rollout tasks,
 // so we wrap the usual task runner before calling it.
-func unidleThenRun(namespace string, prePost string, incoming lagoon.Task) error {
+func unidleThenRun(taskRunner runTaskInEnvironmentFuncType) runTaskInEnvironmentFuncType {
+	return func(namespace string, prePost string, incoming lagoon.Task) error {
+		if err := unidleEnvironment(namespace, incoming); err != nil {
+			return err
+		}
+		return taskRunner(namespace, prePost, incoming)
+	}
+}
+
+// unidleEnvironment scales up the idled deployments of the namespace before a task runs
+func unidleEnvironment(namespace string, incoming lagoon.Task) error {
 	fmt.Printf("Unidling namespace with RequiresEnvironment: %v, ScaleMaxIterations:%v and ScaleWaitTime:%v\n", incoming.RequiresEnvironment, incoming.ScaleMaxIterations, incoming.ScaleWaitTime)
 	err := lagoon.UnidleNamespace(context.TODO(), namespace, incoming.ScaleMaxIterations, incoming.ScaleWaitTime)
 	if err != nil {
@@ -43,7 +57,7 @@ func unidleThenRun(namespace string, prePost string, incoming lagoon.Task) error
 			return fmt.Errorf("there was a problem when unidling the environment for pre-rollout tasks: %v", err.Error())
 		}
 	}
-	return runCleanTaskInEnvironment(namespace, prePost, incoming)
+	return nil
 }
 
 var tasksPreRun = &cobra.Command{
@@ -61,7 +75,7 @@ var tasksPreRun = &cobra.Command{
 		}
 		fmt.Println("Executing Pre-rollout Tasks")
 
-		taskIterator, err := iterateTaskGenerator(true, unidleThenRun, buildValues, "Pre-Rollout", true)
+		taskIterator, err := iterateTaskGenerator(true, unidleThenRun(taskRunnerForBuild(buildValues, preRolloutTasks)), buildValues, "Pre-Rollout", true)
 		if err != nil {
 			fmt.Println("Pre-rollout Tasks Failed with the following error: ", err.Error())
 			os.Exit(1)
@@ -93,7 +107,7 @@ var tasksPostRun = &cobra.Command{
 
 		fmt.Println("Executing Post-rollout Tasks")
 
-		taskIterator, err := iterateTaskGenerator(false, runCleanTaskInEnvironment, buildValues, "Post-Rollout", true)
+		taskIterator, err := iterateTaskGenerator(false, taskRunnerForBuild(buildValues, postRolloutTasks), buildValues, "Post-Rollout", true)
 		if err != nil {
 			fmt.Println("Pre-rollout Tasks Failed with the following error: ", err.Error())
 			os.Exit(1)
@@ -109,6 +123,18 @@ var tasksPostRun = &cobra.Command{
 }
 
 func getEnvironmentInfo(g generator.GeneratorInput) (tasklib.TaskEnvironment, generator.BuildValues, error) {
+	// the images are only needed to render the jobs of post-rollout tasks that run as jobs
+	images, err := rootCmd.PersistentFlags().GetString("images")
+	if err != nil {
+		return nil, generator.BuildValues{}, fmt.Errorf("error reading images flag: %v", err)
+	}
+	if images != "" {
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return nil, generator.BuildValues{}, err
+		}
+		g.ImageReferences = imageRefs.Images
+	}
 	// read the .lagoon.yml file
 	lagoonBuild, err := generator.NewGenerator(
 		g,
@@ -227,6 +253,77 @@ func evaluateWhenConditionsForTaskInEnvironment(environment tasklib.TaskEnvironm
 
 type runTaskInEnvironmentFuncType func(namespace string, prePost string, incoming lagoon.Task) error
 
+// taskRunnerForBuild returns the task runner for the pre or post rollout tasks of a build, tasks with the job mode are run as a job
+// rendered from the pod template of their service, any other task is run in a running pod of its service
+func taskRunnerForBuild(buildValues generator.BuildValues, taskType int) runTaskInEnvironmentFuncType {
+	return func(namespace string, prePost string, incoming lagoon.Task) error {
+		if err := lagoon.ValidateTaskMode(incoming.Mode); err != nil {
+			return fmt.Errorf("task %s: %v", incoming.Name, err)
+		}
+		if incoming.Mode == lagoon.TaskModeJob {
+			return runTaskJobInEnvironment(buildValues, taskType, namespace, prePost, incoming)
+		}
+		return runCleanTaskInEnvironment(namespace, prePost, incoming)
+	}
+}
+
+// runTaskJobInEnvironment renders the job of a task and runs it in the environment
+func runTaskJobInEnvironment(buildValues generator.BuildValues, taskType int, namespace string, prePost string, incoming lagoon.Task) error {
+	task := lagoon.NewTask()
+	task.Command = incoming.Command
+	task.Namespace = namespace
+	task.Service = incoming.Service
+	task.Shell = incoming.Shell
+	task.Container = incoming.Container
+	task.Name = incoming.Name
+	task.ScaleMaxIterations = incoming.ScaleMaxIterations
+	task.ScaleWaitTime = incoming.ScaleWaitTime
+	task.Mode = incoming.Mode
+	restCfg, err := lagoon.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := lagoon.GetK8sClient(restCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create client: %v", err)
+	}
+	job, err := generateTaskJob(context.TODO(), client, buildValues, taskType, prePost, task)
+	if err != nil {
+		return err
+	}
+	return lagoon.ExecuteTaskJobInEnvironment(client, task, job, prePost)
+}
+
+// generateTaskJob renders the job of a task. pre-rollout tasks run before the build rolls out the services, so their job is
+// rendered from the running deployment of the service and the images of the build aren't needed. post-rollout tasks run once
+// the services are rolled out, so their job is rendered from the deployment the build templates with the images of the build
+func generateTaskJob(
+	ctx context.Context,
+	client kubernetes.Interface,
+	buildValues generator.BuildValues,
+	taskType int,
+	prePost string,
+	task lagoon.Task,
+) (*batchv1.Job, error) {
+	if taskType == preRolloutTasks {
+		lagoonServiceLabel := "lagoon.sh/service=" + task.Service
+		deployments, err := client.AppsV1().Deployments(task.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: lagoonServiceLabel,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(deployments.Items) == 0 {
+			return nil, &lagoon.DeploymentMissingError{ErrorText: "No deployments found matching label: " + lagoonServiceLabel}
+		}
+		return servicestemplates.GenerateTaskJobFromDeployment(buildValues, task, prePost, deployments.Items[0])
+	}
+	if err := setConfigMapSha(&buildValues); err != nil {
+		return nil, err
+	}
+	return servicestemplates.GenerateTaskJobTemplate(buildValues, task, prePost)
+}
+
 // runCleanTaskInEnvironment implements runTaskInEnvironmentFuncType and will
 // 1. make sure the task we pass to the execution environment is free of any data we don't want (hence the new task)
 // 2. will actually execute the task in the environment.
@@ -1,12 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"testing"
 
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"github.com/uselagoon/build-deploy-tool/internal/tasklib"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
 )
 
 func Test_evaluateWhenConditionsForTaskInEnvironment(t *testing.T) {
@@ -239,3 +252,172 @@ func Test_iterateTaskGenerator(t *testing.T) {
 		})
 	}
 }
+
+func Test_generateTaskJob(t *testing.T) {
+	namespace := "example-project-main"
+	runningImage := "harbor.example/example-project/main/cli@sha256:0a5c0a1d4d4ba6e8ab1bd1d4bc0dc10d4fe2e04bd0bb7d0b18b26a2e1d0f6e1d"
+	deployment := func(name, serviceType, image string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"lagoon.sh/service":      name,
+					"lagoon.sh/service-type": serviceType,
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  name,
+								Image: image,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	// the images of the build are only passed to post-rollout tasks, so none of the tests have any
+	seed := testdata.GetSeedData(
+		testdata.TestData{
+			ProjectName:     "example-project",
+			EnvironmentName: "main",
+			Branch:          "main",
+			LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+		}, true)
+	tests := []struct {
+		name        string
+		args        testdata.TestData
+		taskType    int
+		prePost     string
+		task        lagoon.Task
+		existing    []runtime.Object
+		wantImage   string
+		wantErr     bool
+		wantMissing bool
+	}{
+		{
+			name:     "test1 pre-rollout task job from the running deployment",
+			args:     seed,
+			taskType: preRolloutTasks,
+			prePost:  "Pre-Rollout",
+			task: lagoon.Task{
+				Name:    "drush deploy",
+				Command: "drush -y deploy",
+				Service: "cli",
+				Mode:    lagoon.TaskModeJob,
+			},
+			existing: []runtime.Object{
+				deployment("cli", "cli-persistent", runningImage),
+			},
+			wantImage: runningImage,
+		},
+		{
+			name:     "test2 pre-rollout task job without a running deployment",
+			args:     seed,
+			taskType: preRolloutTasks,
+			prePost:  "Pre-Rollout",
+			task: lagoon.Task{
+				Name:    "drush deploy",
+				Command: "drush -y deploy",
+				Service: "cli",
+				Mode:    lagoon.TaskModeJob,
+			},
+			wantErr:     true,
+			wantMissing: true,
+		},
+		{
+			name:     "test3 post-rollout task job without the images of the build",
+			args:     seed,
+			taskType: postRolloutTasks,
+			prePost:  "Post-Rollout",
+			task: lagoon.Task{
+				Name:    "drush deploy",
+				Command: "drush -y deploy",
+				Service: "cli",
+				Mode:    lagoon.TaskModeJob,
+			},
+			existing: []runtime.Object{
+				deployment("cli", "cli-persistent", runningImage),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 pre-rollout task job in a statefulset service",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.services.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_STATEFULSETS",
+							Value: "migrate",
+							Scope: "build",
+						},
+					},
+				}, true),
+			taskType: preRolloutTasks,
+			prePost:  "Pre-Rollout",
+			task: lagoon.Task{
+				Name:    "mysql check",
+				Command: "mysqladmin ping",
+				Service: "mariadb-10-5",
+				Mode:    lagoon.TaskModeJob,
+			},
+			existing: []runtime.Object{
+				deployment("mariadb-10-5", "mariadb-single", "harbor.example/example-project/main/mariadb-10-5@sha256:running"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			_, buildValues, err := getEnvironmentInfo(generator)
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+
+			tt.task.Namespace = namespace
+			client := fake.NewSimpleClientset(tt.existing...)
+			job, err := generateTaskJob(context.Background(), client, buildValues, tt.taskType, tt.prePost, tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("generateTaskJob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if _, ok := err.(*lagoon.DeploymentMissingError); ok != tt.wantMissing {
+				t.Errorf("generateTaskJob() error = %v, wantMissing %v", err, tt.wantMissing)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			containers := job.Spec.Template.Spec.Containers
+			if len(containers) != 1 || containers[0].Image != tt.wantImage {
+				t.Errorf("generateTaskJob() containers = %v, want the image %s", containers, tt.wantImage)
+			}
+			if job.Labels["lagoon.sh/service"] != tt.task.Service {
+				t.Errorf("generateTaskJob() service = %s, want %s", job.Labels["lagoon.sh/service"], tt.task.Service)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// the subfolders of the saved templates path that each family of templates is written to
+const (
+	autogenIngressTemplatesDir = "autogenerated-ingress"
+	ingressTemplatesDir        = "ingress"
+	dbaasTemplatesDir          = "dbaas"
+	lagoonEnvTemplatesDir      = "lagoon-env"
+	backupTemplatesDir         = "backups"
+	lagoonServicesTemplatesDir = "lagoon-services"
+	manifestIndexFile          = "manifest-index.yaml"
+)
+
+// ManifestIndex lists every object that was templated by `template all`
+type ManifestIndex struct {
+	Manifests []ManifestIndexEntry `json:"manifests"`
+}
+
+// ManifestIndexEntry is a single object in the manifest index, and the file it was written to
+type ManifestIndexEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	File       string `json:"file"`
+}
+
+var templateAllGeneration = &cobra.Command{
+	Use:     "all",
+	Aliases: []string{"a"},
+	Short:   "Generate all the templates for a Lagoon build in one pass",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		k8upVersion, err := cmd.Flags().GetString("version")
+		if err != nil {
+			return fmt.Errorf("error reading version flag: %v", err)
+		}
+		gen, err := generator.GenerateInput(*rootCmd, true)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return err
+		}
+		gen.ImageReferences = imageRefs.Images
+		gen.BackupConfiguration.K8upVersion = k8upVersion
+		dbaasClient, err := dbaasVariablesClient(cmd)
+		if err != nil {
+			return err
+		}
+		return TemplateAllGeneration(gen, dbaasClient)
+	},
+}
+
+// TemplateAllGeneration runs the generator once and writes every family of templates into its own subfolder
+// of the saved templates path, along with an index of every object that was templated. if a client is provided the
+// credentials of the dbaas consumers in the environment are added to the lagoon-env configmap
+func TemplateAllGeneration(g generator.GeneratorInput, dbaasClient dynamic.Interface) error {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return err
+	}
+	// the credentials are part of the configmap sha, so they are added before lagoon-env and the services are templated
+	if err := setDBaaSVariables(lagoonBuild.BuildValues, dbaasClient); err != nil {
+		return err
+	}
+	savedTemplates := g.SavedTemplatesPath
+
+	families := []struct {
+		dir      string
+		generate func(string) error
+	}{
+		{
+			dir: autogenIngressTemplatesDir,
+			generate: func(path string) error {
+				return generateAutogeneratedIngressTemplates(lagoonBuild, path, g.Debug)
+			},
+		},
+		{
+			dir: ingressTemplatesDir,
+			generate: func(path string) error {
+				return generateIngressTemplates(lagoonBuild, path, g.Debug)
+			},
+		},
+		{
+			dir: dbaasTemplatesDir,
+			generate: func(path string) error {
+				return generateDBaaSTemplates(lagoonBuild, path, g.Debug)
+			},
+		},
+		{
+			// the lagoon-env configmap is templated before the services as the deployments need the configmap sha
+			dir: lagoonEnvTemplatesDir,
+			generate: func(path string) error {
+				return generateLagoonEnvTemplate(lagoonBuild, path, g.Debug)
+			},
+		},
+		{
+			dir: lagoonServicesTemplatesDir,
+			generate: func(path string) error {
+				return generateLagoonServiceTemplates(lagoonBuild, path, g.Debug)
+			},
+		},
+		{
+			// backups are templated last as they modify the services in the generator to add any read replicas
+			dir: backupTemplatesDir,
+			generate: func(path string) error {
+				return generateBackupTemplates(lagoonBuild, path)
+			},
+		},
+	}
+	dirs := []string{}
+	for _, family := range families {
+		path := filepath.Join(savedTemplates, family.dir)
+		// templates left over from an earlier run would otherwise be applied and indexed along with this run
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("couldn't clear directory %v: %v", path, err)
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return fmt.Errorf("couldn't create directory %v: %v", path, err)
+		}
+		if err := family.generate(path); err != nil {
+			return err
+		}
+		dirs = append(dirs, family.dir)
+	}
+
+	index, err := GenerateManifestIndex(savedTemplates, dirs)
+	if err != nil {
+		return err
+	}
+	indexBytes, err := yaml.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("couldn't generate manifest index: %v", err)
+	}
+	if g.Debug {
+		fmt.Printf("Writing manifest index to %s\n", filepath.Join(savedTemplates, manifestIndexFile))
+	}
+	helpers.WriteTemplateFile(filepath.Join(savedTemplates, manifestIndexFile), indexBytes)
+	return nil
+}
+
+// GenerateManifestIndex walks the directories of the template families within the saved templates path and collects every
+// object from the templates found there, any other files in the saved templates path aren't part of the index
+func GenerateManifestIndex(savedTemplates string, dirs []string) (*ManifestIndex, error) {
+	index := &ManifestIndex{
+		Manifests: []ManifestIndexEntry{},
+	}
+	for _, dir := range dirs {
+		if err := indexTemplateDir(index, savedTemplates, dir); err != nil {
+			return nil, fmt.Errorf("couldn't generate manifest index: %v", err)
+		}
+	}
+	return index, nil
+}
+
+// indexTemplateDir adds every object from the templates in a directory of the saved templates path to the index
+func indexTemplateDir(index *ManifestIndex, savedTemplates, dir string) error {
+	return filepath.WalkDir(filepath.Join(savedTemplates, dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		rel, err := filepath.Rel(savedTemplates, path)
+		if err != nil {
+			return err
+		}
+		objects, err := readManifestObjects(path)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			index.Manifests = append(index.Manifests, ManifestIndexEntry{
+				APIVersion: obj.APIVersion,
+				Kind:       obj.Kind,
+				Name:       obj.Metadata.Name,
+				File:       filepath.ToSlash(rel),
+			})
+		}
+		return nil
+	})
+}
+
+type manifestObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+// readManifestObjects reads all the yaml documents in a template file, skipping any empty documents
+func readManifestObjects(file string) ([]manifestObject, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read file %v: %v", file, err)
+	}
+	defer f.Close()
+	return decodeManifestObjects(f, file)
+}
+
+// decodeManifestObjects reads all the yaml documents from a reader, skipping any empty documents
+func decodeManifestObjects(r io.Reader, source string) ([]manifestObject, error) {
+	objects := []manifestObject{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %v: %v", source, err)
+		}
+		obj := manifestObject{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal %v: %v", source, err)
+		}
+		if obj.Kind == "" {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+func init() {
+	templateCmd.AddCommand(templateAllGeneration)
+	templateAllGeneration.Flags().StringP("version", "", "v1", "The version of k8up used.")
+	templateAllGeneration.Flags().Bool("dbaas-credentials", false,
+		"Add the credentials of the dbaas consumers in the environment to the configmap, this waits for the consumers to be provisioned")
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestTemplateAllGeneration(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         testdata.TestData
+		templatePath string
+		existing     map[string]string
+		consumers    []runtime.Object
+		want         string
+		wantErr      bool
+	}{
+		{
+			name: "test1 nginx-php with varnish and redis",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+					ImageReferences: map[string]string{
+						"nginx":   "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":     "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"cli":     "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis":   "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"varnish": "harbor.example/example-project/main/varnish@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			templatePath: "testoutput",
+			want:         "internal/testdata/complex/all-templates/all1",
+		},
+		{
+			name: "test2 nginx-php with mariadb-dbaas and redis",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			templatePath: "testoutput",
+			want:         "internal/testdata/complex/all-templates/all2",
+		},
+		{
+			name: "test3 templates from an earlier run",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.varnish.yml",
+					ImageReferences: map[string]string{
+						"nginx":   "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":     "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"cli":     "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis":   "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"varnish": "harbor.example/example-project/main/varnish@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			templatePath: "testoutput",
+			// the stale deployment in a template family is removed, and the unrelated file is kept but not indexed
+			existing: map[string]string{
+				"lagoon-services/deployment-node.yaml": "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: node\n",
+				"other/configmap-other.yaml":           "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n",
+			},
+			want: "internal/testdata/complex/all-templates/all3",
+		},
+		{
+			name: "test4 nginx-php with mariadb-dbaas credentials",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example/example-project/main/cli@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"nginx": "harbor.example/example-project/main/nginx@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"php":   "harbor.example/example-project/main/php@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+						"redis": "harbor.example/example-project/main/redis@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			consumers: []runtime.Object{
+				testMariaDBConsumer("example-project-main", "mariadb"),
+			},
+			templatePath: "testoutput",
+			want:         "internal/testdata/complex/all-templates/all4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// set the environment variables from args
+			savedTemplates := tt.templatePath
+			generator, err := testdata.SetupEnvironment(*rootCmd, savedTemplates, tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			err = os.MkdirAll(savedTemplates, 0755)
+			if err != nil {
+				t.Errorf("couldn't create directory %v: %v", savedTemplates, err)
+			}
+
+			defer os.RemoveAll(savedTemplates)
+			for file, content := range tt.existing {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(savedTemplates, file)), 0755); err != nil {
+					t.Errorf("couldn't create directory for %v: %v", file, err)
+				}
+				if err := os.WriteFile(filepath.Join(savedTemplates, file), []byte(content), 0644); err != nil {
+					t.Errorf("couldn't write file %v: %v", file, err)
+				}
+			}
+
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			var dbaasClient dynamic.Interface
+			if tt.consumers != nil {
+				dbaasClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.consumers...)
+			}
+			err = TemplateAllGeneration(generator, dbaasClient)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TemplateAllGeneration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			compareTemplateDirs(t, savedTemplates, tt.want)
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
+
+// compareTemplateDirs checks that all the templates generated in a directory, including subdirectories, match the results
+func compareTemplateDirs(t *testing.T, savedTemplates, want string) {
+	files := templateFilesInDir(t, savedTemplates)
+	results := templateFilesInDir(t, want)
+	if !reflect.DeepEqual(files, results) {
+		t.Errorf("generated templates %v don't match results %v", files, results)
+	}
+	for _, f := range files {
+		f1, err := os.ReadFile(filepath.Join(savedTemplates, f))
+		if err != nil {
+			t.Errorf("couldn't read file %v: %v", f, err)
+		}
+		r1, err := os.ReadFile(filepath.Join(want, f))
+		if err != nil {
+			t.Errorf("couldn't read file %v: %v", f, err)
+		}
+		if !reflect.DeepEqual(f1, r1) {
+			t.Errorf("%s = \n%v", f, diff.LineDiff(string(r1), string(f1)))
+		}
+	}
+}
+
+// templateFilesInDir returns the paths of all templates within a directory relative to that directory
+// empty directories in the results contain a .gitkeep file which is ignored
+func templateFilesInDir(t *testing.T, dir string) []string {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == ".gitkeep" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("couldn't read directory %v: %v", dir, err)
+	}
+	return files
+}
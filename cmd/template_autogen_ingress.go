@@ -30,12 +30,15 @@ func AutogeneratedIngressGeneration(g generator.GeneratorInput) error {
 	if err != nil {
 		return err
 	}
-	savedTemplates := g.SavedTemplatesPath
+	return generateAutogeneratedIngressTemplates(lagoonBuild, g.SavedTemplatesPath, g.Debug)
+}
 
+// generateAutogeneratedIngressTemplates writes the autogenerated ingress templates from an existing generator
+func generateAutogeneratedIngressTemplates(lagoonBuild *generator.Generator, savedTemplates string, debug bool) error {
 	// generate the templates
 	for _, route := range lagoonBuild.AutogeneratedRoutes.Routes {
 		// autogenerated routes use the `servicename` as the name of the ingress resource, use `IngressName` in routev2 to handle this
-		if g.Debug {
+		if debug {
 			fmt.Printf("Templating autogenerated ingress manifest for %s to %s\n", route.Domain, fmt.Sprintf("%s/%s.yaml", savedTemplates, route.LagoonService))
 		}
 		templateYAML, err := ingresstemplate.GenerateIngressTemplate(route, *lagoonBuild.BuildValues)
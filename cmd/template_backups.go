@@ -42,8 +42,11 @@ func BackupTemplateGeneration(g generator.GeneratorInput,
 	if err != nil {
 		return err
 	}
-	savedTemplates := g.SavedTemplatesPath
+	return generateBackupTemplates(lagoonBuild, g.SavedTemplatesPath)
+}
 
+// generateBackupTemplates writes the backup schedule and prebackuppod templates from an existing generator
+func generateBackupTemplates(lagoonBuild *generator.Generator, savedTemplates string) error {
 	// TODO: the dbaas consumers aren't known when the generator runs currently
 	// so this is a small helper function to collect this from the build stage
 	// this will eventually need to be collected directly by the generator or some other component
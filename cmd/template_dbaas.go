@@ -31,15 +31,18 @@ func DBaaSTemplateGeneration(g generator.GeneratorInput,
 	if err != nil {
 		return err
 	}
-	savedTemplates := g.SavedTemplatesPath
+	return generateDBaaSTemplates(lagoonBuild, g.SavedTemplatesPath, g.Debug)
+}
 
+// generateDBaaSTemplates writes the dbaas consumer templates from an existing generator
+func generateDBaaSTemplates(lagoonBuild *generator.Generator, savedTemplates string, debug bool) error {
 	templateYAML, err := dbaasTemplater.GenerateDBaaSTemplate(*lagoonBuild.BuildValues)
 	if err != nil {
 		return fmt.Errorf("couldn't generate template: %v", err)
 	}
 	if len(templateYAML) > 0 {
 		helpers.WriteTemplateFile(fmt.Sprintf("%s/%s.yaml", savedTemplates, "dbaas"), templateYAML)
-		if g.Debug {
+		if debug {
 			fmt.Printf("Templating dbaas consumers to %s\n", fmt.Sprintf("%s/%s.yaml", savedTemplates, "dbaas"))
 		}
 	}
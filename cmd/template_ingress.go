@@ -30,11 +30,14 @@ func IngressTemplateGeneration(g generator.GeneratorInput) error {
 	if err != nil {
 		return err
 	}
-	savedTemplates := g.SavedTemplatesPath
+	return generateIngressTemplates(lagoonBuild, g.SavedTemplatesPath, g.Debug)
+}
 
+// generateIngressTemplates writes the ingress and active/standby ingress templates from an existing generator
+func generateIngressTemplates(lagoonBuild *generator.Generator, savedTemplates string, debug bool) error {
 	// generate the templates
 	for _, route := range lagoonBuild.MainRoutes.Routes {
-		if g.Debug {
+		if debug {
 			fmt.Printf("Templating ingress manifest for %s to %s\n", route.Domain, fmt.Sprintf("%s/%s.yaml", savedTemplates, route.Domain))
 		}
 		templateYAML, err := ingresstemplate.GenerateIngressTemplate(route, *lagoonBuild.BuildValues)
@@ -50,7 +53,7 @@ func IngressTemplateGeneration(g generator.GeneratorInput) error {
 		// section are created correctly ensuring active/standby will work
 		// generate the templates for active/standby routes separately to normal routes
 		for _, route := range lagoonBuild.ActiveStandbyRoutes.Routes {
-			if g.Debug {
+			if debug {
 				fmt.Printf("Templating active/standby ingress manifest for %s to %s\n", route.Domain, fmt.Sprintf("%s/%s.yaml", savedTemplates, route.Domain))
 			}
 			templateYAML, err := ingresstemplate.GenerateIngressTemplate(route, *lagoonBuild.BuildValues)
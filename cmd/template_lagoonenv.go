@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasconsumer"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	configmaptemplate "github.com/uselagoon/build-deploy-tool/internal/templating/configmap"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// the dbaas consumers are given the same time to be provisioned as the legacy build, 60 attempts of 5 seconds
+const (
+	dbaasConsumerRetries = 60
+	dbaasConsumerWait    = 5 * time.Second
+)
+
+var lagoonEnvGeneration = &cobra.Command{
+	Use:     "lagoon-env",
+	Aliases: []string{"le"},
+	Short:   "Generate the lagoon-env configmap template for a Lagoon build",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		generator, err := generator.GenerateInput(*rootCmd, true)
+		if err != nil {
+			return err
+		}
+		dbaasClient, err := dbaasVariablesClient(cmd)
+		if err != nil {
+			return err
+		}
+		return LagoonEnvTemplateGeneration(generator, dbaasClient)
+	},
+}
+
+// LagoonEnvTemplateGeneration handles generating the lagoon-env configmap, if a client is provided the credentials
+// of the dbaas consumers in the environment are added to the configmap
+func LagoonEnvTemplateGeneration(g generator.GeneratorInput, dbaasClient dynamic.Interface) error {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setDBaaSVariables(lagoonBuild.BuildValues, dbaasClient); err != nil {
+		return err
+	}
+	return generateLagoonEnvTemplate(lagoonBuild, g.SavedTemplatesPath, g.Debug)
+}
+
+// generateLagoonEnvTemplate writes the lagoon-env configmap template from an existing generator
+func generateLagoonEnvTemplate(lagoonBuild *generator.Generator, savedTemplates string, debug bool) error {
+	cm, err := configmaptemplate.GenerateLagoonEnvConfigMap(*lagoonBuild.BuildValues)
+	if err != nil {
+		return fmt.Errorf("couldn't generate template: %v", err)
+	}
+	if lagoonBuild.BuildValues.ConfigMapSha == "" {
+		lagoonBuild.BuildValues.ConfigMapSha, err = configmaptemplate.GetConfigMapSha(cm)
+		if err != nil {
+			return err
+		}
+	}
+	cmBytes, err := yaml.Marshal(cm)
+	if err != nil {
+		return fmt.Errorf("couldn't generate template: %v", err)
+	}
+	separator := []byte("---\n")
+	restoreResult := append(separator[:], cmBytes[:]...)
+	if debug {
+		fmt.Printf("Templating lagoon-env configmap %s\n", fmt.Sprintf("%s/lagoon-env.yaml", savedTemplates))
+	}
+	helpers.WriteTemplateFile(fmt.Sprintf("%s/lagoon-env.yaml", savedTemplates), restoreResult)
+	return nil
+}
+
+// setConfigMapSha calculates the configmap sha from the generated lagoon-env configmap
+// if a sha was provided to the build already, that is used instead
+func setConfigMapSha(buildValues *generator.BuildValues) error {
+	if buildValues.ConfigMapSha != "" {
+		return nil
+	}
+	cm, err := configmaptemplate.GenerateLagoonEnvConfigMap(*buildValues)
+	if err != nil {
+		return fmt.Errorf("couldn't generate template: %v", err)
+	}
+	buildValues.ConfigMapSha, err = configmaptemplate.GetConfigMapSha(cm)
+	return err
+}
+
+// dbaasVariablesClient returns the client used to read the credentials of the dbaas consumers if the dbaas-credentials
+// flag is set, otherwise there is no client and the configmap is generated without them
+func dbaasVariablesClient(cmd *cobra.Command) (dynamic.Interface, error) {
+	dbaasCredentials, err := cmd.Flags().GetBool("dbaas-credentials")
+	if err != nil {
+		return nil, fmt.Errorf("error reading dbaas-credentials flag: %v", err)
+	}
+	if !dbaasCredentials {
+		return nil, nil
+	}
+	restCfg, err := lagoon.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kubernetes config: %v", err)
+	}
+	client, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kubernetes client: %v", err)
+	}
+	return client, nil
+}
+
+// setDBaaSVariables waits for the dbaas consumers of the build and adds their credentials to the build values
+func setDBaaSVariables(buildValues *generator.BuildValues, client dynamic.Interface) error {
+	if client == nil {
+		return nil
+	}
+	vars, err := dbaasconsumer.Variables(context.TODO(), client, *buildValues, dbaasConsumerRetries, dbaasConsumerWait)
+	if err != nil {
+		return err
+	}
+	buildValues.DBaaSVariables = vars
+	return nil
+}
+
+func init() {
+	templateCmd.AddCommand(lagoonEnvGeneration)
+	lagoonEnvGeneration.Flags().Bool("dbaas-credentials", false,
+		"Add the credentials of the dbaas consumers in the environment to the configmap, this waits for the consumers to be provisioned")
+}
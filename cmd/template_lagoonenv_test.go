@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestLagoonEnvTemplateGeneration(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         testdata.TestData
+		consumers    []runtime.Object
+		templatePath string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name: "test1 basic deployment with variables",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/node/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "MY_RUNTIME_VARIABLE",
+							Value: "project",
+							Scope: "runtime",
+						},
+						{
+							Name:  "MY_GLOBAL_VARIABLE",
+							Value: "global",
+							Scope: "global",
+						},
+						{
+							Name:  "MY_BUILD_VARIABLE",
+							Value: "build",
+							Scope: "build",
+						},
+					},
+					EnvVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "MY_RUNTIME_VARIABLE",
+							Value: "environment",
+							Scope: "runtime",
+						},
+					},
+				}, true),
+			templatePath: "testoutput",
+			want:         "internal/testdata/node/lagoonenv-templates/lagoonenv-1",
+		},
+		{
+			name: "test2 pullrequest",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "pr-123",
+					BuildType:       "pullrequest",
+					PRNumber:        "123",
+					PRTitle:         "my pullrequest",
+					PRHeadBranch:    "main",
+					PRBaseBranch:    "main2",
+					PRHeadSHA:       "a1b2c3",
+					PRBaseSHA:       "1a2b3c",
+					EnvironmentType: "development",
+					LagoonYAML:      "internal/testdata/node/lagoon.yml",
+				}, true),
+			templatePath: "testoutput",
+			want:         "internal/testdata/node/lagoonenv-templates/lagoonenv-2",
+		},
+		{
+			name: "test3 nginx-php with mariadb-dbaas credentials",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			consumers: []runtime.Object{
+				testMariaDBConsumer("example-project-main", "mariadb"),
+			},
+			templatePath: "testoutput",
+			want:         "internal/testdata/complex/lagoonenv-templates/lagoonenv-1",
+		},
+		{
+			name: "test4 nginx-php with mariadb-dbaas consumer that failed",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/complex/lagoon.yml",
+				}, true),
+			consumers: []runtime.Object{
+				func() runtime.Object {
+					u := testMariaDBConsumer("example-project-main", "mariadb")
+					u.SetAnnotations(map[string]string{"dbaas.amazee.io/failed": "true"})
+					return u
+				}(),
+			},
+			templatePath: "testoutput",
+			wantErr:      true,
+		},
+		{
+			name: "test5 build variables defined by the project",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/node/lagoon.yml",
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_ROUTE",
+							Value: "https://www.example.com",
+							Scope: "runtime",
+						},
+					},
+				}, true),
+			templatePath: "testoutput",
+			want:         "internal/testdata/node/lagoonenv-templates/lagoonenv-3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			// set the environment variables from args
+			savedTemplates := tt.templatePath
+			generator, err := testdata.SetupEnvironment(*rootCmd, savedTemplates, tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			err = os.MkdirAll(savedTemplates, 0755)
+			if err != nil {
+				t.Errorf("couldn't create directory %v: %v", savedTemplates, err)
+			}
+			defer os.RemoveAll(savedTemplates)
+
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			var dbaasClient dynamic.Interface
+			if tt.consumers != nil {
+				dbaasClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.consumers...)
+			}
+			if err := LagoonEnvTemplateGeneration(generator, dbaasClient); (err != nil) != tt.wantErr {
+				t.Errorf("LagoonEnvTemplateGeneration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			compareTemplateDirs(t, savedTemplates, tt.want)
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
+
+func TestSetConfigMapSha(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         testdata.TestData
+		configMapSha string
+		want         string
+	}{
+		{
+			name: "test1 calculate configmap sha",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/node/lagoon.yml",
+				}, true),
+			want: "275b69b4ffe728ddf01b360ccaed42b451e1f616eca11c50d600fa00929aa10a",
+		},
+		{
+			name: "test2 provided configmap sha",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/node/lagoon.yml",
+				}, true),
+			configMapSha: "abcdefg1234567890",
+			want:         "abcdefg1234567890",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers.UnsetEnvVars(nil) //unset variables before running tests
+			gen, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			gen.ConfigMapSha = tt.configMapSha
+			lagoonBuild, err := generator.NewGenerator(gen)
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+			if err := setConfigMapSha(lagoonBuild.BuildValues); err != nil {
+				t.Errorf("%v", err)
+			}
+			if lagoonBuild.BuildValues.ConfigMapSha != tt.want {
+				t.Errorf("setConfigMapSha() = %v, want %v", lagoonBuild.BuildValues.ConfigMapSha, tt.want)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
+
+// testMariaDBConsumer is a mariadb consumer that the dbaas operator has provisioned
+func testMariaDBConsumer(namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"consumer": map[string]interface{}{
+				"database": "example-project-main-abc123",
+				"username": "example-user",
+				"password": "example-password",
+				"services": map[string]interface{}{
+					"primary": "mariadb-abc123.example.com",
+				},
+			},
+			"provider": map[string]interface{}{
+				"port": "3306",
+			},
+		},
+	}}
+	u.SetAPIVersion("mariadb.amazee.io/v1")
+	u.SetKind("MariaDBConsumer")
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	return u
+}
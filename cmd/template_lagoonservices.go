@@ -10,6 +10,7 @@ import (
 	"github.com/uselagoon/build-deploy-tool/internal/templating/networkpolicy"
 	"github.com/uselagoon/build-deploy-tool/internal/templating/registrysecret"
 	servicestemplates "github.com/uselagoon/build-deploy-tool/internal/templating/services"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
 )
 
@@ -59,107 +60,168 @@ func LagoonServiceTemplateGeneration(g generator.GeneratorInput) error {
 	if err != nil {
 		return err
 	}
-	savedTemplates := g.SavedTemplatesPath
+	return generateLagoonServiceTemplates(lagoonBuild, g.SavedTemplatesPath, g.Debug)
+}
+
+// lagoonServiceTemplate is a generated lagoon service object and the name of the file it is templated to
+type lagoonServiceTemplate struct {
+	file        string
+	description string
+	object      runtime.Object
+}
 
-	// generate the templates
-	secrets, err := registrysecret.GenerateRegistrySecretTemplate(*lagoonBuild.BuildValues)
+// generateLagoonServiceTemplates writes the lagoon service templates from an existing generator
+func generateLagoonServiceTemplates(lagoonBuild *generator.Generator, savedTemplates string, debug bool) error {
+	templates, err := generateLagoonServiceObjects(lagoonBuild.BuildValues)
 	if err != nil {
-		return fmt.Errorf("couldn't generate template: %v", err)
+		return err
 	}
-	for _, secret := range secrets {
-		serviceBytes, err := yaml.Marshal(secret)
+	for _, tpl := range templates {
+		templateBytes, err := yaml.Marshal(tpl.object)
 		if err != nil {
 			return fmt.Errorf("couldn't generate template: %v", err)
 		}
 		separator := []byte("---\n")
-		restoreResult := append(separator[:], serviceBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating registry secret manifests %s\n", fmt.Sprintf("%s/%s.yaml", savedTemplates, secret.Name))
+		restoreResult := append(separator[:], templateBytes[:]...)
+		if debug {
+			fmt.Printf("Templating %s manifests %s\n", tpl.description, fmt.Sprintf("%s/%s.yaml", savedTemplates, tpl.file))
 		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/%s.yaml", savedTemplates, secret.Name), restoreResult)
+		helpers.WriteTemplateFile(fmt.Sprintf("%s/%s.yaml", savedTemplates, tpl.file), restoreResult)
 	}
-	services, err := servicestemplates.GenerateServiceTemplate(*lagoonBuild.BuildValues)
+	return nil
+}
+
+// generateLagoonServiceObjects generates all the lagoon service objects in the order they should be applied
+func generateLagoonServiceObjects(buildValues *generator.BuildValues) ([]lagoonServiceTemplate, error) {
+	// the deployments need the sha of the lagoon-env configmap to trigger a rollout if only the configmap has changed
+	if err := setConfigMapSha(buildValues); err != nil {
+		return nil, err
+	}
+
+	templates := []lagoonServiceTemplate{}
+	secrets, err := registrysecret.GenerateRegistrySecretTemplate(*buildValues)
 	if err != nil {
-		return fmt.Errorf("couldn't generate template: %v", err)
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
 	}
-	for _, d := range services {
-		serviceBytes, err := yaml.Marshal(d)
-		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
-		}
-		separator := []byte("---\n")
-		restoreResult := append(separator[:], serviceBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating service manifests %s\n", fmt.Sprintf("%s/service-%s.yaml", savedTemplates, d.Name))
-		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/service-%s.yaml", savedTemplates, d.Name), restoreResult)
+	for idx := range secrets {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        secrets[idx].Name,
+			description: "registry secret",
+			object:      &secrets[idx],
+		})
 	}
-	pvcs, err := servicestemplates.GeneratePVCTemplate(*lagoonBuild.BuildValues)
+	services, err := servicestemplates.GenerateServiceTemplate(*buildValues)
 	if err != nil {
-		return fmt.Errorf("couldn't generate template: %v", err)
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
 	}
-	for _, d := range pvcs {
-		serviceBytes, err := yaml.Marshal(d)
-		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
-		}
-		separator := []byte("---\n")
-		restoreResult := append(separator[:], serviceBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating pvc manifests %s\n", fmt.Sprintf("%s/pvc-%s.yaml", savedTemplates, d.Name))
-		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/pvc-%s.yaml", savedTemplates, d.Name), restoreResult)
+	for idx := range services {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("service-%s", services[idx].Name),
+			description: "service",
+			object:      &services[idx],
+		})
 	}
-	deployments, err := servicestemplates.GenerateDeploymentTemplate(*lagoonBuild.BuildValues)
+	pvcs, err := servicestemplates.GeneratePVCTemplate(*buildValues)
 	if err != nil {
-		return fmt.Errorf("couldn't generate template: %v", err)
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
 	}
-	for _, d := range deployments {
-		deploymentBytes, err := yaml.Marshal(d)
-		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
-		}
-		separator := []byte("---\n")
-		restoreResult := append(separator[:], deploymentBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating deployment manifests %s\n", fmt.Sprintf("%s/deployment-%s.yaml", savedTemplates, d.Name))
-		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/deployment-%s.yaml", savedTemplates, d.Name), restoreResult)
+	for idx := range pvcs {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("pvc-%s", pvcs[idx].Name),
+			description: "pvc",
+			object:      &pvcs[idx],
+		})
 	}
-	cronjobs, err := servicestemplates.GenerateCronjobTemplate(*lagoonBuild.BuildValues)
+	deployments, err := servicestemplates.GenerateDeploymentTemplate(*buildValues)
 	if err != nil {
-		return fmt.Errorf("couldn't generate template: %v", err)
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
 	}
-	for _, d := range cronjobs {
-		deploymentBytes, err := yaml.Marshal(d)
-		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
-		}
-		separator := []byte("---\n")
-		restoreResult := append(separator[:], deploymentBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating cronjob manifests %s\n", fmt.Sprintf("%s/cronjob-%s.yaml", savedTemplates, d.Name))
-		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/cronjob-%s.yaml", savedTemplates, d.Name), restoreResult)
+	for idx := range deployments {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("deployment-%s", deployments[idx].Name),
+			description: "deployment",
+			object:      &deployments[idx],
+		})
+	}
+	statefulsets, err := servicestemplates.GenerateStatefulSetTemplate(*buildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	for idx := range statefulsets {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("statefulset-%s", statefulsets[idx].Name),
+			description: "statefulset",
+			object:      &statefulsets[idx],
+		})
+	}
+	hpas, err := servicestemplates.GenerateHPATemplate(*buildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	for idx := range hpas {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("hpa-%s", hpas[idx].Name),
+			description: "hpa",
+			object:      &hpas[idx],
+		})
+	}
+	pdbs, err := servicestemplates.GeneratePDBTemplate(*buildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	for idx := range pdbs {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("pdb-%s", pdbs[idx].Name),
+			description: "pdb",
+			object:      &pdbs[idx],
+		})
 	}
-	if lagoonBuild.BuildValues.IsolationNetworkPolicy {
+	cronjobs, err := servicestemplates.GenerateCronjobTemplate(*buildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	for idx := range cronjobs {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        fmt.Sprintf("cronjob-%s", cronjobs[idx].Name),
+			description: "cronjob",
+			object:      &cronjobs[idx],
+		})
+	}
+	if buildValues.IsolationNetworkPolicy {
 		// if isolation network policies are enabled, template that here
-		np, err := networkpolicy.GenerateNetworkPolicy(*lagoonBuild.BuildValues)
+		np, err := networkpolicy.GenerateNetworkPolicy(*buildValues)
 		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
+			return nil, fmt.Errorf("couldn't generate template: %v", err)
 		}
-		npBytes, err := yaml.Marshal(np)
+		templates = append(templates, lagoonServiceTemplate{
+			file:        "isolation-network-policy",
+			description: "networkpolicy",
+			object:      &np,
+		})
+	}
+	servicePolicies, err := networkpolicy.GenerateServiceNetworkPolicies(*buildValues)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate template: %v", err)
+	}
+	for idx := range servicePolicies {
+		templates = append(templates, lagoonServiceTemplate{
+			file:        servicePolicies[idx].Name,
+			description: "networkpolicy",
+			object:      &servicePolicies[idx],
+		})
+	}
+	if buildValues.NetworkPolicies != nil && buildValues.NetworkPolicies.Egress != nil {
+		np, err := networkpolicy.GenerateEgressNetworkPolicy(*buildValues)
 		if err != nil {
-			return fmt.Errorf("couldn't generate template: %v", err)
+			return nil, fmt.Errorf("couldn't generate template: %v", err)
 		}
-		separator := []byte("---\n")
-		restoreResult := append(separator[:], npBytes[:]...)
-		if g.Debug {
-			fmt.Printf("Templating networkpolicy manifest %s\n", fmt.Sprintf("%s/isolation-network-policy.yaml", savedTemplates))
-		}
-		helpers.WriteTemplateFile(fmt.Sprintf("%s/isolation-network-policy.yaml", savedTemplates), restoreResult)
+		templates = append(templates, lagoonServiceTemplate{
+			file:        "egress-network-policy",
+			description: "networkpolicy",
+			object:      &np,
+		})
 	}
-	return nil
+	return templates, nil
 }
 
 func init() {
@@ -73,6 +73,20 @@ func ValidateLagoonYml(lagoonYml string, lagoonYmlOverride string, lagoonYmlEnvV
 		return fmt.Errorf("found invalid cron jobs")
 	}
 
+	failedTaskValidation := false
+	for _, tasks := range [][]lagoon.TaskRun{lYAML.Tasks.Prerollout, lYAML.Tasks.Postrollout} {
+		for _, task := range tasks {
+			if err := lagoon.ValidateTaskMode(task.Run.Mode); err != nil {
+				failedTaskValidation = true
+				fmt.Println(fmt.Errorf("error: task %s: %v", task.Run.Name, err))
+			}
+		}
+	}
+
+	if failedTaskValidation {
+		return fmt.Errorf("found invalid tasks")
+	}
+
 	return nil
 }
 
@@ -134,6 +134,16 @@ func TestValidateLagoonYml(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid task mode should fail validation",
+			args: args{
+				lagoonYml:   "internal/testdata/validate-lagoon-yml/tasks/lagoon.yml",
+				lYAML:       &lagoon.YAML{},
+				projectName: "",
+				debug:       false,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
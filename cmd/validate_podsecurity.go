@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	generator "github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/podsecurity"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var validatePodSecurity = &cobra.Command{
+	Use:     "pod-security",
+	Aliases: []string{"pss"},
+	Short:   "Check the generated lagoon services against the pod security standards",
+	Long: `Check the pod specs of the generated deployments, statefulsets, and cronjobs against the baseline and restricted
+pod security standards profiles, and exit non-zero if any service violates the profile set by --fail-level`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failLevelFlag, err := cmd.Flags().GetString("fail-level")
+		if err != nil {
+			return fmt.Errorf("error reading fail-level flag: %v", err)
+		}
+		failLevel, err := podsecurity.ParseLevel(failLevelFlag)
+		if err != nil {
+			return err
+		}
+		gen, err := generator.GenerateInput(*rootCmd, false)
+		if err != nil {
+			return err
+		}
+		images, err := rootCmd.PersistentFlags().GetString("images")
+		if err != nil {
+			return fmt.Errorf("error reading images flag: %v", err)
+		}
+		imageRefs, err := loadImagesFromFile(images)
+		if err != nil {
+			return err
+		}
+		gen.ImageReferences = imageRefs.Images
+		results, err := PodSecurityValidation(gen)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, result := range results {
+			fmt.Println(result.summary())
+			for _, v := range result.Violations {
+				fmt.Printf("  - %s\n", v)
+			}
+			if result.fails(failLevel) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d workloads violate the %s pod security standard", failed, len(results), failLevel)
+		}
+		return nil
+	},
+}
+
+// PodSecurityResult is the pod security standards violations of a workload generated for a lagoon service
+type PodSecurityResult struct {
+	Kind       string
+	Name       string
+	Service    string
+	Violations []podsecurity.Violation
+}
+
+// fails returns true if any of the violations fail the given level
+func (r PodSecurityResult) fails(level podsecurity.Level) bool {
+	for _, v := range r.Violations {
+		if v.Level.Fails(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r PodSecurityResult) summary() string {
+	status := "passes baseline and restricted"
+	if r.fails(podsecurity.LevelBaseline) {
+		status = "fails baseline and restricted"
+	} else if r.fails(podsecurity.LevelRestricted) {
+		status = "passes baseline, fails restricted"
+	}
+	return fmt.Sprintf("%s %s (service %s) %s", r.Kind, r.Name, r.Service, status)
+}
+
+// PodSecurityValidation runs the generator and checks the pod spec of every workload against the pod security standards
+func PodSecurityValidation(g generator.GeneratorInput) ([]PodSecurityResult, error) {
+	lagoonBuild, err := generator.NewGenerator(
+		g,
+	)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := generateLagoonServiceObjects(lagoonBuild.BuildValues)
+	if err != nil {
+		return nil, err
+	}
+	results := []PodSecurityResult{}
+	for _, tpl := range templates {
+		var meta metav1.ObjectMeta
+		var template corev1.PodTemplateSpec
+		var path *field.Path
+		switch obj := tpl.object.(type) {
+		case *appsv1.Deployment:
+			meta, template, path = obj.ObjectMeta, obj.Spec.Template, field.NewPath("spec", "template")
+		case *appsv1.StatefulSet:
+			meta, template, path = obj.ObjectMeta, obj.Spec.Template, field.NewPath("spec", "template")
+		case *batchv1.CronJob:
+			meta, template, path = obj.ObjectMeta, obj.Spec.JobTemplate.Spec.Template, field.NewPath("spec", "jobTemplate", "spec", "template")
+		default:
+			continue
+		}
+		results = append(results, PodSecurityResult{
+			Kind:       tpl.description,
+			Name:       meta.Name,
+			Service:    meta.Labels["lagoon.sh/service"],
+			Violations: podsecurity.CheckPodTemplate(template, path),
+		})
+	}
+	return results, nil
+}
+
+func init() {
+	validateCmd.AddCommand(validatePodSecurity)
+	validatePodSecurity.Flags().StringP("fail-level", "", "baseline",
+		"The pod security standards profile that a service must pass for the check to succeed (baseline, restricted, or none)")
+}
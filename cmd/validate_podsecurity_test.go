@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/testdata"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestPodSecurityValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       testdata.TestData
+		want       []string
+		violations []int
+	}{
+		{
+			name: "test1 basic deployment fails restricted",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/basic/lagoon.yml",
+					ImageReferences: map[string]string{
+						"node": "harbor.example/example-project/main/node@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+				}, true),
+			want: []string{
+				"deployment node (service node) passes baseline, fails restricted",
+			},
+			violations: []int{4},
+		},
+		{
+			name: "test2 basic deployment with the restricted security profile",
+			args: testdata.GetSeedData(
+				testdata.TestData{
+					ProjectName:     "example-project",
+					EnvironmentName: "main",
+					Branch:          "main",
+					LagoonYAML:      "internal/testdata/basic/lagoon.yml",
+					ImageReferences: map[string]string{
+						"node": "harbor.example/example-project/main/node@sha256:b2001babafaa8128fe89aa8fd11832cade59931d14c3de5b3ca32e2a010fbaa8",
+					},
+					ProjectVariables: []lagoon.EnvironmentVariable{
+						{
+							Name:  "LAGOON_FEATURE_FLAG_ROOTLESS_WORKLOAD",
+							Value: "enabled",
+							Scope: "build",
+						},
+						{
+							Name:  "LAGOON_FEATURE_FLAG_SECURITY_PROFILE",
+							Value: "restricted",
+							Scope: "build",
+						},
+					},
+				}, true),
+			want: []string{
+				"deployment node (service node) passes baseline and restricted",
+			},
+			violations: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := testdata.SetupEnvironment(*rootCmd, "testoutput", tt.args)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			ts := dbaasclient.TestDBaaSHTTPServer()
+			defer ts.Close()
+			err = os.Setenv("DBAAS_OPERATOR_HTTP", ts.URL)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			results, err := PodSecurityValidation(generator)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			got := []string{}
+			violations := []int{}
+			for _, result := range results {
+				got = append(got, result.summary())
+				violations = append(violations, len(result.Violations))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodSecurityValidation() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(violations, tt.violations) {
+				t.Errorf("PodSecurityValidation() violations = %v, want %v", violations, tt.violations)
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FieldManager is the field manager used for all server-side applies performed by the build-deploy-tool
+const FieldManager = "build-deploy-tool"
+
+// Result is the outcome of applying an object
+type Result string
+
+const (
+	Created    Result = "created"
+	Configured Result = "configured"
+	Unchanged  Result = "unchanged"
+)
+
+// ObjectResult is the result of applying a single object
+type ObjectResult struct {
+	Kind   string
+	Name   string
+	Result Result
+}
+
+func (o ObjectResult) String() string {
+	return fmt.Sprintf("%s/%s %s", strings.ToLower(o.Kind), o.Name, o.Result)
+}
+
+// Applier server-side applies generated objects into a namespace
+type Applier struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewApplier returns an applier for the provided namespace
+func NewApplier(client kubernetes.Interface, namespace string) *Applier {
+	return &Applier{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+// Apply server-side applies all the objects in the order they are provided, and reports what happened to each one
+func (a *Applier) Apply(ctx context.Context, objects ...runtime.Object) ([]ObjectResult, error) {
+	results := []ObjectResult{}
+	for _, obj := range objects {
+		result, err := a.applyObject(ctx, obj)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (a *Applier) applyObject(ctx context.Context, obj runtime.Object) (ObjectResult, error) {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		c := a.client.CoreV1().ConfigMaps(a.namespace)
+		return a.apply(ctx, "ConfigMap", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *corev1.Secret:
+		c := a.client.CoreV1().Secrets(a.namespace)
+		return a.apply(ctx, "Secret", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *corev1.Service:
+		c := a.client.CoreV1().Services(a.namespace)
+		return a.apply(ctx, "Service", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *corev1.PersistentVolumeClaim:
+		c := a.client.CoreV1().PersistentVolumeClaims(a.namespace)
+		return a.apply(ctx, "PersistentVolumeClaim", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *appsv1.Deployment:
+		c := a.client.AppsV1().Deployments(a.namespace)
+		return a.apply(ctx, "Deployment", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *appsv1.StatefulSet:
+		c := a.client.AppsV1().StatefulSets(a.namespace)
+		return a.apply(ctx, "StatefulSet", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		c := a.client.AutoscalingV2().HorizontalPodAutoscalers(a.namespace)
+		return a.apply(ctx, "HorizontalPodAutoscaler", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *policyv1.PodDisruptionBudget:
+		c := a.client.PolicyV1().PodDisruptionBudgets(a.namespace)
+		return a.apply(ctx, "PodDisruptionBudget", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *batchv1.CronJob:
+		c := a.client.BatchV1().CronJobs(a.namespace)
+		return a.apply(ctx, "CronJob", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *networkv1.Ingress:
+		c := a.client.NetworkingV1().Ingresses(a.namespace)
+		return a.apply(ctx, "Ingress", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	case *networkv1.NetworkPolicy:
+		c := a.client.NetworkingV1().NetworkPolicies(a.namespace)
+		return a.apply(ctx, "NetworkPolicy", o, o.Name,
+			func() (runtime.Object, error) { return c.Get(ctx, o.Name, metav1.GetOptions{}) },
+			func(data []byte) (runtime.Object, error) {
+				return c.Patch(ctx, o.Name, types.ApplyPatchType, data, patchOptions())
+			},
+		)
+	}
+	return ObjectResult{}, fmt.Errorf("unable to apply object of type %T", obj)
+}
+
+// apply gets the existing object so the result can be determined, then server-side applies the object
+func (a *Applier) apply(
+	ctx context.Context,
+	kind string,
+	obj runtime.Object,
+	name string,
+	get func() (runtime.Object, error),
+	patch func([]byte) (runtime.Object, error),
+) (ObjectResult, error) {
+	result := ObjectResult{
+		Kind: kind,
+		Name: name,
+	}
+	existing, err := get()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return result, fmt.Errorf("couldn't get %s %s: %v", kind, name, err)
+	}
+	if apierrors.IsNotFound(err) {
+		existing = nil
+	}
+	// apply patches need the type information to be set on the object
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		return result, fmt.Errorf("couldn't apply %s %s: object is missing the apiVersion and kind", kind, name)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return result, fmt.Errorf("couldn't apply %s %s: %v", kind, name, err)
+	}
+	applied, err := patch(data)
+	if err != nil {
+		return result, fmt.Errorf("couldn't apply %s %s: %v", kind, name, err)
+	}
+	switch {
+	case existing == nil:
+		result.Result = Created
+	case equalObjects(existing, applied):
+		result.Result = Unchanged
+	default:
+		result.Result = Configured
+	}
+	return result, nil
+}
+
+// equalObjects compares two objects, ignoring the fields that are changed by the api server on every apply
+func equalObjects(a, b runtime.Object) bool {
+	a = a.DeepCopyObject()
+	b = b.DeepCopyObject()
+	for _, obj := range []runtime.Object{a, b} {
+		obj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+		if accessor, err := meta.Accessor(obj); err == nil {
+			accessor.SetManagedFields(nil)
+			accessor.SetResourceVersion("")
+		}
+	}
+	return equality.Semantic.DeepEqual(a, b)
+}
+
+func patchOptions() metav1.PatchOptions {
+	force := true
+	return metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+}
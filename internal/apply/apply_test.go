@@ -0,0 +1,170 @@
+package apply
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/apply/applytest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testService(port int32) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "nginx",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "build-deploy-tool",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name: "http",
+					Port: port,
+				},
+			},
+		},
+	}
+}
+
+func testDeployment(replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "nginx",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "build-deploy-tool",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+		},
+	}
+}
+
+func TestApplier_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []runtime.Object
+		objects  []runtime.Object
+		want     []ObjectResult
+		wantErr  bool
+	}{
+		{
+			name: "test1 - create objects",
+			objects: []runtime.Object{
+				testService(8080),
+				testDeployment(1),
+			},
+			want: []ObjectResult{
+				{Kind: "Service", Name: "nginx", Result: Created},
+				{Kind: "Deployment", Name: "nginx", Result: Created},
+			},
+		},
+		{
+			name: "test2 - unchanged and configured objects",
+			existing: []runtime.Object{
+				func() runtime.Object {
+					s := testService(8080)
+					s.Namespace = "example-project-main"
+					return s
+				}(),
+				func() runtime.Object {
+					d := testDeployment(1)
+					d.Namespace = "example-project-main"
+					return d
+				}(),
+			},
+			objects: []runtime.Object{
+				testService(8080),
+				testDeployment(2),
+			},
+			want: []ObjectResult{
+				{Kind: "Service", Name: "nginx", Result: Unchanged},
+				{Kind: "Deployment", Name: "nginx", Result: Configured},
+			},
+		},
+		{
+			name: "test3 - object without type information",
+			objects: []runtime.Object{
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "nginx",
+					},
+				},
+			},
+			want:    []ObjectResult{},
+			wantErr: true,
+		},
+		{
+			name: "test4 - unsupported object",
+			objects: []runtime.Object{
+				&corev1.Pod{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Pod",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "nginx",
+					},
+				},
+			},
+			want:    []ObjectResult{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewApplier(applytest.NewFakeClient(tt.existing...), "example-project-main")
+			got, err := a.Apply(context.Background(), tt.objects...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplier_ApplyTwice(t *testing.T) {
+	a := NewApplier(applytest.NewFakeClient(), "example-project-main")
+	for _, want := range []Result{Created, Unchanged} {
+		got, err := a.Apply(context.Background(), testDeployment(1))
+		if err != nil {
+			t.Errorf("Apply() error = %v", err)
+			return
+		}
+		if got[0].Result != want {
+			t.Errorf("Apply() = %v, want %v", got[0].Result, want)
+		}
+	}
+	// check that the object was stored in the namespace
+	d, err := a.client.AppsV1().Deployments("example-project-main").Get(context.Background(), "nginx", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("couldn't get deployment: %v", err)
+		return
+	}
+	if *d.Spec.Replicas != 1 {
+		t.Errorf("deployment replicas = %v, want 1", *d.Spec.Replicas)
+	}
+}
+
+func TestObjectResult_String(t *testing.T) {
+	r := ObjectResult{Kind: "Deployment", Name: "nginx", Result: Configured}
+	if r.String() != "deployment/nginx configured" {
+		t.Errorf("String() = %v", r.String())
+	}
+}
@@ -0,0 +1,41 @@
+package applytest
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// NewFakeClient returns a fake clientset that handles apply patches by replacing the stored object,
+// the fake clientset doesn't support server-side apply itself so this is used for testing the applier
+func NewFakeClient(objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(patchAction.GetPatch(), nil, nil)
+		if err != nil {
+			return true, nil, err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		accessor.SetNamespace(patchAction.GetNamespace())
+		_, err = client.Tracker().Get(patchAction.GetResource(), patchAction.GetNamespace(), patchAction.GetName())
+		if apierrors.IsNotFound(err) {
+			return true, obj, client.Tracker().Create(patchAction.GetResource(), obj, patchAction.GetNamespace())
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		return true, obj, client.Tracker().Update(patchAction.GetResource(), obj, patchAction.GetNamespace())
+	})
+	return client
+}
@@ -0,0 +1,247 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ManagedBySelector selects all the resources that are created by the build-deploy-tool
+const ManagedBySelector = "app.kubernetes.io/managed-by=build-deploy-tool"
+
+// any resource with this label set to false is never removed
+const removeLabel = "lagoon.sh/remove"
+
+// the kinds of resources that are checked for removal
+const (
+	Deployment              = "Deployment"
+	StatefulSet             = "StatefulSet"
+	Service                 = "Service"
+	Ingress                 = "Ingress"
+	CronJob                 = "CronJob"
+	PersistentVolumeClaim   = "PersistentVolumeClaim"
+	HorizontalPodAutoscaler = "HorizontalPodAutoscaler"
+	PodDisruptionBudget     = "PodDisruptionBudget"
+	NetworkPolicy           = "NetworkPolicy"
+	MariaDBConsumer         = "MariaDBConsumer"
+	MongoDBConsumer         = "MongoDBConsumer"
+	PostgreSQLConsumer      = "PostgreSQLConsumer"
+)
+
+// the dbaas consumers are custom resources, so they are handled with the dynamic client
+var dbaasConsumers = map[string]schema.GroupVersionResource{
+	MariaDBConsumer:    {Group: "mariadb.amazee.io", Version: "v1", Resource: "mariadbconsumers"},
+	MongoDBConsumer:    {Group: "mongodb.amazee.io", Version: "v1", Resource: "mongodbconsumers"},
+	PostgreSQLConsumer: {Group: "postgres.amazee.io", Version: "v1", Resource: "postgresqlconsumers"},
+}
+
+// Object is a resource in the namespace
+type Object struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (o Object) String() string {
+	return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+}
+
+// Expected is the names of the resources the generator templates for a build, by kind
+type Expected map[string][]string
+
+// Add adds a resource to the expected resources
+func (e Expected) Add(kind, name string) {
+	e[kind] = append(e[kind], name)
+}
+
+func (e Expected) contains(kind, name string) bool {
+	for _, n := range e[kind] {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Cleaner finds and removes resources in a namespace that the build no longer templates
+type Cleaner struct {
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface
+	namespace     string
+}
+
+// NewCleaner returns a cleaner for the provided namespace
+func NewCleaner(client kubernetes.Interface, dynamicClient dynamic.Interface, namespace string) *Cleaner {
+	return &Cleaner{
+		client:        client,
+		dynamicClient: dynamicClient,
+		namespace:     namespace,
+	}
+}
+
+// FindStale returns all the resources managed by the build-deploy-tool in the namespace that are not expected
+func (c *Cleaner) FindStale(ctx context.Context, expected Expected) ([]Object, error) {
+	existing, err := c.listManaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stale := []Object{}
+	for _, obj := range existing {
+		if !expected.contains(obj.Kind, obj.Name) {
+			stale = append(stale, obj)
+		}
+	}
+	return stale, nil
+}
+
+// Delete removes a resource from the namespace, if it has already been removed this is not an error
+func (c *Cleaner) Delete(ctx context.Context, obj Object) error {
+	var err error
+	opts := metav1.DeleteOptions{}
+	switch obj.Kind {
+	case Deployment:
+		err = c.client.AppsV1().Deployments(c.namespace).Delete(ctx, obj.Name, opts)
+	case StatefulSet:
+		err = c.client.AppsV1().StatefulSets(c.namespace).Delete(ctx, obj.Name, opts)
+	case Service:
+		err = c.client.CoreV1().Services(c.namespace).Delete(ctx, obj.Name, opts)
+	case Ingress:
+		err = c.client.NetworkingV1().Ingresses(c.namespace).Delete(ctx, obj.Name, opts)
+	case CronJob:
+		err = c.client.BatchV1().CronJobs(c.namespace).Delete(ctx, obj.Name, opts)
+	case PersistentVolumeClaim:
+		err = c.client.CoreV1().PersistentVolumeClaims(c.namespace).Delete(ctx, obj.Name, opts)
+	case HorizontalPodAutoscaler:
+		err = c.client.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).Delete(ctx, obj.Name, opts)
+	case PodDisruptionBudget:
+		err = c.client.PolicyV1().PodDisruptionBudgets(c.namespace).Delete(ctx, obj.Name, opts)
+	case NetworkPolicy:
+		err = c.client.NetworkingV1().NetworkPolicies(c.namespace).Delete(ctx, obj.Name, opts)
+	default:
+		gvr, ok := dbaasConsumers[obj.Kind]
+		if !ok {
+			return fmt.Errorf("unable to remove %s, unsupported kind", obj)
+		}
+		err = c.dynamicClient.Resource(gvr).Namespace(c.namespace).Delete(ctx, obj.Name, opts)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to remove %s: %v", obj, err)
+	}
+	return nil
+}
+
+// listManaged lists all the resources in the namespace that are managed by the build-deploy-tool
+func (c *Cleaner) listManaged(ctx context.Context) ([]Object, error) {
+	opts := metav1.ListOptions{
+		LabelSelector: ManagedBySelector,
+	}
+	objects := []Object{}
+	deployments, err := c.client.AppsV1().Deployments(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		if d.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: Deployment, Name: d.Name})
+		}
+	}
+	statefulsets, err := c.client.AppsV1().StatefulSets(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list statefulsets: %v", err)
+	}
+	for _, s := range statefulsets.Items {
+		if s.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: StatefulSet, Name: s.Name})
+		}
+	}
+	services, err := c.client.CoreV1().Services(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list services: %v", err)
+	}
+	for _, s := range services.Items {
+		if s.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: Service, Name: s.Name})
+		}
+	}
+	ingresses, err := c.client.NetworkingV1().Ingresses(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ingress: %v", err)
+	}
+	for _, i := range ingresses.Items {
+		if i.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: Ingress, Name: i.Name})
+		}
+	}
+	cronjobs, err := c.client.BatchV1().CronJobs(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list cronjobs: %v", err)
+	}
+	for _, cj := range cronjobs.Items {
+		if cj.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: CronJob, Name: cj.Name})
+		}
+	}
+	pvcs, err := c.client.CoreV1().PersistentVolumeClaims(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pvcs: %v", err)
+	}
+	for _, p := range pvcs.Items {
+		if p.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: PersistentVolumeClaim, Name: p.Name})
+		}
+	}
+	hpas, err := c.client.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list horizontal pod autoscalers: %v", err)
+	}
+	for _, h := range hpas.Items {
+		if h.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: HorizontalPodAutoscaler, Name: h.Name})
+		}
+	}
+	pdbs, err := c.client.PolicyV1().PodDisruptionBudgets(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pod disruption budgets: %v", err)
+	}
+	for _, p := range pdbs.Items {
+		if p.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: PodDisruptionBudget, Name: p.Name})
+		}
+	}
+	networkPolicies, err := c.client.NetworkingV1().NetworkPolicies(c.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list network policies: %v", err)
+	}
+	for _, np := range networkPolicies.Items {
+		if np.Labels[removeLabel] != "false" {
+			objects = append(objects, Object{Kind: NetworkPolicy, Name: np.Name})
+		}
+	}
+	// sort the consumer kinds so the results are always in the same order
+	kinds := []string{}
+	for kind := range dbaasConsumers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		consumers, err := c.dynamicClient.Resource(dbaasConsumers[kind]).Namespace(c.namespace).List(ctx, opts)
+		if err != nil {
+			// if the dbaas-operator isn't installed in the cluster, there are no consumers to check
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to list %s: %v", kind, err)
+		}
+		for _, consumer := range consumers.Items {
+			if consumer.GetLabels()[removeLabel] != "false" {
+				objects = append(objects, Object{Kind: kind, Name: consumer.GetName()})
+			}
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,167 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "example-project-main"
+
+func managedMeta(name string, labels map[string]string) metav1.ObjectMeta {
+	l := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: testNamespace,
+		Labels:    l,
+	}
+}
+
+func testConsumer(kind, apiVersion, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(testNamespace)
+	u.SetLabels(map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+	})
+	return u
+}
+
+func newDynamicFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for kind, gvr := range dbaasConsumers {
+		listKinds[gvr] = kind + "List"
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func TestCleaner_FindStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []runtime.Object
+		consumer []runtime.Object
+		expected Expected
+		want     []Object
+	}{
+		{
+			name: "test1 - stale resources",
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: managedMeta("nginx", nil)},
+				&appsv1.Deployment{ObjectMeta: managedMeta("old-nginx", nil)},
+				// a deployment that has been migrated to a statefulset
+				&appsv1.Deployment{ObjectMeta: managedMeta("mariadb", nil)},
+				&appsv1.StatefulSet{ObjectMeta: managedMeta("mariadb", nil)},
+				&appsv1.StatefulSet{ObjectMeta: managedMeta("old-mariadb", nil)},
+				&corev1.Service{ObjectMeta: managedMeta("nginx", nil)},
+				&corev1.Service{ObjectMeta: managedMeta("old-nginx", nil)},
+				&corev1.PersistentVolumeClaim{ObjectMeta: managedMeta("old-nginx", nil)},
+				&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: managedMeta("old-nginx", nil)},
+				&policyv1.PodDisruptionBudget{ObjectMeta: managedMeta("old-nginx", nil)},
+				&networkv1.NetworkPolicy{ObjectMeta: managedMeta("isolation-network-policy", nil)},
+				&networkv1.NetworkPolicy{ObjectMeta: managedMeta("service-network-policy-old-mariadb", nil)},
+				&networkv1.Ingress{ObjectMeta: managedMeta("example.com", nil)},
+				&networkv1.Ingress{ObjectMeta: managedMeta("old.example.com", nil)},
+				// not managed by the build-deploy-tool
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: testNamespace}},
+				// excluded from removal
+				&networkv1.Ingress{ObjectMeta: managedMeta("keep.example.com", map[string]string{"lagoon.sh/remove": "false"})},
+			},
+			consumer: []runtime.Object{
+				testConsumer("MariaDBConsumer", "mariadb.amazee.io/v1", "mariadb"),
+				testConsumer("PostgreSQLConsumer", "postgres.amazee.io/v1", "postgres"),
+			},
+			expected: Expected{
+				Deployment:      []string{"nginx"},
+				StatefulSet:     []string{"mariadb"},
+				Service:         []string{"nginx"},
+				Ingress:         []string{"example.com"},
+				NetworkPolicy:   []string{"isolation-network-policy"},
+				MariaDBConsumer: []string{"mariadb"},
+			},
+			want: []Object{
+				{Kind: Deployment, Name: "mariadb"},
+				{Kind: Deployment, Name: "old-nginx"},
+				{Kind: StatefulSet, Name: "old-mariadb"},
+				{Kind: Service, Name: "old-nginx"},
+				{Kind: Ingress, Name: "old.example.com"},
+				{Kind: PersistentVolumeClaim, Name: "old-nginx"},
+				{Kind: HorizontalPodAutoscaler, Name: "old-nginx"},
+				{Kind: PodDisruptionBudget, Name: "old-nginx"},
+				{Kind: NetworkPolicy, Name: "service-network-policy-old-mariadb"},
+				{Kind: PostgreSQLConsumer, Name: "postgres"},
+			},
+		},
+		{
+			name: "test2 - nothing to remove",
+			existing: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: managedMeta("nginx", nil)},
+			},
+			expected: Expected{
+				Deployment: []string{"nginx"},
+			},
+			want: []Object{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCleaner(fake.NewSimpleClientset(tt.existing...), newDynamicFakeClient(tt.consumer...), testNamespace)
+			got, err := c.FindStale(context.Background(), tt.expected)
+			if err != nil {
+				t.Errorf("FindStale() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleaner_Delete(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: managedMeta("old-nginx", nil)},
+	)
+	dynamicClient := newDynamicFakeClient(
+		testConsumer("MariaDBConsumer", "mariadb.amazee.io/v1", "mariadb"),
+	)
+	c := NewCleaner(client, dynamicClient, testNamespace)
+	for _, obj := range []Object{
+		{Kind: Deployment, Name: "old-nginx"},
+		{Kind: MariaDBConsumer, Name: "mariadb"},
+		// already removed resources are not an error
+		{Kind: Service, Name: "old-nginx"},
+	} {
+		if err := c.Delete(context.Background(), obj); err != nil {
+			t.Errorf("Delete() error = %v", err)
+		}
+	}
+	stale, err := c.FindStale(context.Background(), Expected{})
+	if err != nil {
+		t.Errorf("FindStale() error = %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("FindStale() = %v, want none", stale)
+	}
+	if err := c.Delete(context.Background(), Object{Kind: "Pod", Name: "nginx"}); err == nil {
+		t.Errorf("Delete() expected an error for an unsupported kind")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 )
 
+// Client is the HTTP backend that talks to the dbaas-operator
 type Client struct {
 	HTTPClient   *retryablehttp.Client
 	RetryMax     int
@@ -21,7 +22,8 @@ type Client struct {
 
 type providerResponse struct {
 	Result struct {
-		Found bool `json:"found"`
+		Found        bool          `json:"found"`
+		Capabilities *Capabilities `json:"capabilities"`
 	} `json:"result"`
 	Error string `json:"error"`
 }
@@ -75,27 +77,36 @@ func (c *Client) CheckHealth(dbaasEndpoint string) error {
 }
 
 // check the dbaas provider exists, will return true or false without error if it can talk to the dbaas-operator
-// will return error if there an issue with the dbaas-operator or the specified endpoint
-func (c *Client) CheckProvider(dbaasEndpoint, dbaasType, dbaasEnvironment string) (bool, error) {
+// will return error if there an issue with the dbaas-operator or the specified endpoint.
+// the capabilities of the provider are also returned, older versions of the dbaas-operator don't report any capabilities
+func (c *Client) CheckProvider(dbaasEndpoint, dbaasType, dbaasEnvironment string) (bool, *Capabilities, error) {
+	response, err := c.getProvider(dbaasEndpoint, dbaasType, dbaasEnvironment)
+	if err != nil {
+		return false, nil, err
+	}
+	if response.Result.Found {
+		return true, response.Result.Capabilities, nil
+	}
+	return false, nil, nil
+}
+
+func (c *Client) getProvider(dbaasEndpoint, dbaasType, dbaasEnvironment string) (*providerResponse, error) {
 	dbaasEndpoint = addProtocol(dbaasEndpoint)
 	// curl --silent "http://dbaas/type/env"
 	resp, err := c.HTTPClient.Get(fmt.Sprintf("%s/%s/%s", dbaasEndpoint, dbaasType, dbaasEnvironment))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	response := new(providerResponse)
 	defer resp.Body.Close()
 	err = json.NewDecoder(resp.Body).Decode(response)
 	if err != nil {
-		return false, fmt.Errorf("dbaas operator responded, but response is not a valid JSON payload")
+		return nil, fmt.Errorf("dbaas operator responded, but response is not a valid JSON payload")
 	}
 	if response.Error != "" {
-		return false, fmt.Errorf(response.Error)
-	}
-	if response.Result.Found {
-		return true, nil
+		return nil, fmt.Errorf(response.Error)
 	}
-	return false, nil
+	return response, nil
 }
 
 // TestDBaaSHTTPServer is a test server used to test dbaas-responses
@@ -111,7 +122,7 @@ func TestDBaaSHTTPServer() *httptest.Server {
 		res.Write([]byte(`{"result":{"found":false},"error":"no providers for dbaas environment development2"}`))
 	})
 	mux.HandleFunc("/postgres/production", func(res http.ResponseWriter, req *http.Request) {
-		res.Write([]byte(`{"result":{"found":true}}`))
+		res.Write([]byte(`{"result":{"found":true,"capabilities":{"readReplicas":true,"versions":["14","15"]}}}`))
 	})
 	mux.HandleFunc("/postgres/development", func(res http.ResponseWriter, req *http.Request) {
 		res.Write([]byte(`{"result":{"found":true}}`))
@@ -1,6 +1,7 @@
 package dbaasclient
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -67,7 +68,7 @@ func TestCheckDBaaSProvider(t *testing.T) {
 				RetryWaitMin: time.Duration(10) * time.Millisecond,
 				RetryWaitMax: time.Duration(50) * time.Millisecond,
 			})
-			got, err := d.CheckProvider(testURL, tt.args.dbaasType, tt.args.dbaasEnvironment)
+			got, _, err := d.CheckProvider(testURL, tt.args.dbaasType, tt.args.dbaasEnvironment)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CheckDBaaSProvider() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -79,6 +80,62 @@ func TestCheckDBaaSProvider(t *testing.T) {
 	}
 }
 
+func TestDBaaSProviderCapabilities(t *testing.T) {
+	type args struct {
+		dbaasType        string
+		dbaasEnvironment string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Capabilities
+		wantErr bool
+	}{
+		{
+			name: "test1 - provider that reports capabilities",
+			args: args{
+				dbaasType:        "postgres",
+				dbaasEnvironment: "production",
+			},
+			want: &Capabilities{ReadReplicas: true, Versions: []string{"14", "15"}},
+		},
+		{
+			name: "test2 - provider that doesn't report capabilities",
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "production",
+			},
+		},
+		{
+			name: "test3 - environment that doesn't exist",
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "development2",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := TestDBaaSHTTPServer()
+			defer ts.Close()
+			d := NewClient(Client{
+				RetryMax:     5,
+				RetryWaitMin: time.Duration(10) * time.Millisecond,
+				RetryWaitMax: time.Duration(50) * time.Millisecond,
+			})
+			_, got, err := d.CheckProvider(ts.URL, tt.args.dbaasType, tt.args.dbaasEnvironment)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckProvider() capabilities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCheckDBaaSHealth(t *testing.T) {
 	type args struct {
 		dbaasEndpoint string
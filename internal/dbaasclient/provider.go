@@ -0,0 +1,18 @@
+package dbaasclient
+
+// Provider is a backend that can answer which dbaas providers are available to a build.
+// The HTTP client talks to the dbaas-operator, and the static client answers from a file for clusters
+// that run a different database operator, or builds that are simulated
+type Provider interface {
+	// CheckHealth returns an error if the backend can't be used
+	CheckHealth(dbaasEndpoint string) error
+	// CheckProvider returns if a provider exists for the type and environment, and what the provider supports.
+	// nil capabilities are returned if the backend doesn't report any, an error is returned if the backend could not answer
+	CheckProvider(dbaasEndpoint, dbaasType, dbaasEnvironment string) (bool, *Capabilities, error)
+}
+
+// Capabilities are the optional features a dbaas provider supports
+type Capabilities struct {
+	ReadReplicas bool     `json:"readReplicas,omitempty"`
+	Versions     []string `json:"versions,omitempty"`
+}
@@ -0,0 +1,112 @@
+package dbaasclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// the answers that a static provider understands
+const (
+	AnswerFound    = "found"
+	AnswerNotFound = "notfound"
+	// the key used to answer any provider check that isn't explicitly defined
+	AnswerDefaultKey = "*"
+	// the key used to answer the health check, any answer other than `ok` is an unhealthy endpoint
+	AnswerHealthKey = "healthz"
+)
+
+// ProviderAnswer is the answer for a single provider check. It can be defined as just the answer, eg `found`,
+// or with the capabilities of the provider, eg `{answer: found, readReplicas: true, versions: ["10.6"]}`
+type ProviderAnswer struct {
+	Answer string `json:"answer"`
+	Capabilities
+}
+
+func (a *ProviderAnswer) UnmarshalJSON(data []byte) error {
+	var answer string
+	if err := json.Unmarshal(data, &answer); err == nil {
+		a.Answer = answer
+		return nil
+	}
+	type answerAlias ProviderAnswer
+	aa := answerAlias{}
+	if err := json.Unmarshal(data, &aa); err != nil {
+		return err
+	}
+	*a = ProviderAnswer(aa)
+	return nil
+}
+
+// ProviderAnswers are the responses a static provider gives, keyed by `type/environment`, eg `mariadb/production: found`.
+// An answer that is not `found` or `notfound` is returned as the error message from the provider
+type ProviderAnswers map[string]ProviderAnswer
+
+// LoadProviderAnswers reads the static provider responses from a yaml or json file
+func LoadProviderAnswers(file string) (ProviderAnswers, error) {
+	answers := ProviderAnswers{}
+	answersYAML, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read file %v: %v", file, err)
+	}
+	if err := yaml.Unmarshal(answersYAML, &answers); err != nil {
+		return nil, fmt.Errorf("error unmarshalling dbaas provider answers: %v", err)
+	}
+	return answers, nil
+}
+
+// StaticClient is the backend that answers from a fixed set of answers instead of calling a dbaas-operator,
+// the endpoint is ignored
+type StaticClient struct {
+	Answers ProviderAnswers
+}
+
+// NewStaticClient returns a static provider that responds from the provided answers.
+// If no answers are provided, every provider is found
+func NewStaticClient(answers ProviderAnswers) *StaticClient {
+	if answers == nil {
+		answers = ProviderAnswers{AnswerDefaultKey: {Answer: AnswerFound}}
+	}
+	return &StaticClient{
+		Answers: answers,
+	}
+}
+
+func (s *StaticClient) CheckHealth(dbaasEndpoint string) error {
+	if answer, ok := s.Answers[AnswerHealthKey]; ok && answer.Answer != "ok" {
+		return fmt.Errorf("static dbaas provider is unhealthy: %s", answer.Answer)
+	}
+	return nil
+}
+
+func (s *StaticClient) CheckProvider(dbaasEndpoint, dbaasType, dbaasEnvironment string) (bool, *Capabilities, error) {
+	answer, err := s.answer(dbaasType, dbaasEnvironment)
+	if err != nil || answer == nil {
+		return false, nil, err
+	}
+	if !answer.ReadReplicas && len(answer.Versions) == 0 {
+		return true, nil, nil
+	}
+	return true, &answer.Capabilities, nil
+}
+
+// answer returns the answer for a provider if it is found
+func (s *StaticClient) answer(dbaasType, dbaasEnvironment string) (*ProviderAnswer, error) {
+	answer, ok := s.Answers[fmt.Sprintf("%s/%s", dbaasType, dbaasEnvironment)]
+	if !ok {
+		answer, ok = s.Answers[AnswerDefaultKey]
+	}
+	if !ok {
+		return nil, nil
+	}
+	switch answer.Answer {
+	case AnswerFound:
+		return &answer, nil
+	case AnswerNotFound:
+		return nil, nil
+	}
+	return nil, errors.New(answer.Answer)
+}
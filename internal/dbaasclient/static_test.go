@@ -0,0 +1,213 @@
+package dbaasclient
+
+import (
+	"reflect"
+	"testing"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestLoadProviderAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    ProviderAnswers
+		wantErr bool
+	}{
+		{
+			name: "test1 - answers file",
+			file: "internal/testdata/simulate/dbaas-answers.yml",
+			want: ProviderAnswers{
+				"mariadb/production":  {Answer: AnswerFound},
+				"mariadb/development": {Answer: AnswerNotFound},
+				"postgres/production": {
+					Answer:       AnswerFound,
+					Capabilities: Capabilities{ReadReplicas: true, Versions: []string{"14", "15"}},
+				},
+			},
+		},
+		{
+			name:    "test2 - missing answers file",
+			file:    "internal/testdata/simulate/does-not-exist.yml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadProviderAnswers(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadProviderAnswers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadProviderAnswers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticCheckProvider(t *testing.T) {
+	type args struct {
+		dbaasType        string
+		dbaasEnvironment string
+	}
+	tests := []struct {
+		name    string
+		answers ProviderAnswers
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "test1 - no answers finds every provider",
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "development2",
+			},
+			want: true,
+		},
+		{
+			name: "test2 - provider found",
+			answers: ProviderAnswers{
+				"mariadb/production": {Answer: AnswerFound},
+			},
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "production",
+			},
+			want: true,
+		},
+		{
+			name: "test3 - provider not found",
+			answers: ProviderAnswers{
+				"mariadb/production":  {Answer: AnswerFound},
+				"mariadb/development": {Answer: AnswerNotFound},
+			},
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "development",
+			},
+			want: false,
+		},
+		{
+			name: "test4 - provider not in the answers",
+			answers: ProviderAnswers{
+				"mariadb/production": {Answer: AnswerFound},
+			},
+			args: args{
+				dbaasType:        "postgres",
+				dbaasEnvironment: "production",
+			},
+			want: false,
+		},
+		{
+			name: "test5 - default answer",
+			answers: ProviderAnswers{
+				"mariadb/production": {Answer: AnswerNotFound},
+				AnswerDefaultKey:     {Answer: AnswerFound},
+			},
+			args: args{
+				dbaasType:        "postgres",
+				dbaasEnvironment: "production",
+			},
+			want: true,
+		},
+		{
+			name: "test6 - error from the dbaas-operator",
+			answers: ProviderAnswers{
+				"mariadb/production": {Answer: "no providers for dbaas environment production"},
+			},
+			args: args{
+				dbaasType:        "mariadb",
+				dbaasEnvironment: "production",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewStaticClient(tt.answers)
+			got, _, err := d.CheckProvider("http://this-does-not-exist", tt.args.dbaasType, tt.args.dbaasEnvironment)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CheckProvider() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticProviderCapabilities(t *testing.T) {
+	answers := ProviderAnswers{
+		"mariadb/production":  {Answer: AnswerFound},
+		"mariadb/development": {Answer: AnswerNotFound},
+		"postgres/production": {
+			Answer:       AnswerFound,
+			Capabilities: Capabilities{ReadReplicas: true, Versions: []string{"14", "15"}},
+		},
+	}
+	tests := []struct {
+		name             string
+		dbaasType        string
+		dbaasEnvironment string
+		want             *Capabilities
+	}{
+		{
+			name:             "test1 - provider with capabilities",
+			dbaasType:        "postgres",
+			dbaasEnvironment: "production",
+			want:             &Capabilities{ReadReplicas: true, Versions: []string{"14", "15"}},
+		},
+		{
+			name:             "test2 - provider without capabilities",
+			dbaasType:        "mariadb",
+			dbaasEnvironment: "production",
+		},
+		{
+			name:             "test3 - provider not found",
+			dbaasType:        "mariadb",
+			dbaasEnvironment: "development",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewStaticClient(answers)
+			_, got, err := d.CheckProvider("", tt.dbaasType, tt.dbaasEnvironment)
+			if err != nil {
+				t.Errorf("CheckProvider() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckProvider() capabilities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticCheckHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers ProviderAnswers
+		wantErr bool
+	}{
+		{
+			name: "test1 - healthy without an answer",
+		},
+		{
+			name:    "test2 - unhealthy",
+			answers: ProviderAnswers{AnswerHealthKey: {Answer: "connection refused"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewStaticClient(tt.answers)
+			if err := d.CheckHealth("http://this-does-not-exist"); (err != nil) != tt.wantErr {
+				t.Errorf("CheckHealth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package dbaasconsumer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// the consumer custom resource that is created for each dbaas service type
+var consumers = map[string]schema.GroupVersionResource{
+	"mariadb-dbaas":  {Group: "mariadb.amazee.io", Version: "v1", Resource: "mariadbconsumers"},
+	"mongodb-dbaas":  {Group: "mongodb.amazee.io", Version: "v1", Resource: "mongodbconsumers"},
+	"postgres-dbaas": {Group: "postgres.amazee.io", Version: "v1", Resource: "postgresqlconsumers"},
+}
+
+// the annotation the dbaas operator sets on a consumer when it can't provision a database
+const failedAnnotation = "dbaas.amazee.io/failed"
+
+// ConsumerFailedError is returned when the dbaas operator couldn't provision the database of a consumer
+type ConsumerFailedError struct {
+	Service string
+}
+
+func (e *ConsumerFailedError) Error() string {
+	return fmt.Sprintf("failed to provision a database for %s, contact your support team to investigate", e.Service)
+}
+
+// Variables waits for the dbaas consumers of the build to be provisioned, and returns the credentials of each of them as
+// the variables that are added to the `lagoon-env` configmap. the variables are prefixed with the name of the service,
+// eg `MARIADB_HOST`, `MARIADB_USERNAME`, `MARIADB_PASSWORD`, `MARIADB_DATABASE`, `MARIADB_PORT`
+func Variables(
+	ctx context.Context,
+	client dynamic.Interface,
+	buildValues generator.BuildValues,
+	retries int,
+	wait time.Duration,
+) ([]lagoon.EnvironmentVariable, error) {
+	vars := []lagoon.EnvironmentVariable{}
+	for _, service := range buildValues.Services {
+		gvr, ok := consumers[service.Type]
+		if !ok || !service.IsDBaaS {
+			continue
+		}
+		consumer, err := waitForConsumer(ctx, client.Resource(gvr).Namespace(buildValues.Namespace), service.Name, retries, wait)
+		if err != nil {
+			return nil, err
+		}
+		prefix := strings.ToUpper(strings.ReplaceAll(service.Name, "-", "_"))
+		add := func(name string, fields ...string) {
+			value, _, _ := unstructured.NestedString(consumer.Object, fields...)
+			vars = append(vars, lagoon.EnvironmentVariable{Name: prefix + "_" + name, Value: value, Scope: "runtime"})
+		}
+		add("HOST", "spec", "consumer", "services", "primary")
+		add("USERNAME", "spec", "consumer", "username")
+		add("PASSWORD", "spec", "consumer", "password")
+		add("DATABASE", "spec", "consumer", "database")
+		add("PORT", "spec", "provider", "port")
+		switch service.Type {
+		case "mongodb-dbaas":
+			add("AUTHSOURCE", "spec", "provider", "auth", "source")
+			add("AUTHMECHANISM", "spec", "provider", "auth", "mechanism")
+			tls, _, _ := unstructured.NestedBool(consumer.Object, "spec", "provider", "auth", "tls")
+			vars = append(vars, lagoon.EnvironmentVariable{Name: prefix + "_AUTHTLS", Value: fmt.Sprintf("%t", tls), Scope: "runtime"})
+		default:
+			// the operator can support multiple read replica hosts, these are comma separated
+			replicas, _, _ := unstructured.NestedStringSlice(consumer.Object, "spec", "consumer", "services", "replicas")
+			if len(replicas) > 0 {
+				vars = append(vars, lagoon.EnvironmentVariable{Name: prefix + "_READREPLICA_HOSTS", Value: strings.Join(replicas, ","), Scope: "runtime"})
+			}
+		}
+	}
+	return vars, nil
+}
+
+// waitForConsumer waits until the operator has provisioned the database of the consumer
+func waitForConsumer(
+	ctx context.Context,
+	client dynamic.ResourceInterface,
+	name string,
+	retries int,
+	wait time.Duration,
+) (*unstructured.Unstructured, error) {
+	for attempt := 1; ; attempt++ {
+		consumer, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("unable to get the dbaas consumer %s: %v", name, err)
+		}
+		if err == nil {
+			if consumer.GetAnnotations()[failedAnnotation] == "true" {
+				return nil, &ConsumerFailedError{Service: name}
+			}
+			if database, _, _ := unstructured.NestedString(consumer.Object, "spec", "consumer", "database"); database != "" {
+				return consumer, nil
+			}
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("timeout waiting for the dbaas consumer %s to be provisioned", name)
+		}
+		// the progress goes to stderr, the output of the commands that wait for the consumers can be captured by the build
+		fmt.Fprintf(os.Stderr, "Service for %s not available yet, waiting for %s\n", name, wait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
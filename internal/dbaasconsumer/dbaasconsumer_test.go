@@ -0,0 +1,149 @@
+package dbaasconsumer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "example-project-main"
+
+func testConsumer(kind, apiVersion, name string, spec map[string]interface{}, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(testNamespace)
+	u.SetAnnotations(annotations)
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	return u
+}
+
+func TestVariables(t *testing.T) {
+	mariadb := testConsumer("MariaDBConsumer", "mariadb.amazee.io/v1", "mariadb", map[string]interface{}{
+		"consumer": map[string]interface{}{
+			"database": "drupal",
+			"username": "drupal-user",
+			"password": "drupal-pass",
+			"services": map[string]interface{}{
+				"primary":  "mariadb-primary.example.com",
+				"replicas": []interface{}{"mariadb-replica-1.example.com", "mariadb-replica-2.example.com"},
+			},
+		},
+		"provider": map[string]interface{}{
+			"port": "3306",
+		},
+	}, nil)
+	mongo := testConsumer("MongoDBConsumer", "mongodb.amazee.io/v1", "mongo-db", map[string]interface{}{
+		"consumer": map[string]interface{}{
+			"database": "app",
+			"username": "app-user",
+			"password": "app-pass",
+			"services": map[string]interface{}{
+				"primary": "mongo.example.com",
+			},
+		},
+		"provider": map[string]interface{}{
+			"port": "27017",
+			"auth": map[string]interface{}{
+				"source":    "admin",
+				"mechanism": "SCRAM-SHA-1",
+				"tls":       true,
+			},
+		},
+	}, nil)
+	tests := []struct {
+		name      string
+		services  []generator.ServiceValues
+		consumers []runtime.Object
+		want      []lagoon.EnvironmentVariable
+		wantErr   bool
+	}{
+		{
+			name: "test1 - mariadb and mongodb consumers",
+			services: []generator.ServiceValues{
+				{Name: "cli", Type: "cli"},
+				{Name: "mariadb", Type: "mariadb-dbaas", IsDBaaS: true},
+				{Name: "mongo-db", Type: "mongodb-dbaas", IsDBaaS: true},
+			},
+			consumers: []runtime.Object{mariadb, mongo},
+			want: []lagoon.EnvironmentVariable{
+				{Name: "MARIADB_HOST", Value: "mariadb-primary.example.com", Scope: "runtime"},
+				{Name: "MARIADB_USERNAME", Value: "drupal-user", Scope: "runtime"},
+				{Name: "MARIADB_PASSWORD", Value: "drupal-pass", Scope: "runtime"},
+				{Name: "MARIADB_DATABASE", Value: "drupal", Scope: "runtime"},
+				{Name: "MARIADB_PORT", Value: "3306", Scope: "runtime"},
+				{Name: "MARIADB_READREPLICA_HOSTS", Value: "mariadb-replica-1.example.com,mariadb-replica-2.example.com", Scope: "runtime"},
+				{Name: "MONGO_DB_HOST", Value: "mongo.example.com", Scope: "runtime"},
+				{Name: "MONGO_DB_USERNAME", Value: "app-user", Scope: "runtime"},
+				{Name: "MONGO_DB_PASSWORD", Value: "app-pass", Scope: "runtime"},
+				{Name: "MONGO_DB_DATABASE", Value: "app", Scope: "runtime"},
+				{Name: "MONGO_DB_PORT", Value: "27017", Scope: "runtime"},
+				{Name: "MONGO_DB_AUTHSOURCE", Value: "admin", Scope: "runtime"},
+				{Name: "MONGO_DB_AUTHMECHANISM", Value: "SCRAM-SHA-1", Scope: "runtime"},
+				{Name: "MONGO_DB_AUTHTLS", Value: "true", Scope: "runtime"},
+			},
+		},
+		{
+			name: "test2 - single database services have no consumer",
+			services: []generator.ServiceValues{
+				{Name: "mariadb", Type: "mariadb-single", IsSingle: true},
+			},
+			want: []lagoon.EnvironmentVariable{},
+		},
+		{
+			name: "test3 - consumer that failed to provision",
+			services: []generator.ServiceValues{
+				{Name: "postgres", Type: "postgres-dbaas", IsDBaaS: true},
+			},
+			consumers: []runtime.Object{
+				testConsumer("PostgreSQLConsumer", "postgres.amazee.io/v1", "postgres", nil, map[string]string{
+					"dbaas.amazee.io/failed": "true",
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 - consumer that is never provisioned",
+			services: []generator.ServiceValues{
+				{Name: "postgres", Type: "postgres-dbaas", IsDBaaS: true},
+			},
+			consumers: []runtime.Object{
+				testConsumer("PostgreSQLConsumer", "postgres.amazee.io/v1", "postgres", nil, nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - consumer that doesn't exist",
+			services: []generator.ServiceValues{
+				{Name: "mariadb", Type: "mariadb-dbaas", IsDBaaS: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.consumers...)
+			buildValues := generator.BuildValues{
+				Namespace: testNamespace,
+				Services:  tt.services,
+			}
+			got, err := Variables(context.Background(), client, buildValues, 2, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Variables() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
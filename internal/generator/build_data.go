@@ -38,6 +38,26 @@ func collectBuildVariables(buildValues BuildValues) []lagoon.EnvironmentVariable
 	return vars
 }
 
+// the lagoon core api and ssh-portal details are provided to the build by the remote-controller if they are available
+var lagoonCoreConfigVariables = []string{
+	"LAGOON_CONFIG_API_HOST",
+	"LAGOON_CONFIG_TOKEN_HOST",
+	"LAGOON_CONFIG_TOKEN_PORT",
+	"LAGOON_CONFIG_SSH_HOST",
+	"LAGOON_CONFIG_SSH_PORT",
+}
+
+// this collects the lagoon core api and ssh-portal details that are injected into the `lagoon-env` configmap
+func collectCoreConfigVariables(debug bool) []lagoon.EnvironmentVariable {
+	vars := []lagoon.EnvironmentVariable{}
+	for _, name := range lagoonCoreConfigVariables {
+		if value := helpers.GetEnv(name, "", debug); value != "" {
+			vars = append(vars, lagoon.EnvironmentVariable{Name: name, Value: value, Scope: "runtime"})
+		}
+	}
+	return vars
+}
+
 // this creates all the build arguments that an image build can consume
 func collectImageBuildArguments(buildValues BuildValues) map[string]string {
 	buildArgs := map[string]string{}
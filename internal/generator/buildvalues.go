@@ -11,6 +11,14 @@ const (
 	DefaultImagePullSecret = "lagoon-internal-registry-secret"
 )
 
+// the dbaas fallback policies set by `LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE`, these define if a service
+// should fall back to a single pod or fail the build when a dbaas provider can't be used
+const (
+	DBaaSFallbackPolicyFallback       = "fallback"
+	DBaaSFallbackPolicyFail           = "fail"
+	DBaaSFallbackPolicyFailProduction = "fail-production-only"
+)
+
 // BuildValues is the values file data generated by the lagoon build
 type BuildValues struct {
 	SourceRepository              string                       `json:"sourceRepository" description:"the source repository for the project"`
@@ -28,6 +36,7 @@ type BuildValues struct {
 	IsActiveEnvironment           bool                         `json:"isActiveEnvironment" activestandby:"true" description:"flag to determine if this environment is currently an active environment"`
 	IsStandbyEnvironment          bool                         `json:"isStandbyEnvironment" activestandby:"true" description:"flag to determine if this environment is currently a standby environment"`
 	PodSecurityContext            PodSecurityContext           `json:"podSecurityContext" description:"stores the podsecuritycontext overrides"`
+	SecurityProfile               string                       `json:"securityProfile" description:"the security profile used to harden the containers of services, only restricted is supported"`
 	Branch                        string                       `json:"branch" buildtype:"branch" description:"the branch used for this environment"`
 	PRNumber                      string                       `json:"prNumber" buildtype:"pullrequest" description:"pullrequest number"`
 	PRTitle                       string                       `json:"prTitle" buildtype:"pullrequest" description:"title of the pullrequest"`
@@ -51,6 +60,7 @@ type BuildValues struct {
 	ServiceTypeOverrides          *lagoon.EnvironmentVariable  `json:"serviceTypeOverrides" description:"stores any service type overrides"`
 	DBaaSEnvironmentTypeOverrides *lagoon.EnvironmentVariable  `json:"dbaasEnvironmentTypeOverrides" description:"stores any dbaas type overrides"`
 	DBaaSFallbackSingle           bool                         `json:"dbaasFallbackSingle" description:"the fallback flag to define if a single pod should be used if no provider is found"`
+	DBaaSFallbackPolicy           string                       `json:"dbaasFallbackPolicy" description:"the dbaas fallback policy, one of fallback, fail, or fail-production-only"`
 	IngressClass                  string                       `json:"ingressClass" description:"the ingress class used for this environment"`
 	TaskScaleMaxIterations        int                          `json:"taskScaleMaxIterations" description:"the number of attempts to wait for pods to scale for pre and post rollout tasks"`
 	TaskScaleWaitTime             int                          `json:"taskScaleWaitTime" description:"the time to wait for pods to scale for pre and post rollout tasks"`
@@ -59,20 +69,26 @@ type BuildValues struct {
 	DynamicDBaaSSecrets           []string                     `json:"dynamicDBaaSSecrets" description:"stores any dynamic dbaas secret definitions"`
 	ImageCache                    string                       `json:"imageCache" description:"if an imagecache has been provided for images outside of the imageregistry"`
 	DefaultBackupSchedule         string                       `json:"defaultBackupSchedule" description:"the default backup scheduled"`
-	DBaaSClient                   *dbaasclient.Client          `json:"-" description:"used to store connection information for the dbaas operator endpoint"`
+	DBaaSClient                   dbaasclient.Provider         `json:"-" description:"the dbaas provider backend used to check for dbaas providers"`
 	ImageReferences               map[string]string            `json:"imageReferences" description:"the post image build phase storage location of images for this build"`
 	Resources                     Resources                    `json:"resources" description:"this stores resource overrides for this environment"`
+	PriorityClassPolicy           PriorityClassPolicy          `json:"priorityClassPolicy" description:"the policy that assigns priority classes to the pods of services, cronjobs, and tasks"`
 	CronjobsDisabled              bool                         `json:"cronjobsDisabled" description:"this controls whether cronjobs are enabled for this environment or not"`
 	FeatureFlags                  map[string]bool              `json:"-" description:"these are used by templating systems to turn on or off certain functionality based on if feature flags are defined"`
 	ImageRegistry                 string                       `json:"imageRegistry" description:"the image registry in use for this environment, usually harbor"`
 	DockerBuildKit                bool                         `json:"dockerBuildKit" description:"the flag to determine if docker buildkit is used"`
 	ImageBuildArguments           map[string]string            `json:"imageBuildArguments" description:"where the calculated image build arguments are stored"`
 	EnvironmentVariables          []lagoon.EnvironmentVariable `json:"environmentVariables" description:"the merged project and environment variables for this environment"`
+	DBaaSVariables                []lagoon.EnvironmentVariable `json:"-" description:"the credentials of the provisioned dbaas consumers that are added to the lagoon-env configmap"`
+	APIVariables                  []lagoon.EnvironmentVariable `json:"-" description:"the merged project and environment variables from the lagoon api, these replace the build variables in the lagoon-env configmap"`
 	LagoonYAML                    lagoon.YAML                  `json:"lagoonYAML" description:"the unmarshalled lagoon yaml file"`
 	PromotionSourceEnvironment    string                       `json:"promotionSourceEnvironment" buildtype:"promote" description:"the promotion source environment to pull images from"`
 	IsCI                          bool                         `json:"isCI" description:"this controls aspects of the environment or build depending on if a CI job"`
 	RWX2RWO                       bool                         `json:"RWX2RWO" description:"this controls whether the ReadWriteMany to ReadWriteOnce override should be used"`
 	IsolationNetworkPolicy        bool                         `json:"isolationNetworkPolicy" description:"this controls whether isolation network policies should be enabled"`
+	NetworkPolicies               *lagoon.NetworkPolicies      `json:"networkPolicies,omitempty" description:"the service and egress network policies declared in the .lagoon.yml"`
+	StatefulSets                  bool                         `json:"statefulSets" description:"this controls whether service types that support them are templated as statefulsets instead of deployments"`
+	StatefulSetMigration          bool                         `json:"statefulSetMigration" description:"this controls whether statefulsets mount the existing persistent volume claim of a service instead of using a volume claim template"`
 	ContainerRegistry             []ContainerRegistry          `json:"containerRegistry" description:"this contains any private container registries that may exist within the environment that need to be logged into"`
 	RoutesAutogeneratePrefixes    []string                     `json:"routesAutogeneratePrefixes"`
 	BackupsEnabled                bool                         `json:"backupsEnabled"`
@@ -88,6 +104,7 @@ type Resources struct {
 }
 
 type ResourceLimits struct {
+	CPU              string `json:"cpu"`
 	Memory           string `json:"memory"`
 	EphemeralStorage string `json:"ephemeral-storage"`
 }
@@ -107,6 +124,9 @@ type ContainerRegistry struct {
 	IsDockerHub    *bool  `json:"isDockerHub" description:"if this registry is dockerhub or not"`
 }
 
+// SecurityProfileRestricted hardens the containers of services so that they pass the restricted pod security standard
+const SecurityProfileRestricted = "restricted"
+
 type PodSecurityContext struct {
 	FsGroup        int64 `json:"fsGroup"`
 	RunAsGroup     int64 `json:"runAsGroup"`
@@ -170,6 +190,17 @@ type ServiceValues struct {
 	CronjobUseSpotInstances                bool                    `json:"cronjobUseSpot"`
 	CronjobForceSpotInstances              bool                    `json:"cronjobForceUseSpot"`
 	Replicas                               int32                   `json:"replicas"`
+	ReplicaSpread                          string                  `json:"replicaSpread,omitempty"`
+	PriorityClassName                      string                  `json:"priorityClassName,omitempty"`
+	CronjobPriorityClassName               string                  `json:"cronjobPriorityClassName,omitempty"`
+	TaskPriorityClassName                  string                  `json:"taskPriorityClassName,omitempty"`
+	StatefulSet                            bool                    `json:"statefulSet"`
+	Autoscaling                            *lagoon.Autoscaling     `json:"autoscaling,omitempty"`
+	Resources                              *lagoon.Resources       `json:"resources,omitempty"`
+	Probes                                 *lagoon.Probes          `json:"probes,omitempty"`
+	Lifecycle                              *lagoon.Lifecycle       `json:"lifecycle,omitempty"`
+	Sidecars                               []lagoon.Container      `json:"sidecars,omitempty"`
+	InitContainers                         []lagoon.Container      `json:"initContainers,omitempty"`
 	LinkedService                          *ServiceValues          `json:"linkedService"`
 	PodSecurityContext                     PodSecurityContext      `json:"podSecurityContext"`
 	AdditionalServicePorts                 []AdditionalServicePort `json:"additionalServicePorts,omitempty"`
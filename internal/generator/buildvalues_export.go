@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+)
+
+// RedactedValue replaces the value of anything secret in the exported build values
+const RedactedValue = "**REDACTED**"
+
+// Redacted returns a copy of the build values with any secrets removed, so that the values can be safely exported.
+// The values of project and environment variables are also redacted as they are commonly used to store secrets
+func (b BuildValues) Redacted() BuildValues {
+	redact := func(value string) string {
+		if value == "" {
+			return value
+		}
+		return RedactedValue
+	}
+	// the copy shares any slices and maps with the original, so those that are redacted are copied first
+	r := b
+	r.SSHPrivateKey = redact(r.SSHPrivateKey)
+	r.ContainerRegistry = append([]ContainerRegistry(nil), b.ContainerRegistry...)
+	for idx := range r.ContainerRegistry {
+		r.ContainerRegistry[idx].Password = redact(r.ContainerRegistry[idx].Password)
+	}
+	if b.LagoonYAML.ContainerRegistries != nil {
+		r.LagoonYAML.ContainerRegistries = map[string]lagoon.ContainerRegistry{}
+		for name, cr := range b.LagoonYAML.ContainerRegistries {
+			cr.Password = redact(cr.Password)
+			r.LagoonYAML.ContainerRegistries[name] = cr
+		}
+	}
+	r.Backup.CustomLocation.BackupLocationAccessKey = redact(r.Backup.CustomLocation.BackupLocationAccessKey)
+	r.Backup.CustomLocation.BackupLocationSecretKey = redact(r.Backup.CustomLocation.BackupLocationSecretKey)
+	r.Backup.CustomLocation.RestoreLocationAccessKey = redact(r.Backup.CustomLocation.RestoreLocationAccessKey)
+	r.Backup.CustomLocation.RestoreLocationSecretKey = redact(r.Backup.CustomLocation.RestoreLocationSecretKey)
+	variables := map[string]bool{}
+	r.EnvironmentVariables = append([]lagoon.EnvironmentVariable(nil), b.EnvironmentVariables...)
+	for idx := range r.EnvironmentVariables {
+		variables[r.EnvironmentVariables[idx].Name] = true
+		r.EnvironmentVariables[idx].Value = redact(r.EnvironmentVariables[idx].Value)
+	}
+	// build arguments are sourced from the variables too, the ones lagoon calculates are safe to keep except the ssh key
+	variables["LAGOON_SSH_PRIVATE_KEY"] = true
+	if b.ImageBuildArguments != nil {
+		r.ImageBuildArguments = map[string]string{}
+		for name, value := range b.ImageBuildArguments {
+			if variables[name] {
+				value = redact(value)
+			}
+			r.ImageBuildArguments[name] = value
+		}
+	}
+	return r
+}
+
+// JSONSchema is a JSON Schema document, only the keywords needed to describe the build values are supported
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+	BuildType            string                 `json:"x-lagoon-buildtype,omitempty"`
+	ActiveStandby        bool                   `json:"x-lagoon-activestandby,omitempty"`
+	AllOf                []*JSONSchema          `json:"allOf,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// BuildValuesSchema generates the JSON Schema of the build values. The `description` and `deprecated` struct tags are
+// used for the standard keywords, the `buildtype` and `activestandby` tags are added as lagoon specific keywords
+func BuildValuesSchema() *JSONSchema {
+	definitions := map[string]*JSONSchema{}
+	ref := schemaForType(reflect.TypeOf(BuildValues{}), definitions)
+	return &JSONSchema{
+		Schema:      "http://json-schema.org/draft-07/schema#",
+		Title:       "BuildValues",
+		Description: "the values file data generated by the lagoon build",
+		AllOf:       []*JSONSchema{ref},
+		Definitions: definitions,
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaForType returns the schema for a type, named structs are added to the definitions and referenced
+// so that recursive types like the linked service of a service can be described
+func schemaForType(t reflect.Type, definitions map[string]*JSONSchema) *JSONSchema {
+	if t.Kind() == reflect.Pointer {
+		return schemaForType(t.Elem(), definitions)
+	}
+	// types with custom marshalling can't be described by their fields
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &JSONSchema{Type: "string"}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &JSONSchema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// byte slices are base64 encoded
+			return &JSONSchema{Type: "string"}
+		}
+		return &JSONSchema{Type: "array", Items: schemaForType(t.Elem(), definitions)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: schemaForType(t.Elem(), definitions)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, definitions)
+		}
+		name := fmt.Sprintf("%s.%s", path.Base(t.PkgPath()), t.Name())
+		if _, ok := definitions[name]; !ok {
+			// reserve the name before the fields are walked, in case the struct refers to itself
+			definitions[name] = &JSONSchema{}
+			*definitions[name] = *structSchema(t, definitions)
+		}
+		return &JSONSchema{Ref: fmt.Sprintf("#/definitions/%s", name)}
+	}
+	// anything else, like interfaces, can hold any value
+	return &JSONSchema{}
+}
+
+// structSchema returns the schema of the exported fields of a struct, following the encoding/json naming rules
+func structSchema(t reflect.Type, definitions map[string]*JSONSchema) *JSONSchema {
+	s := &JSONSchema{
+		Type:       "object",
+		Properties: map[string]*JSONSchema{},
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// embedded structs without a name have their fields promoted into the parent
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range structSchema(ft, definitions).Properties {
+					s.Properties[k] = v
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fs := schemaForType(field.Type, definitions)
+		if fs.Ref != "" {
+			// keywords alongside a $ref are ignored in draft-07, so the reference is wrapped
+			fs = &JSONSchema{AllOf: []*JSONSchema{fs}}
+		}
+		fs.Description = field.Tag.Get("description")
+		fs.Deprecated = field.Tag.Get("deprecated") == "true"
+		fs.BuildType = field.Tag.Get("buildtype")
+		fs.ActiveStandby = field.Tag.Get("activestandby") == "true"
+		s.Properties[name] = fs
+	}
+	return s
+}
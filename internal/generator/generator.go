@@ -10,6 +10,8 @@ import (
 	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
 	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	machineryns "github.com/uselagoon/machinery/utils/namespace"
 )
 
 type Generator struct {
@@ -55,7 +57,9 @@ type GeneratorInput struct {
 	IgnoreNonStringKeyErrors   bool
 	IgnoreMissingEnvFiles      bool
 	Debug                      bool
-	DBaaSClient                *dbaasclient.Client
+	DBaaSClient                dbaasclient.Provider
+	DBaaSProviderFile          string
+	CustomServiceTypesFile     string
 	ImageReferences            map[string]string
 	Namespace                  string
 	DefaultBackupSchedule      string
@@ -66,6 +70,7 @@ type GeneratorInput struct {
 	DynamicDBaaSSecrets        []string
 	ImageCacheBuildArgsJSON    string
 	SSHPrivateKey              string
+	Simulate                   bool
 }
 
 func NewGenerator(
@@ -132,10 +137,18 @@ func NewGenerator(
 	// try source the namespace from the generator, but whatever is defined in the service account location
 	// should be used if one exists, falls back to whatever came in via generator
 	namespace := helpers.GetEnv("NAMESPACE", generator.Namespace, generator.Debug)
-	namespace, err := helpers.GetNamespace(namespace, "/var/run/secrets/kubernetes.io/serviceaccount/namespace")
-	if err != nil {
-		// a file was found, but there was an issue accessing it
-		return nil, err
+	var err error
+	if generator.Simulate {
+		// a simulated build has no service account, so if no namespace is provided use the one lagoon would create
+		if namespace == "" {
+			namespace = machineryns.GenerateNamespaceName("", environmentName, projectName, "", "lagoon", false)
+		}
+	} else {
+		namespace, err = helpers.GetNamespace(namespace, "/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err != nil {
+			// a file was found, but there was an issue accessing it
+			return nil, err
+		}
 	}
 
 	buildValues.Backup.K8upVersion = helpers.GetEnv("K8UP_VERSION", generator.BackupConfiguration.K8upVersion, generator.Debug)
@@ -155,6 +168,22 @@ func NewGenerator(
 
 	//add the dbaas client to build values too
 	buildValues.DBaaSClient = generator.DBaaSClient
+	// clusters that don't run the dbaas-operator can provide the dbaas providers they support in a file instead
+	dbaasProviderFile := helpers.GetEnv("DBAAS_PROVIDER_FILE", generator.DBaaSProviderFile, generator.Debug)
+	if dbaasProviderFile != "" {
+		answers, err := dbaasclient.LoadProviderAnswers(dbaasProviderFile)
+		if err != nil {
+			return nil, err
+		}
+		buildValues.DBaaSClient = dbaasclient.NewStaticClient(answers)
+	}
+	// clusters can support additional service types by providing their definitions in a file
+	customServiceTypesFile := helpers.GetEnv("CUSTOM_SERVICE_TYPES_FILE", generator.CustomServiceTypesFile, generator.Debug)
+	if customServiceTypesFile != "" {
+		if err := servicetypes.LoadCustomServiceTypes(customServiceTypesFile); err != nil {
+			return nil, err
+		}
+	}
 
 	buildValues.DefaultBackupSchedule = defaultBackupSchedule
 
@@ -253,8 +282,10 @@ func NewGenerator(
 	json.Unmarshal([]byte(projectVariables), &projectVars)
 	json.Unmarshal([]byte(environmentVariables), &envVars)
 	mergedVariables := lagoon.MergeVariables(projectVars, envVars)
+	buildValues.APIVariables = mergedVariables
 	// collect a bunch of the default LAGOON_X based build variables that are injected into `lagoon-env` and make them available
 	configVars := collectBuildVariables(buildValues)
+	configVars = append(configVars, collectCoreConfigVariables(generator.Debug)...)
 	// add the calculated build runtime variables into the existing variable slice
 	// this will later be used to add `runtime|global` scope into the `lagoon-env` configmap
 	buildValues.EnvironmentVariables = lagoon.MergeVariables(mergedVariables, configVars)
@@ -282,6 +313,19 @@ func NewGenerator(
 		buildValues.IsolationNetworkPolicy = true
 	}
 
+	// check for statefulsets, disabled by default. existing environments should use `migrate` so that the statefulsets
+	// keep using the persistent volume claims the deployments used, as volume claim templates would create new empty volumes.
+	// applying `enabled` is refused while the persistent volume claim of a deployment still exists.
+	// the persistent volume claims the deployments used are never removed by the cleanup, so the data can still be recovered
+	statefulSets := CheckFeatureFlag("STATEFULSETS", buildValues.EnvironmentVariables, generator.Debug)
+	switch statefulSets {
+	case "enabled":
+		buildValues.StatefulSets = true
+	case "migrate":
+		buildValues.StatefulSets = true
+		buildValues.StatefulSetMigration = true
+	}
+
 	// check for imagecache override, disabled by default
 	imageCache := CheckFeatureFlag("IMAGECACHE_REGISTRY", buildValues.EnvironmentVariables, generator.Debug)
 	if imageCache != "" {
@@ -325,11 +369,32 @@ func NewGenerator(
 		buildValues.PodSecurityContext.OnRootMismatch = true
 	}
 
+	// check for a security profile, the restricted profile hardens the containers so the workloads pass the restricted pod
+	// security standard, which doesn't allow containers to run as root so rootless workloads must be enabled too
+	securityProfile := CheckFeatureFlag("SECURITY_PROFILE", buildValues.EnvironmentVariables, generator.Debug)
+	switch securityProfile {
+	case "":
+	case SecurityProfileRestricted:
+		if !buildValues.FeatureFlags["rootlessworkloads"] {
+			return nil, fmt.Errorf("the %s security profile requires rootless workloads to be enabled", SecurityProfileRestricted)
+		}
+		buildValues.SecurityProfile = securityProfile
+	default:
+		return nil, fmt.Errorf("the security profile %s is not valid, the only supported profile is %s", securityProfile, SecurityProfileRestricted)
+	}
+
 	// check admin features for resources
+	buildValues.Resources.Limits.CPU = CheckAdminFeatureFlag("CONTAINER_CPU_LIMIT", false)
 	buildValues.Resources.Limits.Memory = CheckAdminFeatureFlag("CONTAINER_MEMORY_LIMIT", false)
 	buildValues.Resources.Limits.EphemeralStorage = CheckAdminFeatureFlag("EPHEMERAL_STORAGE_LIMIT", false)
 	buildValues.Resources.Requests.EphemeralStorage = CheckAdminFeatureFlag("EPHEMERAL_STORAGE_REQUESTS", false)
 	// validate that what is provided
+	if buildValues.Resources.Limits.CPU != "" {
+		err := ValidateResourceQuantity(buildValues.Resources.Limits.CPU)
+		if err != nil {
+			return nil, fmt.Errorf("provided cpu limit %s is not a valid resource quantity", buildValues.Resources.Limits.CPU)
+		}
+	}
 	if buildValues.Resources.Limits.Memory != "" {
 		err := ValidateResourceQuantity(buildValues.Resources.Limits.Memory)
 		if err != nil {
@@ -349,6 +414,13 @@ func NewGenerator(
 		}
 	}
 
+	// check admin features for the priority classes of services, cronjobs, and tasks
+	priorityClassPolicy, err := getPriorityClassPolicy(generator.Debug)
+	if err != nil {
+		return nil, err
+	}
+	buildValues.PriorityClassPolicy = priorityClassPolicy
+
 	// get any variables from the API here that could be used to influence a build or services within the environment
 	// collect docker buildkit value
 	dockerBuildKit, _ := lagoon.GetLagoonVariable("DOCKER_BUILDKIT", []string{"build"}, buildValues.EnvironmentVariables)
@@ -392,9 +464,23 @@ func NewGenerator(
 		}
 	}
 
-	// @TODO: eventually fail builds if this is not set https://github.com/uselagoon/build-deploy-tool/issues/56
-	// lagoonDBaaSFallbackSingle, _ := lagoon.GetLagoonVariable("LAGOON_FEATURE_FLAG_DBAAS_FALLBACK_SINGLE", nil, buildValues.EnvironmentVariables)
-	// buildValues.DBaaSFallbackSingle = helpers.StrToBool(lagoonDBaaSFallbackSingle.Value)
+	// check what should happen if a dbaas provider can't be used, falls back to a single pod by default
+	dbaasFallbackPolicy := CheckFeatureFlag("DBAAS_FALLBACK_SINGLE", buildValues.EnvironmentVariables, generator.Debug)
+	switch dbaasFallbackPolicy {
+	case "", "enabled", DBaaSFallbackPolicyFallback:
+		buildValues.DBaaSFallbackPolicy = DBaaSFallbackPolicyFallback
+	case "disabled", DBaaSFallbackPolicyFail:
+		buildValues.DBaaSFallbackPolicy = DBaaSFallbackPolicyFail
+	case DBaaSFallbackPolicyFailProduction:
+		buildValues.DBaaSFallbackPolicy = DBaaSFallbackPolicyFailProduction
+	default:
+		return nil, fmt.Errorf(
+			"unsupported dbaas fallback policy %s, must be one of %s, %s, or %s",
+			dbaasFallbackPolicy, DBaaSFallbackPolicyFallback, DBaaSFallbackPolicyFail, DBaaSFallbackPolicyFailProduction,
+		)
+	}
+	buildValues.DBaaSFallbackSingle = buildValues.DBaaSFallbackPolicy == DBaaSFallbackPolicyFallback ||
+		(buildValues.DBaaSFallbackPolicy == DBaaSFallbackPolicyFailProduction && buildValues.EnvironmentType != "production")
 
 	/* start backups configuration */
 	err = generateBackupValues(&buildValues, buildValues.EnvironmentVariables, generator.Debug)
@@ -420,6 +506,12 @@ func NewGenerator(
 		}
 	}
 	buildValues.ImageBuildArguments = collectImageBuildArguments(buildValues)
+
+	// the network policies reference the services, so they are checked once the services are generated
+	buildValues.NetworkPolicies, err = getNetworkPolicies(&buildValues)
+	if err != nil {
+		return nil, err
+	}
 	/* end compose->service configuration */
 
 	/* start route generation */
@@ -111,8 +111,35 @@ func GenerateInput(rootCmd cobra.Command, debug bool) (GeneratorInput, error) {
 	if err != nil {
 		return GeneratorInput{}, fmt.Errorf("error reading default-backup-schedule flag: %v", err)
 	}
+	dbaasProviderFile, err := rootCmd.PersistentFlags().GetString("dbaas-provider-file")
+	if err != nil {
+		return GeneratorInput{}, fmt.Errorf("error reading dbaas-provider-file flag: %v", err)
+	}
+	customServiceTypesFile, err := rootCmd.PersistentFlags().GetString("custom-service-types-file")
+	if err != nil {
+		return GeneratorInput{}, fmt.Errorf("error reading custom-service-types-file flag: %v", err)
+	}
+	simulate, err := rootCmd.PersistentFlags().GetBool("simulate")
+	if err != nil {
+		return GeneratorInput{}, fmt.Errorf("error reading simulate flag: %v", err)
+	}
+	simulateDBaaSAnswers, err := rootCmd.PersistentFlags().GetString("simulate-dbaas-answers")
+	if err != nil {
+		return GeneratorInput{}, fmt.Errorf("error reading simulate-dbaas-answers flag: %v", err)
+	}
 	// create a dbaas client with the default configuration
-	dbaas := dbaasclient.NewClient(dbaasclient.Client{})
+	var dbaas dbaasclient.Provider = dbaasclient.NewClient(dbaasclient.Client{})
+	if simulate {
+		// a simulated build answers the dbaas provider checks from the answers file instead of the dbaas-operator
+		var answers dbaasclient.ProviderAnswers
+		if simulateDBaaSAnswers != "" {
+			answers, err = dbaasclient.LoadProviderAnswers(simulateDBaaSAnswers)
+			if err != nil {
+				return GeneratorInput{}, err
+			}
+		}
+		dbaas = dbaasclient.NewStaticClient(answers)
+	}
 	return GeneratorInput{
 		Debug:                    debug,
 		LagoonYAML:               lagoonYAML,
@@ -139,7 +166,10 @@ func GenerateInput(rootCmd cobra.Command, debug bool) (GeneratorInput, error) {
 		IgnoreMissingEnvFiles:    ignoreMissingEnvFiles,
 		IgnoreNonStringKeyErrors: ignoreNonStringKeyErrors,
 		DBaaSClient:              dbaas,
+		DBaaSProviderFile:        dbaasProviderFile,
+		CustomServiceTypesFile:   customServiceTypesFile,
 		DefaultBackupSchedule:    defaultBackupSchedule,
+		Simulate:                 simulate,
 	}, nil
 }
 
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// getNetworkPolicies returns the network policies declared in the .lagoon.yml file, the network policies of an environment
+// replace any that are declared for the whole project.
+// The services in the policies are the docker-compose service names, these are changed to the names of the services
+// that the pods are labelled with, so the services must be generated before this is called
+func getNetworkPolicies(buildValues *BuildValues) (*lagoon.NetworkPolicies, error) {
+	declared := buildValues.LagoonYAML.NetworkPolicies
+	if env, ok := buildValues.LagoonYAML.Environments[buildValues.Environment]; ok && env.NetworkPolicies != nil {
+		declared = env.NetworkPolicies
+	}
+	if declared == nil {
+		return nil, nil
+	}
+	serviceNames := map[string]string{}
+	for _, service := range buildValues.Services {
+		serviceNames[service.Name] = service.OverrideName
+	}
+	policies := &lagoon.NetworkPolicies{}
+	if len(declared.Services) > 0 {
+		policies.Services = map[string]lagoon.ServiceNetworkPolicy{}
+	}
+	for service, policy := range declared.Services {
+		name, ok := serviceNames[service]
+		if !ok {
+			return nil, fmt.Errorf("the network policy for service %s is not valid: the service doesn't exist", service)
+		}
+		from := policies.Services[name].From
+		for _, f := range policy.From {
+			fromName, ok := serviceNames[f]
+			if !ok {
+				return nil, fmt.Errorf("the network policy for service %s is not valid: the service %s it allows traffic from doesn't exist", service, f)
+			}
+			if !helpers.Contains(from, fromName) {
+				from = append(from, fromName)
+			}
+		}
+		sort.Strings(from)
+		policies.Services[name] = lagoon.ServiceNetworkPolicy{From: from}
+	}
+	if declared.Ingress != nil {
+		// the ingress network policy only adds peers to the isolation network policy, without it the environment already
+		// allows traffic from every namespace
+		if !buildValues.IsolationNetworkPolicy {
+			return nil, fmt.Errorf("the ingress network policy is not valid: it requires the isolation network policy to be enabled")
+		}
+		if err := validatePolicyEnvironments(buildValues, declared.Ingress.Environments); err != nil {
+			return nil, fmt.Errorf("the ingress network policy is not valid: %v", err)
+		}
+		policies.Ingress = &lagoon.IngressNetworkPolicy{
+			Environments: declared.Ingress.Environments,
+		}
+	}
+	if declared.Egress != nil {
+		egress := &lagoon.EgressNetworkPolicy{
+			DNS: helpers.BoolPtr(declared.Egress.DNS == nil || *declared.Egress.DNS),
+		}
+		for _, cidr := range declared.Egress.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return nil, fmt.Errorf("the egress network policy is not valid: %v", err)
+			}
+			egress.CIDRs = append(egress.CIDRs, cidr)
+		}
+		if err := validatePolicyEnvironments(buildValues, declared.Egress.Environments); err != nil {
+			return nil, fmt.Errorf("the egress network policy is not valid: %v", err)
+		}
+		egress.Environments = declared.Egress.Environments
+		policies.Egress = egress
+	}
+	return policies, nil
+}
+
+// validatePolicyEnvironments checks the environments that a network policy allows traffic to or from. the policies only ever
+// select the environments by name within the namespaces of the same project, so the names must be plain environment names
+// that can't be used to select the namespaces of another project
+func validatePolicyEnvironments(buildValues *BuildValues, environments []string) error {
+	if len(environments) > 0 && buildValues.Project == "" {
+		return fmt.Errorf("environments can only be allowed when the project is known")
+	}
+	for _, environment := range environments {
+		if errs := validation.IsDNS1123Label(environment); len(errs) != 0 {
+			return fmt.Errorf("the environment %s is not valid: %v", environment, errs)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+)
+
+func Test_getNetworkPolicies(t *testing.T) {
+	services := []ServiceValues{
+		{Name: "cli", OverrideName: "cli"},
+		{Name: "nginx", OverrideName: "nginx"},
+		{Name: "php", OverrideName: "nginx"},
+		{Name: "mariadb", OverrideName: "mariadb"},
+	}
+	tests := []struct {
+		name        string
+		buildValues *BuildValues
+		want        *lagoon.NetworkPolicies
+		wantErr     bool
+	}{
+		{
+			name: "test1 - no network policies",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+			},
+		},
+		{
+			name: "test2 - project network policies",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"php", "cli", "nginx"}},
+						},
+						Egress: &lagoon.EgressNetworkPolicy{
+							CIDRs:        []string{"10.10.0.0/16"},
+							Environments: []string{"develop"},
+						},
+					},
+				},
+			},
+			want: &lagoon.NetworkPolicies{
+				Services: map[string]lagoon.ServiceNetworkPolicy{
+					"mariadb": {From: []string{"cli", "nginx"}},
+				},
+				Egress: &lagoon.EgressNetworkPolicy{
+					CIDRs:        []string{"10.10.0.0/16"},
+					DNS:          helpers.BoolPtr(true),
+					Environments: []string{"develop"},
+				},
+			},
+		},
+		{
+			name: "test3 - environment network policies replace the project network policies",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"cli", "nginx"}},
+						},
+					},
+					Environments: lagoon.Environments{
+						"main": lagoon.Environment{
+							NetworkPolicies: &lagoon.NetworkPolicies{
+								Egress: &lagoon.EgressNetworkPolicy{
+									DNS: helpers.BoolPtr(false),
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &lagoon.NetworkPolicies{
+				Egress: &lagoon.EgressNetworkPolicy{
+					DNS: helpers.BoolPtr(false),
+				},
+			},
+		},
+		{
+			name: "test4 - environments of the same project allowed by the isolation policy",
+			buildValues: &BuildValues{
+				Project:                "example-project",
+				Environment:            "main",
+				Services:               services,
+				IsolationNetworkPolicy: true,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"staging", "develop"},
+						},
+					},
+				},
+			},
+			want: &lagoon.NetworkPolicies{
+				Ingress: &lagoon.IngressNetworkPolicy{
+					Environments: []string{"staging", "develop"},
+				},
+			},
+		},
+		{
+			name: "test5 - environment that could select another project",
+			buildValues: &BuildValues{
+				Project:                "example-project",
+				Environment:            "main",
+				Services:               services,
+				IsolationNetworkPolicy: true,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"other-project.main"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - environments without a project",
+			buildValues: &BuildValues{
+				Environment:            "main",
+				Services:               services,
+				IsolationNetworkPolicy: true,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"staging"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - service that doesn't exist",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"node"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test8 - invalid cidr",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Egress: &lagoon.EgressNetworkPolicy{
+							CIDRs: []string{"10.10.0.0"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test9 - invalid environment",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Egress: &lagoon.EgressNetworkPolicy{
+							Environments: []string{"feature/branch"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test10 - environments allowed without the isolation policy",
+			buildValues: &BuildValues{
+				Project:     "example-project",
+				Environment: "main",
+				Services:    services,
+				LagoonYAML: lagoon.YAML{
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"staging"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getNetworkPolicies(tt.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getNetworkPolicies() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getNetworkPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
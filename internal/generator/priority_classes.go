@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// the priority classes that lagoon installs, these are always allowed unless the allowed priority classes are changed
+var defaultAllowedPriorityClasses = []string{"lagoon-priority-production", "lagoon-priority-development"}
+
+// the environment and build types a priority class rule can match, a rule with any other type would never match a workload
+var (
+	priorityClassEnvironmentTypes = []string{"production", "development"}
+	priorityClassBuildTypes       = []string{"branch", "pullrequest", "promote"}
+)
+
+// PriorityClassPolicy maps the environment type, build type, and service type of a workload to the priority class that
+// its pods use. if no rule matches, the pods use the default lagoon priority class of the environment type
+type PriorityClassPolicy struct {
+	Allowed  []string            `json:"allowed"`
+	Services []PriorityClassRule `json:"services"`
+	Cronjobs []PriorityClassRule `json:"cronjobs"`
+	Tasks    []PriorityClassRule `json:"tasks"`
+}
+
+// PriorityClassRule assigns a priority class to workloads that match all of the environment type, build type, and service type
+// an empty value matches any
+type PriorityClassRule struct {
+	EnvironmentType string `json:"environmentType,omitempty"`
+	BuildType       string `json:"buildType,omitempty"`
+	ServiceType     string `json:"serviceType,omitempty"`
+	PriorityClass   string `json:"priorityClass"`
+}
+
+// getPriorityClassPolicy reads the priority class policy from the admin feature flags
+// PRIORITY_CLASSES_ALLOWED is a comma separated list of the priority classes that the policy can assign
+// PRIORITY_CLASS_POLICY, CRONJOB_PRIORITY_CLASS_POLICY, and TASK_PRIORITY_CLASS_POLICY are comma separated lists of
+// rules for the pods of services, native cronjobs, and pre or post rollout tasks, in the format
+// `environmentType:buildType:serviceType=priorityClass` where any of the types can be `*` to match all of them.
+// the first rule that matches a workload is used
+func getPriorityClassPolicy(debug bool) (PriorityClassPolicy, error) {
+	policy := PriorityClassPolicy{
+		Allowed: defaultAllowedPriorityClasses,
+	}
+	if allowed := CheckAdminFeatureFlag("PRIORITY_CLASSES_ALLOWED", debug); allowed != "" {
+		policy.Allowed = []string{}
+		for _, class := range strings.Split(allowed, ",") {
+			class = strings.TrimSpace(class)
+			if errs := validation.IsDNS1123Subdomain(class); len(errs) != 0 {
+				return PriorityClassPolicy{}, fmt.Errorf("the allowed priority class %s is not valid: %v", class, errs)
+			}
+			policy.Allowed = append(policy.Allowed, class)
+		}
+	}
+	for _, p := range []struct {
+		flag  string
+		rules *[]PriorityClassRule
+	}{
+		{"PRIORITY_CLASS_POLICY", &policy.Services},
+		{"CRONJOB_PRIORITY_CLASS_POLICY", &policy.Cronjobs},
+		{"TASK_PRIORITY_CLASS_POLICY", &policy.Tasks},
+	} {
+		rules, err := parsePriorityClassRules(CheckAdminFeatureFlag(p.flag, debug), policy.Allowed)
+		if err != nil {
+			return PriorityClassPolicy{}, fmt.Errorf("the %s admin feature flag is not valid: %v", p.flag, err)
+		}
+		*p.rules = rules
+	}
+	return policy, nil
+}
+
+// parsePriorityClassRules parses the rules of a priority class policy and checks they only match known types and only assign
+// allowed priority classes
+func parsePriorityClassRules(policy string, allowed []string) ([]PriorityClassRule, error) {
+	var rules []PriorityClassRule
+	if policy == "" {
+		return rules, nil
+	}
+	for _, r := range strings.Split(policy, ",") {
+		rule := strings.Split(strings.TrimSpace(r), "=")
+		if len(rule) != 2 {
+			return nil, fmt.Errorf("the rule %s must be in the format environmentType:buildType:serviceType=priorityClass", r)
+		}
+		types := strings.Split(rule[0], ":")
+		if len(types) != 3 {
+			return nil, fmt.Errorf("the rule %s must match an environment type, build type, and service type", r)
+		}
+		for idx := range types {
+			if types[idx] == "*" {
+				types[idx] = ""
+			}
+		}
+		if types[0] != "" && !helpers.Contains(priorityClassEnvironmentTypes, types[0]) {
+			return nil, fmt.Errorf("the rule %s has an unknown environment type %s, it must be one of %s or *", r, types[0], strings.Join(priorityClassEnvironmentTypes, ","))
+		}
+		if types[1] != "" && !helpers.Contains(priorityClassBuildTypes, types[1]) {
+			return nil, fmt.Errorf("the rule %s has an unknown build type %s, it must be one of %s or *", r, types[1], strings.Join(priorityClassBuildTypes, ","))
+		}
+		if _, ok := servicetypes.ServiceTypes[types[2]]; types[2] != "" && !ok {
+			return nil, fmt.Errorf("the rule %s has an unknown service type %s, it must be a lagoon service type or *", r, types[2])
+		}
+		if !helpers.Contains(allowed, rule[1]) {
+			return nil, fmt.Errorf("the priority class %s is not one of the allowed priority classes %s", rule[1], strings.Join(allowed, ","))
+		}
+		rules = append(rules, PriorityClassRule{
+			EnvironmentType: types[0],
+			BuildType:       types[1],
+			ServiceType:     types[2],
+			PriorityClass:   rule[1],
+		})
+	}
+	return rules, nil
+}
+
+// getPriorityClass returns the priority class of the first rule that matches the workload, or an empty string if none match
+func getPriorityClass(rules []PriorityClassRule, environmentType, buildType, serviceType string) string {
+	for _, rule := range rules {
+		if (rule.EnvironmentType == "" || rule.EnvironmentType == environmentType) &&
+			(rule.BuildType == "" || rule.BuildType == buildType) &&
+			(rule.ServiceType == "" || rule.ServiceType == serviceType) {
+			return rule.PriorityClass
+		}
+	}
+	return ""
+}
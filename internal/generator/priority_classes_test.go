@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+)
+
+func Test_getPriorityClassPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    []helpers.EnvironmentVariable
+		want    PriorityClassPolicy
+		wantErr bool
+	}{
+		{
+			name: "test1 - no policy",
+			want: PriorityClassPolicy{
+				Allowed: []string{"lagoon-priority-production", "lagoon-priority-development"},
+			},
+		},
+		{
+			name: "test2 - policies for services, cronjobs, and tasks",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_PRIORITY_CLASSES_ALLOWED",
+					Value: "lagoon-priority-production,lagoon-priority-development,lagoon-priority-critical,lagoon-priority-batch",
+				},
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_PRIORITY_CLASS_POLICY",
+					Value: "production:*:nginx-php=lagoon-priority-critical,development:pullrequest:*=lagoon-priority-batch",
+				},
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_CRONJOB_PRIORITY_CLASS_POLICY",
+					Value: "*:*:*=lagoon-priority-batch",
+				},
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_TASK_PRIORITY_CLASS_POLICY",
+					Value: "production:*:cli=lagoon-priority-production",
+				},
+			},
+			want: PriorityClassPolicy{
+				Allowed: []string{"lagoon-priority-production", "lagoon-priority-development", "lagoon-priority-critical", "lagoon-priority-batch"},
+				Services: []PriorityClassRule{
+					{EnvironmentType: "production", ServiceType: "nginx-php", PriorityClass: "lagoon-priority-critical"},
+					{EnvironmentType: "development", BuildType: "pullrequest", PriorityClass: "lagoon-priority-batch"},
+				},
+				Cronjobs: []PriorityClassRule{
+					{PriorityClass: "lagoon-priority-batch"},
+				},
+				Tasks: []PriorityClassRule{
+					{EnvironmentType: "production", ServiceType: "cli", PriorityClass: "lagoon-priority-production"},
+				},
+			},
+		},
+		{
+			name: "test3 - priority class that isn't allowed",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_PRIORITY_CLASS_POLICY",
+					Value: "production:*:nginx-php=lagoon-priority-critical",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 - rule without a build type",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_CRONJOB_PRIORITY_CLASS_POLICY",
+					Value: "production:cli=lagoon-priority-production",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - invalid allowed priority class",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_PRIORITY_CLASSES_ALLOWED",
+					Value: "Lagoon_Priority",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - unknown environment type",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_PRIORITY_CLASS_POLICY",
+					Value: "prod:*:nginx-php=lagoon-priority-production",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - unknown build type",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_TASK_PRIORITY_CLASS_POLICY",
+					Value: "production:pr:cli=lagoon-priority-production",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test8 - unknown service type",
+			vars: []helpers.EnvironmentVariable{
+				{
+					Name:  "ADMIN_LAGOON_FEATURE_FLAG_CRONJOB_PRIORITY_CLASS_POLICY",
+					Value: "*:*:nginx-phpp=lagoon-priority-development",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, envVar := range tt.vars {
+				err := os.Setenv(envVar.Name, envVar.Value)
+				if err != nil {
+					t.Errorf("%v", err)
+				}
+			}
+			t.Cleanup(func() {
+				helpers.UnsetEnvVars(tt.vars)
+			})
+			got, err := getPriorityClassPolicy(false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getPriorityClassPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getPriorityClassPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getPriorityClass(t *testing.T) {
+	rules := []PriorityClassRule{
+		{EnvironmentType: "production", ServiceType: "nginx-php", PriorityClass: "lagoon-priority-critical"},
+		{EnvironmentType: "development", BuildType: "pullrequest", PriorityClass: "lagoon-priority-batch"},
+		{EnvironmentType: "production", PriorityClass: "lagoon-priority-production"},
+	}
+	tests := []struct {
+		name            string
+		environmentType string
+		buildType       string
+		serviceType     string
+		want            string
+	}{
+		{
+			name:            "test1 - production nginx-php",
+			environmentType: "production",
+			buildType:       "branch",
+			serviceType:     "nginx-php",
+			want:            "lagoon-priority-critical",
+		},
+		{
+			name:            "test2 - production service falls through to the environment type rule",
+			environmentType: "production",
+			buildType:       "branch",
+			serviceType:     "mariadb-single",
+			want:            "lagoon-priority-production",
+		},
+		{
+			name:            "test3 - development pullrequest",
+			environmentType: "development",
+			buildType:       "pullrequest",
+			serviceType:     "nginx-php",
+			want:            "lagoon-priority-batch",
+		},
+		{
+			name:            "test4 - development branch doesn't match",
+			environmentType: "development",
+			buildType:       "branch",
+			serviceType:     "nginx-php",
+			want:            "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getPriorityClass(rules, tt.environmentType, tt.buildType, tt.serviceType); got != tt.want {
+				t.Errorf("getPriorityClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	composetypes "github.com/compose-spec/compose-go/types"
 	"github.com/drone/envsubst"
+	"github.com/uselagoon/build-deploy-tool/internal/dbaasclient"
 	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
@@ -26,22 +27,6 @@ var oldServiceMap = map[string]string{
 	"mongo":                 "mongodb",
 }
 
-// these are lagoon types that support autogenerated routes
-var supportedAutogeneratedTypes = []string{
-	// "kibana", //@TODO: don't even need this anymore?
-	"basic",
-	"basic-persistent",
-	"node",
-	"node-persistent",
-	"nginx",
-	"nginx-php",
-	"nginx-php-persistent",
-	"varnish",
-	"varnish-persistent",
-	"python-persistent",
-	"python",
-}
-
 // these service types don't have images
 var ignoredImageTypes = []string{
 	"mariadb-dbaas",
@@ -59,24 +44,12 @@ var supportedDBTypes = []string{
 	"mongodb-dbaas",
 }
 
-// these are lagoon types that come with resources requiring backups
-var typesWithBackups = []string{
-	"basic-persistent",
-	"node-persistent",
-	"nginx-php-persistent",
-	"python-persistent",
-	"varnish-persistent",
-	"redis-persistent",
-	"solr",
-	"elasticsearch",
-	"opensearch",
-	"rabbitmq",
+// the dbaas types don't have a service type, but the resources they provide require backups, all other lagoon types
+// declare if they require backups in their service type
+var dbaasTypesWithBackups = []string{
 	"mongodb-dbaas",
 	"mariadb-dbaas",
 	"postgres-dbaas",
-	"mariadb-single",
-	"postgres-single",
-	"mongodb-single",
 }
 
 // generateServicesFromDockerCompose unmarshals the docker-compose file and processes the services using composeToServiceValues
@@ -277,10 +250,12 @@ func composeToServiceValues(
 			lagoonType = strings.Split(lagoonType, "-dbaas")[0]
 			err := buildValues.DBaaSClient.CheckHealth(buildValues.DBaaSOperatorEndpoint)
 			if err != nil {
-				// @TODO eventually this error should be handled and fail a build, with a flag to override https://github.com/uselagoon/build-deploy-tool/issues/56
-				// if !buildValues.DBaaSFallbackSingle {
-				// 	return ServiceValues{}, fmt.Errorf("unable to check the DBaaS endpoint %s: %v", buildValues.DBaaSOperatorEndpoint, err)
-				// }
+				if !buildValues.DBaaSFallbackSingle {
+					return ServiceValues{}, fmt.Errorf(
+						"unable to check the DBaaS endpoint %s for service %s with the %s environment: %v",
+						buildValues.DBaaSOperatorEndpoint, lagoonOverrideName, dbaasEnvironment, err,
+					)
+				}
 				if debug {
 					fmt.Printf("Unable to check the DBaaS endpoint %s, falling back to %s-single: %v\n", buildValues.DBaaSOperatorEndpoint, lagoonType, err)
 				}
@@ -304,12 +279,11 @@ func composeToServiceValues(
 
 				// if there are overrides defined in the lagoon API `LAGOON_DBAAS_ENVIRONMENT_TYPES`
 				// handle those here
-				exists, err := getDBaasEnvironment(buildValues, &dbaasEnvironment, lagoonOverrideName, lagoonType)
+				exists, capabilities, err := getDBaasEnvironment(buildValues, &dbaasEnvironment, lagoonOverrideName, lagoonType)
 				if err != nil {
-					// @TODO eventually this error should be handled and fail a build, with a flag to override https://github.com/uselagoon/build-deploy-tool/issues/56
-					// if !buildValues.DBaaSFallbackSingle {
-					// 	return ServiceValues{}, err
-					// }
+					if !buildValues.DBaaSFallbackSingle {
+						return ServiceValues{}, err
+					}
 					if debug {
 						fmt.Printf(
 							"There was an error checking DBaaS endpoint %s, falling back to %s-single: %v\n",
@@ -320,10 +294,20 @@ func composeToServiceValues(
 
 				// if the requested dbaas environment exists, then set the type to be the requested type with `-dbaas`
 				if exists {
+					if capabilities != nil && debug {
+						fmt.Printf("DBaaS provider for %s with the %s environment supports read replicas: %t, versions: %v\n",
+							lagoonType, dbaasEnvironment, capabilities.ReadReplicas, capabilities.Versions)
+					}
 					lagoonType = fmt.Sprintf("%s-dbaas", lagoonType)
 					svcIsDBaaS = true
 				} else {
-					// otherwise fallback to -single (if DBaaSFallbackSingle is enabled, otherwise it will error out prior)
+					if err == nil && !buildValues.DBaaSFallbackSingle {
+						return ServiceValues{}, fmt.Errorf(
+							"no DBaaS provider was found at %s for service %s with the %s environment",
+							buildValues.DBaaSOperatorEndpoint, lagoonOverrideName, dbaasEnvironment,
+						)
+					}
+					// otherwise fallback to -single if DBaaSFallbackSingle is enabled
 					lagoonType = fmt.Sprintf("%s-single", lagoonType)
 					svcIsSingle = true
 				}
@@ -390,6 +374,48 @@ func composeToServiceValues(
 		}
 		// end spot instance handling
 
+		// work out if this service is scaled by a horizontal pod autoscaler
+		autoscaling, err := getAutoscaling(buildValues, composeService, composeServiceValues.Labels, spotReplicas)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
+		// work out any cpu and memory requests or limits for this service
+		resources, err := getResources(buildValues, composeService, composeServiceValues.Labels)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
+		// work out any changes to the probes for this service
+		probes, err := getProbes(buildValues, composeService, composeServiceValues.Labels)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
+		// work out how the replicas of this service are spread across zones and nodes
+		replicaSpread, err := getReplicaSpread(buildValues, composeService, composeServiceValues.Labels, debug)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
+		// work out the priority classes from the policy, any that aren't set use the default for the environment type
+		policy := buildValues.PriorityClassPolicy
+		priorityClass := getPriorityClass(policy.Services, buildValues.EnvironmentType, buildValues.BuildType, lagoonType)
+		cronjobPriorityClass := getPriorityClass(policy.Cronjobs, buildValues.EnvironmentType, buildValues.BuildType, lagoonType)
+		taskPriorityClass := getPriorityClass(policy.Tasks, buildValues.EnvironmentType, buildValues.BuildType, lagoonType)
+
+		// work out any changes to how the pods of this service are stopped
+		lifecycle, err := getLifecycle(buildValues, composeService, lagoonType, composeServiceValues.Labels)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
+		// work out any sidecars or init containers for this service
+		sidecars, initContainers, err := getAdditionalContainers(buildValues, composeService)
+		if err != nil {
+			return ServiceValues{}, err
+		}
+
 		// work out cronjobs for this service
 		inpodcronjobs := []lagoon.Cronjob{}
 		nativecronjobs := []lagoon.Cronjob{}
@@ -427,18 +453,20 @@ func composeToServiceValues(
 		}
 
 		// check if this service is one that supports autogenerated routes
-		if !helpers.Contains(supportedAutogeneratedTypes, lagoonType) {
+		if !servicetypes.ServiceTypes[lagoonType].SupportsAutogeneratedRoutes {
 			autogenEnabled = false
 			autogenTLSAcmeEnabled = false
 		}
 
 		// check if this service is one that supports backups
 		backupsEnabled := false
-		if helpers.Contains(typesWithBackups, lagoonType) {
+		if servicetypes.ServiceTypes[lagoonType].RequiresBackups || helpers.Contains(dbaasTypesWithBackups, lagoonType) {
 			backupsEnabled = true
-
 		}
 
+		// check if this service should be a statefulset instead of a deployment
+		statefulSet := buildValues.StatefulSets && servicetypes.ServiceTypes[lagoonType].SupportsStatefulSet
+
 		// create the service values
 		cService := ServiceValues{
 			Name:                                   composeService,
@@ -448,6 +476,12 @@ func composeToServiceValues(
 			AutogeneratedRoutesTLSAcme:             autogenTLSAcmeEnabled,
 			AutogeneratedRoutesRequestVerification: autogeRequestVerification,
 			DBaaSEnvironment:                       dbaasEnvironment,
+			Autoscaling:                            autoscaling,
+			Resources:                              resources,
+			Probes:                                 probes,
+			Lifecycle:                              lifecycle,
+			Sidecars:                               sidecars,
+			InitContainers:                         initContainers,
 			PersistentVolumePath:                   servicePersistentPath,
 			PersistentVolumeName:                   servicePersistentName,
 			PersistentVolumeSize:                   servicePersistentSize,
@@ -456,6 +490,11 @@ func composeToServiceValues(
 			CronjobUseSpotInstances:                cronjobUseSpot,
 			CronjobForceSpotInstances:              cronjobForceSpot,
 			Replicas:                               spotReplicas,
+			ReplicaSpread:                          replicaSpread,
+			PriorityClassName:                      priorityClass,
+			CronjobPriorityClassName:               cronjobPriorityClass,
+			TaskPriorityClassName:                  taskPriorityClass,
+			StatefulSet:                            statefulSet,
 			InPodCronjobs:                          inpodcronjobs,
 			NativeCronjobs:                         nativecronjobs,
 			PodSecurityContext:                     buildValues.PodSecurityContext,
@@ -525,19 +564,7 @@ func composeToServiceValues(
 						// else set the pullimage to whatever is defined in the docker-compose file otherwise
 						pullImage = composeServiceValues.Image
 					}
-					// if the image just is an image name (like "alpine") we prefix it with `libary/` as the imagecache does not understand
-					// the magic `alpine` image
-					if !strings.Contains(pullImage, "/") {
-						imageBuild.PullImage = fmt.Sprintf("library/%s", pullImage)
-					} else {
-						imageBuild.PullImage = pullImage
-					}
-					if !ContainsRegistry(buildValues.ContainerRegistry, pullImage) {
-						// if the image isn't in dockerhub, then the imagecache can't be used
-						if buildValues.ImageCache != "" && strings.Count(pullImage, "/") == 1 && !buildValues.IgnoreImageCache {
-							imageBuild.PullImage = fmt.Sprintf("%s%s", buildValues.ImageCache, imageBuild.PullImage)
-						}
-					}
+					imageBuild.PullImage = imageCachePullImage(buildValues, pullImage)
 				} else {
 					// otherwise this must be an image build
 					// set temporary image to prevent clashes?? not sure this is even required, the temporary name is just as unique as the final image name eventually is
@@ -613,13 +640,13 @@ func composeToServiceValues(
 	}
 }
 
-// getDBaasEnvironment will check the dbaas provider to see if an environment exists or not
+// getDBaasEnvironment will check the dbaas provider to see if an environment exists or not, and what the provider supports
 func getDBaasEnvironment(
 	buildValues *BuildValues,
 	dbaasEnvironment *string,
 	lagoonOverrideName,
 	lagoonType string,
-) (bool, error) {
+) (bool, *dbaasclient.Capabilities, error) {
 	if buildValues.DBaaSEnvironmentTypeOverrides != nil {
 		dbaasEnvironmentTypeSplit := strings.Split(buildValues.DBaaSEnvironmentTypeOverrides.Value, ",")
 		for _, sType := range dbaasEnvironmentTypeSplit {
@@ -629,11 +656,14 @@ func getDBaasEnvironment(
 			}
 		}
 	}
-	exists, err := buildValues.DBaaSClient.CheckProvider(buildValues.DBaaSOperatorEndpoint, lagoonType, *dbaasEnvironment)
+	exists, capabilities, err := buildValues.DBaaSClient.CheckProvider(buildValues.DBaaSOperatorEndpoint, lagoonType, *dbaasEnvironment)
 	if err != nil {
-		return exists, fmt.Errorf("there was an error checking DBaaS endpoint %s: %v", buildValues.DBaaSOperatorEndpoint, err)
+		return exists, nil, fmt.Errorf(
+			"there was an error checking DBaaS endpoint %s for service %s with the %s environment: %v",
+			buildValues.DBaaSOperatorEndpoint, lagoonOverrideName, *dbaasEnvironment, err,
+		)
 	}
-	return exists, nil
+	return exists, capabilities, nil
 }
 
 func checkDuplicateCronjobs(cronjobs []lagoon.Cronjob) error {
@@ -679,3 +709,20 @@ func ContainsRegistry(regex []ContainerRegistry, match string) bool {
 	}
 	return false
 }
+
+// imageCachePullImage returns the image to pull, any image that is in dockerhub is pulled through the imagecache if one is defined
+func imageCachePullImage(buildValues *BuildValues, image string) string {
+	// if the image just is an image name (like "alpine") we prefix it with `libary/` as the imagecache does not understand
+	// the magic `alpine` image
+	pullImage := image
+	if !strings.Contains(image, "/") {
+		pullImage = fmt.Sprintf("library/%s", image)
+	}
+	if !ContainsRegistry(buildValues.ContainerRegistry, image) {
+		// if the image isn't in dockerhub, then the imagecache can't be used
+		if buildValues.ImageCache != "" && strings.Count(image, "/") == 1 && !buildValues.IgnoreImageCache {
+			pullImage = fmt.Sprintf("%s%s", buildValues.ImageCache, pullImage)
+		}
+	}
+	return pullImage
+}
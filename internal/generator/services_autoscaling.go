@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+)
+
+// the target average cpu utilization used if a service doesn't define one
+const defaultAutoscalingCPU = 80
+
+// getAutoscaling works out the horizontal pod autoscaling for a service from the `lagoon.autoscaling.min`,
+// `lagoon.autoscaling.max`, and `lagoon.autoscaling.cpu` docker-compose labels, and any `autoscaling` override for the
+// service in the environment in the .lagoon.yml file. If autoscaling is not configured, nil is returned
+func getAutoscaling(
+	buildValues *BuildValues,
+	composeService string,
+	labels map[string]string,
+	minReplicas int32,
+) (*lagoon.Autoscaling, error) {
+	autoscaling := lagoon.Autoscaling{}
+	configured := false
+	for _, l := range []struct {
+		label string
+		value *int32
+	}{
+		{"lagoon.autoscaling.min", &autoscaling.Min},
+		{"lagoon.autoscaling.max", &autoscaling.Max},
+		{"lagoon.autoscaling.cpu", &autoscaling.CPU},
+	} {
+		labelValue := lagoon.CheckServiceLagoonLabel(labels, l.label)
+		if labelValue == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(labelValue, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("the %s label on service %s must be a number: %v", l.label, composeService, err)
+		}
+		*l.value = int32(v)
+		configured = true
+	}
+	// the values in the .lagoon.yml override any provided by the labels
+	if override := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService].Autoscaling; override != nil {
+		if override.Min != 0 {
+			autoscaling.Min = override.Min
+		}
+		if override.Max != 0 {
+			autoscaling.Max = override.Max
+		}
+		if override.CPU != 0 {
+			autoscaling.CPU = override.CPU
+		}
+		configured = true
+	}
+	if !configured {
+		return nil, nil
+	}
+	if autoscaling.Min == 0 {
+		autoscaling.Min = 1
+		if minReplicas > 1 {
+			autoscaling.Min = minReplicas
+		}
+	}
+	if autoscaling.CPU == 0 {
+		autoscaling.CPU = defaultAutoscalingCPU
+	}
+	if autoscaling.Max == 0 {
+		return nil, fmt.Errorf("autoscaling for service %s requires a maximum number of replicas", composeService)
+	}
+	if autoscaling.Min < 1 || autoscaling.Min > autoscaling.Max {
+		return nil, fmt.Errorf(
+			"autoscaling for service %s has a minimum of %d replicas, it must be between 1 and the maximum of %d",
+			composeService, autoscaling.Min, autoscaling.Max,
+		)
+	}
+	if autoscaling.CPU < 1 || autoscaling.CPU > 100 {
+		return nil, fmt.Errorf("autoscaling for service %s has a cpu target of %d, it must be between 1 and 100", composeService, autoscaling.CPU)
+	}
+	return &autoscaling, nil
+}
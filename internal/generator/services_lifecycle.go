@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+)
+
+// the grace period kubernetes gives pods to stop if neither the service type or the service change it
+const defaultTerminationGracePeriodSeconds int64 = 30
+
+// getLifecycle works out any changes to how the pods of a service are stopped from the
+// `lagoon.lifecycle.terminationgraceperiod`, `lagoon.lifecycle.prestop.sleep`, and `lagoon.lifecycle.prestop.command`
+// docker-compose labels, and any `lifecycle` override for the service in the environment in the .lagoon.yml file.
+// If nothing is configured, nil is returned and the defaults of the service type are used
+func getLifecycle(
+	buildValues *BuildValues,
+	composeService, lagoonType string,
+	labels map[string]string,
+) (*lagoon.Lifecycle, error) {
+	lifecycle := &lagoon.Lifecycle{}
+	configured := false
+	for _, l := range []struct {
+		label string
+		value **int64
+	}{
+		{"lagoon.lifecycle.terminationgraceperiod", &lifecycle.TerminationGracePeriodSeconds},
+		{"lagoon.lifecycle.prestop.sleep", &lifecycle.PreStopSleepSeconds},
+	} {
+		labelValue := lagoon.CheckServiceLagoonLabel(labels, l.label)
+		if labelValue == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(labelValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("the %s label on service %s must be a number: %v", l.label, composeService, err)
+		}
+		*l.value = &v
+		configured = true
+	}
+	if command := lagoon.CheckServiceLagoonLabel(labels, "lagoon.lifecycle.prestop.command"); command != "" {
+		lifecycle.PreStopCommand = command
+		configured = true
+	}
+	// the values in the .lagoon.yml override any provided by the labels
+	if override := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService].Lifecycle; override != nil {
+		if override.TerminationGracePeriodSeconds != nil {
+			lifecycle.TerminationGracePeriodSeconds = override.TerminationGracePeriodSeconds
+		}
+		if override.PreStopSleepSeconds != nil {
+			lifecycle.PreStopSleepSeconds = override.PreStopSleepSeconds
+		}
+		if override.PreStopCommand != "" {
+			lifecycle.PreStopCommand = override.PreStopCommand
+		}
+		configured = true
+	}
+	if !configured {
+		return nil, nil
+	}
+	if err := validateLifecycle(composeService, lagoonType, *lifecycle); err != nil {
+		return nil, err
+	}
+	return lifecycle, nil
+}
+
+// validateLifecycle rejects any lifecycle values that kubernetes won't accept, or where the pre stop sleep would never finish
+// before the pod is killed
+func validateLifecycle(composeService, lagoonType string, lifecycle lagoon.Lifecycle) error {
+	gracePeriod := defaultTerminationGracePeriodSeconds
+	if t, ok := servicetypes.ServiceTypes[lagoonType]; ok && t.TerminationGracePeriodSeconds != 0 {
+		gracePeriod = t.TerminationGracePeriodSeconds
+	}
+	if lifecycle.TerminationGracePeriodSeconds != nil {
+		if *lifecycle.TerminationGracePeriodSeconds < 0 {
+			return fmt.Errorf("the termination grace period for service %s can't be negative", composeService)
+		}
+		gracePeriod = *lifecycle.TerminationGracePeriodSeconds
+	}
+	if lifecycle.PreStopSleepSeconds != nil {
+		if *lifecycle.PreStopSleepSeconds < 0 {
+			return fmt.Errorf("the pre stop sleep for service %s can't be negative", composeService)
+		}
+		if *lifecycle.PreStopSleepSeconds >= gracePeriod {
+			return fmt.Errorf("the pre stop sleep of %d seconds for service %s must be shorter than the termination grace period of %d seconds",
+				*lifecycle.PreStopSleepSeconds, composeService, gracePeriod)
+		}
+	}
+	return nil
+}
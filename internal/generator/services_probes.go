@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+)
+
+// getProbes works out any changes to the liveness, readiness, and startup probes of a service from the
+// `lagoon.probes.<liveness|readiness|startup>.<path|port|initialdelay|period|timeout|successthreshold|failurethreshold>`
+// docker-compose labels, and any `probes` override for the service in the environment in the .lagoon.yml file.
+// If no probes are configured, nil is returned
+func getProbes(
+	buildValues *BuildValues,
+	composeService string,
+	labels map[string]string,
+) (*lagoon.Probes, error) {
+	probes := &lagoon.Probes{}
+	// the values in the .lagoon.yml override any provided by the labels
+	override := lagoon.Probes{}
+	if o := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService].Probes; o != nil {
+		override = *o
+	}
+	configured := false
+	for _, p := range []struct {
+		name     string
+		probe    **lagoon.Probe
+		override *lagoon.Probe
+	}{
+		{"liveness", &probes.Liveness, override.Liveness},
+		{"readiness", &probes.Readiness, override.Readiness},
+		{"startup", &probes.Startup, override.Startup},
+	} {
+		probe, err := getProbe(composeService, p.name, labels, p.override)
+		if err != nil {
+			return nil, err
+		}
+		if probe != nil {
+			*p.probe = probe
+			configured = true
+		}
+	}
+	if !configured {
+		return nil, nil
+	}
+	return probes, nil
+}
+
+// getProbe works out a single probe from the labels and the .lagoon.yml override, the override takes precedence
+func getProbe(composeService, name string, labels map[string]string, override *lagoon.Probe) (*lagoon.Probe, error) {
+	probe := lagoon.Probe{}
+	configured := false
+	if path := lagoon.CheckServiceLagoonLabel(labels, fmt.Sprintf("lagoon.probes.%s.path", name)); path != "" {
+		probe.Path = path
+		configured = true
+	}
+	for _, l := range []struct {
+		label string
+		value *int32
+	}{
+		{fmt.Sprintf("lagoon.probes.%s.port", name), &probe.Port},
+		{fmt.Sprintf("lagoon.probes.%s.initialdelay", name), &probe.InitialDelaySeconds},
+		{fmt.Sprintf("lagoon.probes.%s.period", name), &probe.PeriodSeconds},
+		{fmt.Sprintf("lagoon.probes.%s.timeout", name), &probe.TimeoutSeconds},
+		{fmt.Sprintf("lagoon.probes.%s.successthreshold", name), &probe.SuccessThreshold},
+		{fmt.Sprintf("lagoon.probes.%s.failurethreshold", name), &probe.FailureThreshold},
+	} {
+		labelValue := lagoon.CheckServiceLagoonLabel(labels, l.label)
+		if labelValue == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(labelValue, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("the %s label on service %s must be a number: %v", l.label, composeService, err)
+		}
+		*l.value = int32(v)
+		configured = true
+	}
+	// the values in the .lagoon.yml override any provided by the labels
+	if override != nil {
+		if override.Path != "" {
+			probe.Path = override.Path
+		}
+		for _, o := range []struct {
+			override int32
+			value    *int32
+		}{
+			{override.Port, &probe.Port},
+			{override.InitialDelaySeconds, &probe.InitialDelaySeconds},
+			{override.PeriodSeconds, &probe.PeriodSeconds},
+			{override.TimeoutSeconds, &probe.TimeoutSeconds},
+			{override.SuccessThreshold, &probe.SuccessThreshold},
+			{override.FailureThreshold, &probe.FailureThreshold},
+		} {
+			if o.override != 0 {
+				*o.value = o.override
+			}
+		}
+		configured = true
+	}
+	if !configured {
+		return nil, nil
+	}
+	if err := validateProbe(composeService, name, probe); err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
+
+// validateProbe rejects any probe values that kubernetes won't accept, or that would never result in a healthy container
+func validateProbe(composeService, name string, probe lagoon.Probe) error {
+	if probe.Path != "" && !strings.HasPrefix(probe.Path, "/") {
+		return fmt.Errorf("the %s probe path %s for service %s must start with /", name, probe.Path, composeService)
+	}
+	if probe.Port < 0 || probe.Port > 65535 {
+		return fmt.Errorf("the %s probe port %d for service %s is not a valid port", name, probe.Port, composeService)
+	}
+	for _, v := range []struct {
+		field string
+		value int32
+	}{
+		{"initial delay", probe.InitialDelaySeconds},
+		{"period", probe.PeriodSeconds},
+		{"timeout", probe.TimeoutSeconds},
+		{"success threshold", probe.SuccessThreshold},
+		{"failure threshold", probe.FailureThreshold},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("the %s probe %s for service %s can't be negative", name, v.field, composeService)
+		}
+	}
+	// kubernetes only allows a success threshold of 1 for liveness and startup probes
+	if name != "readiness" && probe.SuccessThreshold > 1 {
+		return fmt.Errorf("the %s probe for service %s can only have a success threshold of 1", name, composeService)
+	}
+	if probe.TimeoutSeconds != 0 && probe.PeriodSeconds != 0 && probe.TimeoutSeconds > probe.PeriodSeconds {
+		return fmt.Errorf("the %s probe timeout of %d seconds for service %s is longer than the period of %d seconds",
+			name, probe.TimeoutSeconds, composeService, probe.PeriodSeconds)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// getResources works out the cpu and memory requests and limits for the containers of a service from the
+// `lagoon.resources.requests.cpu`, `lagoon.resources.requests.memory`, `lagoon.resources.limits.cpu`, and
+// `lagoon.resources.limits.memory` docker-compose labels, and any `resources` override for the service in the environment
+// in the .lagoon.yml file. The limits can't exceed any container limits set by the admin feature flags.
+// If no resources are configured, nil is returned
+func getResources(
+	buildValues *BuildValues,
+	composeService string,
+	labels map[string]string,
+) (*lagoon.Resources, error) {
+	resources := lagoon.Resources{}
+	configured := false
+	for _, l := range []struct {
+		label string
+		value *string
+	}{
+		{"lagoon.resources.requests.cpu", &resources.Requests.CPU},
+		{"lagoon.resources.requests.memory", &resources.Requests.Memory},
+		{"lagoon.resources.limits.cpu", &resources.Limits.CPU},
+		{"lagoon.resources.limits.memory", &resources.Limits.Memory},
+	} {
+		if labelValue := lagoon.CheckServiceLagoonLabel(labels, l.label); labelValue != "" {
+			*l.value = labelValue
+			configured = true
+		}
+	}
+	// the values in the .lagoon.yml override any provided by the labels
+	if override := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService].Resources; override != nil {
+		for _, o := range []struct {
+			override string
+			value    *string
+		}{
+			{override.Requests.CPU, &resources.Requests.CPU},
+			{override.Requests.Memory, &resources.Requests.Memory},
+			{override.Limits.CPU, &resources.Limits.CPU},
+			{override.Limits.Memory, &resources.Limits.Memory},
+		} {
+			if o.override != "" {
+				*o.value = o.override
+			}
+		}
+		configured = true
+	}
+	if !configured {
+		return nil, nil
+	}
+	if err := validateResources(buildValues, fmt.Sprintf("service %s", composeService), resources); err != nil {
+		return nil, err
+	}
+	return &resources, nil
+}
+
+// validateResources checks that the resources are valid resource quantities, that no request is greater than its limit,
+// and that none of them exceed the container limits set by the admin feature flags
+func validateResources(buildValues *BuildValues, description string, resources lagoon.Resources) error {
+	for _, r := range []struct {
+		name     string
+		request  string
+		limit    string
+		adminMax string
+	}{
+		{"cpu", resources.Requests.CPU, resources.Limits.CPU, buildValues.Resources.Limits.CPU},
+		{"memory", resources.Requests.Memory, resources.Limits.Memory, buildValues.Resources.Limits.Memory},
+	} {
+		if r.request != "" {
+			if err := ValidateResourceQuantity(r.request); err != nil {
+				return fmt.Errorf("provided %s request %s for %s is not a valid resource quantity", r.name, r.request, description)
+			}
+		}
+		if r.limit != "" {
+			if err := ValidateResourceQuantity(r.limit); err != nil {
+				return fmt.Errorf("provided %s limit %s for %s is not a valid resource quantity", r.name, r.limit, description)
+			}
+		}
+		if r.request != "" && r.limit != "" && compareResourceQuantity(r.request, r.limit) > 0 {
+			return fmt.Errorf("the %s request %s for %s is greater than the limit %s", r.name, r.request, description, r.limit)
+		}
+		// the admin limit is the most a service can use, so neither the request or the limit can be more than it
+		if r.adminMax != "" {
+			for _, value := range []string{r.request, r.limit} {
+				if value != "" && compareResourceQuantity(value, r.adminMax) > 0 {
+					return fmt.Errorf("the %s resources %s for %s exceed the maximum of %s allowed", r.name, value, description, r.adminMax)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compareResourceQuantity compares two resource quantities that have already been validated, returning -1 if a is less
+// than b, 0 if they are equal, or 1 if a is greater than b
+func compareResourceQuantity(a, b string) int {
+	qa := resource.MustParse(a)
+	return qa.Cmp(resource.MustParse(b))
+}
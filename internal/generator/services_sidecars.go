@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// getAdditionalContainers works out any sidecars and init containers defined for a service in the environment in the
+// .lagoon.yml file. The images of the containers are pulled through the imagecache the same way as any other pulled image
+func getAdditionalContainers(
+	buildValues *BuildValues,
+	composeService string,
+) ([]lagoon.Container, []lagoon.Container, error) {
+	override := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService]
+	names := map[string]bool{}
+	sidecars, err := validateAdditionalContainers(buildValues, composeService, "sidecar", override.Sidecars, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	initContainers, err := validateAdditionalContainers(buildValues, composeService, "init container", override.InitContainers, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sidecars, initContainers, nil
+}
+
+// validateAdditionalContainers checks the containers are valid and returns a copy of them with the image to pull,
+// the names of the containers must be unique across all the additional containers of the service
+func validateAdditionalContainers(
+	buildValues *BuildValues,
+	composeService, kind string,
+	containers []lagoon.Container,
+	names map[string]bool,
+) ([]lagoon.Container, error) {
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	result := []lagoon.Container{}
+	for _, c := range containers {
+		if errs := validation.IsDNS1123Label(c.Name); len(errs) != 0 {
+			return nil, fmt.Errorf("the %s name %q for service %s is not valid: %s", kind, c.Name, composeService, strings.Join(errs, ", "))
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("the %s name %s for service %s is already used by another container", kind, c.Name, composeService)
+		}
+		names[c.Name] = true
+		if c.Image == "" {
+			return nil, fmt.Errorf("the %s %s for service %s has no image defined", kind, c.Name, composeService)
+		}
+		for _, p := range c.Ports {
+			if p < 1 || p > 65535 {
+				return nil, fmt.Errorf("the %s %s for service %s has an invalid port %d", kind, c.Name, composeService, p)
+			}
+		}
+		for _, vm := range c.VolumeMounts {
+			if vm.Name == "" || !path.IsAbs(vm.Path) {
+				return nil, fmt.Errorf("the %s %s for service %s has a volume mount that requires a name and an absolute path", kind, c.Name, composeService)
+			}
+		}
+		if c.Resources != nil {
+			if err := validateResources(buildValues, fmt.Sprintf("%s %s of service %s", kind, c.Name, composeService), *c.Resources); err != nil {
+				return nil, err
+			}
+		}
+		c.Image = imageCachePullImage(buildValues, c.Image)
+		result = append(result, c)
+	}
+	return result, nil
+}
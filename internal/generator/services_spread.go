@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+)
+
+// the ways the replicas of a service can be spread across the zones and nodes of a cluster
+const (
+	ReplicaSpreadDisabled  = "disabled"
+	ReplicaSpreadPreferred = "preferred"
+	ReplicaSpreadRequired  = "required"
+)
+
+// getReplicaSpread works out how the replicas of a service are spread across zones and nodes. the REPLICA_SPREAD feature
+// flag sets it for the environment, which a service can change with the `lagoon.replicaspread` docker-compose label, or
+// the `replicaSpread` override for the service in the environment in the .lagoon.yml file.
+// The spread only applies to services that run more than one replica
+func getReplicaSpread(
+	buildValues *BuildValues,
+	composeService string,
+	labels map[string]string,
+	debug bool,
+) (string, error) {
+	spread := CheckFeatureFlag("REPLICA_SPREAD", buildValues.EnvironmentVariables, debug)
+	if err := validateReplicaSpread(spread); err != nil {
+		return "", fmt.Errorf("the REPLICA_SPREAD feature flag is not valid: %v", err)
+	}
+	if label := lagoon.CheckServiceLagoonLabel(labels, "lagoon.replicaspread"); label != "" {
+		if err := validateReplicaSpread(label); err != nil {
+			return "", fmt.Errorf("the lagoon.replicaspread label on service %s is not valid: %v", composeService, err)
+		}
+		spread = label
+	}
+	// the values in the .lagoon.yml override any provided by the labels
+	if override := buildValues.LagoonYAML.Environments[buildValues.Environment].Overrides[composeService].ReplicaSpread; override != "" {
+		if err := validateReplicaSpread(override); err != nil {
+			return "", fmt.Errorf("the replica spread override for service %s is not valid: %v", composeService, err)
+		}
+		spread = override
+	}
+	if spread == ReplicaSpreadDisabled {
+		return "", nil
+	}
+	return spread, nil
+}
+
+func validateReplicaSpread(spread string) error {
+	switch spread {
+	case "", ReplicaSpreadDisabled, ReplicaSpreadPreferred, ReplicaSpreadRequired:
+		return nil
+	}
+	return fmt.Errorf("%s must be one of %s, %s, or %s", spread, ReplicaSpreadDisabled, ReplicaSpreadPreferred, ReplicaSpreadRequired)
+}
@@ -477,7 +477,6 @@ func Test_composeToServiceValues(t *testing.T) {
 			},
 		},
 		{
-			//@TODO: this should FAIL in the future https://github.com/uselagoon/build-deploy-tool/issues/56
 			name: "test11 - mariadb to mariadb-single via environment override with no patching db provider",
 			args: args{
 				buildValues: &BuildValues{
@@ -488,6 +487,7 @@ func Test_composeToServiceValues(t *testing.T) {
 					Branch:               "main",
 					BuildType:            "branch",
 					EnvironmentType:      "development",
+					DBaaSFallbackSingle:  true,
 					ServiceTypeOverrides: &lagoon.EnvironmentVariable{},
 					DBaaSEnvironmentTypeOverrides: &lagoon.EnvironmentVariable{
 						Name:  "LAGOON_DBAAS_ENVIRONMENT_TYPES",
@@ -532,6 +532,46 @@ func Test_composeToServiceValues(t *testing.T) {
 				IsSingle:       true,
 			},
 		},
+		{
+			name: "test11a - mariadb with no patching db provider fails the build when fallback is disabled",
+			args: args{
+				buildValues: &BuildValues{
+					Namespace:            "example-project-main",
+					Project:              "example-project",
+					ImageRegistry:        "harbor.example",
+					Environment:          "main",
+					Branch:               "main",
+					BuildType:            "branch",
+					EnvironmentType:      "development",
+					ServiceTypeOverrides: &lagoon.EnvironmentVariable{},
+					DBaaSEnvironmentTypeOverrides: &lagoon.EnvironmentVariable{
+						Name:  "LAGOON_DBAAS_ENVIRONMENT_TYPES",
+						Value: "mariadb:development2,postgres:postgres-single",
+					},
+					LagoonYAML: lagoon.YAML{
+						Routes: lagoon.Routes{
+							Autogenerate: lagoon.Autogenerate{
+								Enabled:           helpers.BoolPtr(true),
+								AllowPullRequests: helpers.BoolPtr(false),
+							},
+						},
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								AutogenerateRoutes: helpers.BoolPtr(true),
+							},
+						},
+					},
+				},
+				composeService: "mariadb",
+				composeServiceValues: composetypes.ServiceConfig{
+					Labels: composetypes.Labels{
+						"lagoon.type": "mariadb",
+					},
+					Image: "uselagoon/fake-mariadb:latest",
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "test12 - postgres to postgres-dbaas",
 			args: args{
@@ -1263,3 +1303,862 @@ func Test_checkDuplicateCronjobs(t *testing.T) {
 		})
 	}
 }
+
+func Test_getAutoscaling(t *testing.T) {
+	type args struct {
+		buildValues    *BuildValues
+		composeService string
+		labels         map[string]string
+		minReplicas    int32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *lagoon.Autoscaling
+		wantErr bool
+	}{
+		{
+			name: "test1 - no autoscaling",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+		},
+		{
+			name: "test2 - autoscaling labels with defaults",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":            "nginx-php",
+					"lagoon.autoscaling.max": "4",
+				},
+			},
+			want: &lagoon.Autoscaling{Min: 1, Max: 4, CPU: 80},
+		},
+		{
+			name: "test3 - autoscaling labels with spot replicas as the minimum",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":            "nginx-php",
+					"lagoon.autoscaling.max": "4",
+					"lagoon.autoscaling.cpu": "70",
+				},
+				minReplicas: 2,
+			},
+			want: &lagoon.Autoscaling{Min: 2, Max: 4, CPU: 70},
+		},
+		{
+			name: "test4 - autoscaling labels overridden by the environment in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"nginx": {
+										Autoscaling: &lagoon.Autoscaling{Min: 3, Max: 10},
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":            "nginx-php",
+					"lagoon.autoscaling.min": "2",
+					"lagoon.autoscaling.max": "4",
+					"lagoon.autoscaling.cpu": "70",
+				},
+			},
+			want: &lagoon.Autoscaling{Min: 3, Max: 10, CPU: 70},
+		},
+		{
+			name: "test5 - autoscaling only in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"nginx": {
+										Autoscaling: &lagoon.Autoscaling{Max: 6, CPU: 50},
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+			want: &lagoon.Autoscaling{Min: 1, Max: 6, CPU: 50},
+		},
+		{
+			name: "test6 - no maximum",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.autoscaling.min": "2",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - minimum greater than the maximum",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.autoscaling.min": "5",
+					"lagoon.autoscaling.max": "2",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test8 - invalid cpu",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.autoscaling.max": "2",
+					"lagoon.autoscaling.cpu": "80%",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getAutoscaling(tt.args.buildValues, tt.args.composeService, tt.args.labels, tt.args.minReplicas)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getAutoscaling() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getAutoscaling() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getResources(t *testing.T) {
+	type args struct {
+		buildValues    *BuildValues
+		composeService string
+		labels         map[string]string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *lagoon.Resources
+		wantErr bool
+	}{
+		{
+			name: "test1 - no resources",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+		},
+		{
+			name: "test2 - resources from labels",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":                      "nginx-php",
+					"lagoon.resources.requests.cpu":    "100m",
+					"lagoon.resources.requests.memory": "128Mi",
+					"lagoon.resources.limits.memory":   "1Gi",
+				},
+			},
+			want: &lagoon.Resources{
+				Requests: lagoon.ResourceValues{CPU: "100m", Memory: "128Mi"},
+				Limits:   lagoon.ResourceValues{Memory: "1Gi"},
+			},
+		},
+		{
+			name: "test3 - resource labels overridden by the environment in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"nginx": {
+										Resources: &lagoon.Resources{
+											Requests: lagoon.ResourceValues{Memory: "512Mi"},
+											Limits:   lagoon.ResourceValues{CPU: "2"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":                      "nginx-php",
+					"lagoon.resources.requests.cpu":    "100m",
+					"lagoon.resources.requests.memory": "128Mi",
+					"lagoon.resources.limits.memory":   "1Gi",
+				},
+			},
+			want: &lagoon.Resources{
+				Requests: lagoon.ResourceValues{CPU: "100m", Memory: "512Mi"},
+				Limits:   lagoon.ResourceValues{CPU: "2", Memory: "1Gi"},
+			},
+		},
+		{
+			name: "test4 - invalid resource quantity",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.resources.requests.memory": "128MB",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - request greater than the limit",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.resources.requests.cpu": "2",
+					"lagoon.resources.limits.cpu":   "500m",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - limit within the admin memory limit",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					Resources: Resources{
+						Limits: ResourceLimits{Memory: "16Gi"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.resources.limits.memory": "8Gi",
+				},
+			},
+			want: &lagoon.Resources{
+				Limits: lagoon.ResourceValues{Memory: "8Gi"},
+			},
+		},
+		{
+			name: "test7 - limit greater than the admin memory limit",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					Resources: Resources{
+						Limits: ResourceLimits{Memory: "16Gi"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.resources.limits.memory": "32Gi",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test8 - request greater than the admin cpu limit",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					Resources: Resources{
+						Limits: ResourceLimits{CPU: "1"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.resources.requests.cpu": "1500m",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getResources(tt.args.buildValues, tt.args.composeService, tt.args.labels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getResources() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getResources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getProbes(t *testing.T) {
+	type args struct {
+		buildValues    *BuildValues
+		composeService string
+		labels         map[string]string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *lagoon.Probes
+		wantErr bool
+	}{
+		{
+			name: "test1 - no probes",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.type": "node",
+				},
+			},
+		},
+		{
+			name: "test2 - probes from labels",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.type":                              "node",
+					"lagoon.probes.readiness.path":             "/healthz",
+					"lagoon.probes.readiness.port":             "8080",
+					"lagoon.probes.startup.period":             "10",
+					"lagoon.probes.startup.failurethreshold":   "30",
+					"lagoon.probes.liveness.initialdelay":      "120",
+					"lagoon.probes.readiness.successthreshold": "2",
+				},
+			},
+			want: &lagoon.Probes{
+				Liveness: &lagoon.Probe{InitialDelaySeconds: 120},
+				Readiness: &lagoon.Probe{
+					Path:             "/healthz",
+					Port:             8080,
+					SuccessThreshold: 2,
+				},
+				Startup: &lagoon.Probe{PeriodSeconds: 10, FailureThreshold: 30},
+			},
+		},
+		{
+			name: "test3 - probe labels overridden by the environment in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"node": {
+										Probes: &lagoon.Probes{
+											Readiness: &lagoon.Probe{Path: "/status"},
+											Startup:   &lagoon.Probe{FailureThreshold: 60},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.type":                  "node",
+					"lagoon.probes.readiness.path": "/healthz",
+					"lagoon.probes.readiness.port": "8080",
+				},
+			},
+			want: &lagoon.Probes{
+				Readiness: &lagoon.Probe{Path: "/status", Port: 8080},
+				Startup:   &lagoon.Probe{FailureThreshold: 60},
+			},
+		},
+		{
+			name: "test4 - invalid number",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.probes.liveness.initialdelay": "60s",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - path without a leading slash",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.probes.readiness.path": "healthz",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - liveness success threshold greater than 1",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.probes.liveness.successthreshold": "3",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - timeout longer than the period",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.probes.readiness.timeout": "20",
+					"lagoon.probes.readiness.period":  "10",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test8 - invalid port",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "node",
+				labels: map[string]string{
+					"lagoon.probes.startup.port": "70000",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getProbes(tt.args.buildValues, tt.args.composeService, tt.args.labels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getProbes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getProbes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getReplicaSpread(t *testing.T) {
+	type args struct {
+		buildValues    *BuildValues
+		composeService string
+		labels         map[string]string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - no replica spread",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+		},
+		{
+			name: "test2 - replica spread from the feature flag",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_REPLICA_SPREAD", Value: "preferred", Scope: "build"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+			want: "preferred",
+		},
+		{
+			name: "test3 - feature flag changed by the label",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_REPLICA_SPREAD", Value: "preferred", Scope: "build"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":          "nginx-php",
+					"lagoon.replicaspread": "required",
+				},
+			},
+			want: "required",
+		},
+		{
+			name: "test4 - label disabled by the environment in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"nginx": {
+										ReplicaSpread: "disabled",
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type":          "nginx-php",
+					"lagoon.replicaspread": "required",
+				},
+			},
+		},
+		{
+			name: "test5 - invalid feature flag",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_FEATURE_FLAG_REPLICA_SPREAD", Value: "enabled", Scope: "build"},
+					},
+				},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx-php",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - invalid label",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				labels: map[string]string{
+					"lagoon.replicaspread": "zone",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getReplicaSpread(tt.args.buildValues, tt.args.composeService, tt.args.labels, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getReplicaSpread() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getReplicaSpread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getLifecycle(t *testing.T) {
+	type args struct {
+		buildValues    *BuildValues
+		composeService string
+		lagoonType     string
+		labels         map[string]string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *lagoon.Lifecycle
+		wantErr bool
+	}{
+		{
+			name: "test1 - no lifecycle changes",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx",
+				},
+			},
+		},
+		{
+			name: "test2 - lifecycle from labels",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx",
+					"lagoon.lifecycle.terminationgraceperiod": "60",
+					"lagoon.lifecycle.prestop.sleep":          "15",
+					"lagoon.lifecycle.prestop.command":        "nginx -s quit",
+				},
+			},
+			want: &lagoon.Lifecycle{
+				TerminationGracePeriodSeconds: helpers.Int64Ptr(60),
+				PreStopSleepSeconds:           helpers.Int64Ptr(15),
+				PreStopCommand:                "nginx -s quit",
+			},
+		},
+		{
+			name: "test3 - lifecycle labels overridden by the environment in the lagoon.yml",
+			args: args{
+				buildValues: &BuildValues{
+					Environment: "main",
+					LagoonYAML: lagoon.YAML{
+						Environments: lagoon.Environments{
+							"main": lagoon.Environment{
+								Overrides: map[string]lagoon.Override{
+									"nginx": {
+										Lifecycle: &lagoon.Lifecycle{
+											PreStopSleepSeconds: helpers.Int64Ptr(0),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.type": "nginx",
+					"lagoon.lifecycle.terminationgraceperiod": "60",
+					"lagoon.lifecycle.prestop.sleep":          "15",
+				},
+			},
+			want: &lagoon.Lifecycle{
+				TerminationGracePeriodSeconds: helpers.Int64Ptr(60),
+				PreStopSleepSeconds:           helpers.Int64Ptr(0),
+			},
+		},
+		{
+			name: "test4 - invalid number",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.lifecycle.prestop.sleep": "5s",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - negative grace period",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.lifecycle.terminationgraceperiod": "-1",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - sleep longer than the default grace period",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "nginx",
+				lagoonType:     "nginx",
+				labels: map[string]string{
+					"lagoon.lifecycle.prestop.sleep": "30",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - sleep within the grace period of the service type",
+			args: args{
+				buildValues:    &BuildValues{Environment: "main"},
+				composeService: "mariadb",
+				lagoonType:     "mariadb-single",
+				labels: map[string]string{
+					"lagoon.lifecycle.prestop.sleep": "30",
+				},
+			},
+			want: &lagoon.Lifecycle{
+				PreStopSleepSeconds: helpers.Int64Ptr(30),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getLifecycle(tt.args.buildValues, tt.args.composeService, tt.args.lagoonType, tt.args.labels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getLifecycle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getLifecycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getAdditionalContainers(t *testing.T) {
+	overrides := func(o lagoon.Override) lagoon.YAML {
+		return lagoon.YAML{
+			Environments: lagoon.Environments{
+				"main": lagoon.Environment{
+					Overrides: map[string]lagoon.Override{
+						"nginx": o,
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name               string
+		buildValues        *BuildValues
+		wantSidecars       []lagoon.Container
+		wantInitContainers []lagoon.Container
+		wantErr            bool
+	}{
+		{
+			name:        "test1 - no additional containers",
+			buildValues: &BuildValues{Environment: "main"},
+		},
+		{
+			name: "test2 - sidecar and init container through the imagecache",
+			buildValues: &BuildValues{
+				Environment: "main",
+				ImageCache:  "imagecache.example.com/",
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{Name: "log-shipper", Image: "fluent/fluent-bit:2.2"},
+						{Name: "cloudsql-proxy", Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.8.0", Ports: []int32{5432}},
+					},
+					InitContainers: []lagoon.Container{
+						{Name: "wait-for-db", Image: "busybox", Command: []string{"sh", "-c", "sleep 5"}},
+					},
+				}),
+			},
+			wantSidecars: []lagoon.Container{
+				{Name: "log-shipper", Image: "imagecache.example.com/fluent/fluent-bit:2.2"},
+				{Name: "cloudsql-proxy", Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.8.0", Ports: []int32{5432}},
+			},
+			wantInitContainers: []lagoon.Container{
+				{Name: "wait-for-db", Image: "library/busybox", Command: []string{"sh", "-c", "sleep 5"}},
+			},
+		},
+		{
+			name: "test3 - invalid name",
+			buildValues: &BuildValues{
+				Environment: "main",
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{Name: "Log_Shipper", Image: "fluent/fluent-bit:2.2"},
+					},
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 - duplicate name across sidecars and init containers",
+			buildValues: &BuildValues{
+				Environment: "main",
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{Name: "helper", Image: "fluent/fluent-bit:2.2"},
+					},
+					InitContainers: []lagoon.Container{
+						{Name: "helper", Image: "busybox"},
+					},
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test5 - no image",
+			buildValues: &BuildValues{
+				Environment: "main",
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{Name: "log-shipper"},
+					},
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test6 - relative volume mount path",
+			buildValues: &BuildValues{
+				Environment: "main",
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{
+							Name:         "log-shipper",
+							Image:        "fluent/fluent-bit:2.2",
+							VolumeMounts: []lagoon.VolumeMount{{Name: "nginx", Path: "var/log"}},
+						},
+					},
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "test7 - resources exceed the admin memory limit",
+			buildValues: &BuildValues{
+				Environment: "main",
+				Resources: Resources{
+					Limits: ResourceLimits{Memory: "1Gi"},
+				},
+				LagoonYAML: overrides(lagoon.Override{
+					Sidecars: []lagoon.Container{
+						{
+							Name:      "log-shipper",
+							Image:     "fluent/fluent-bit:2.2",
+							Resources: &lagoon.Resources{Limits: lagoon.ResourceValues{Memory: "2Gi"}},
+						},
+					},
+				}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecars, initContainers, err := getAdditionalContainers(tt.buildValues, "nginx")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getAdditionalContainers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(sidecars, tt.wantSidecars) {
+				t.Errorf("getAdditionalContainers() sidecars = %v, want %v", sidecars, tt.wantSidecars)
+			}
+			if !reflect.DeepEqual(initContainers, tt.wantInitContainers) {
+				t.Errorf("getAdditionalContainers() initContainers = %v, want %v", initContainers, tt.wantInitContainers)
+			}
+		})
+	}
+}
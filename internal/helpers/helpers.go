@@ -232,6 +232,8 @@ func UnsetEnvVars(localVars []EnvironmentVariable) {
 		"LAGOON_FEATURE_FLAG_DEFAULT_INGRESS_CLASS",
 		"LAGOON_FEATURE_FLAG_ROOTLESS_WORKLOAD",
 		"DBAAS_OPERATOR_HTTP",
+		"DBAAS_PROVIDER_FILE",
+		"CUSTOM_SERVICE_TYPES_FILE",
 		"CONFIG_MAP_SHA",
 		"LAGOON_FEATURE_FLAG_IMAGECACHE_REGISTRY",
 		"CI",
@@ -25,6 +25,7 @@ type Environment struct {
 	Routes             []map[string][]Route `json:"routes"`
 	Cronjobs           []Cronjob            `json:"cronjobs"`
 	Overrides          map[string]Override  `json:"overrides,omitempty"`
+	NetworkPolicies    *NetworkPolicies     `json:"network-policies,omitempty"`
 }
 
 // Cronjob represents a Lagoon cronjob.
@@ -36,8 +37,105 @@ type Cronjob struct {
 }
 
 type Override struct {
-	Build Build  `json:"build,omitempty"`
-	Image string `json:"image,omitempty"`
+	Build          Build        `json:"build,omitempty"`
+	Image          string       `json:"image,omitempty"`
+	Autoscaling    *Autoscaling `json:"autoscaling,omitempty"`
+	Resources      *Resources   `json:"resources,omitempty"`
+	Probes         *Probes      `json:"probes,omitempty"`
+	Sidecars       []Container  `json:"sidecars,omitempty"`
+	InitContainers []Container  `json:"initContainers,omitempty"`
+	Lifecycle      *Lifecycle   `json:"lifecycle,omitempty"`
+	// how the replicas of the service are spread across zones and nodes, one of disabled, preferred, or required
+	ReplicaSpread string `json:"replicaSpread,omitempty"`
+}
+
+// Autoscaling is the horizontal pod autoscaling of a service, cpu is the target average utilization as a percentage
+type Autoscaling struct {
+	Min int32 `json:"min,omitempty"`
+	Max int32 `json:"max,omitempty"`
+	CPU int32 `json:"cpu,omitempty"`
+}
+
+// Resources are the cpu and memory requests and limits of the containers of a service
+type Resources struct {
+	Requests ResourceValues `json:"requests,omitempty"`
+	Limits   ResourceValues `json:"limits,omitempty"`
+}
+
+type ResourceValues struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// Probes are the liveness, readiness, and startup probes of the primary container of a service
+type Probes struct {
+	Liveness  *Probe `json:"liveness,omitempty"`
+	Readiness *Probe `json:"readiness,omitempty"`
+	Startup   *Probe `json:"startup,omitempty"`
+}
+
+// Probe changes the default probe of a service, if a path is provided the probe is a http probe, otherwise it is a tcp probe
+// any value that isn't provided uses the value from the default probe of the service type
+type Probe struct {
+	Path                string `json:"path,omitempty"`
+	Port                int32  `json:"port,omitempty"`
+	InitialDelaySeconds int32  `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32  `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int32  `json:"timeoutSeconds,omitempty"`
+	SuccessThreshold    int32  `json:"successThreshold,omitempty"`
+	FailureThreshold    int32  `json:"failureThreshold,omitempty"`
+}
+
+// Lifecycle changes how the pods of a service are stopped, if a pre stop sleep or command is provided it replaces the default
+// pre stop hook of the service type, a pre stop sleep of 0 without a command removes the hook
+type Lifecycle struct {
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	PreStopSleepSeconds           *int64 `json:"preStopSleepSeconds,omitempty"`
+	PreStopCommand                string `json:"preStopCommand,omitempty"`
+}
+
+// Container is an additional container that runs in the pods of a service, either as a sidecar or as an init container
+type Container struct {
+	Name         string        `json:"name"`
+	Image        string        `json:"image"`
+	Command      []string      `json:"command,omitempty"`
+	Args         []string      `json:"args,omitempty"`
+	Ports        []int32       `json:"ports,omitempty"`
+	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty"`
+	Resources    *Resources    `json:"resources,omitempty"`
+}
+
+// VolumeMount mounts one of the volumes of the pods of a service into an additional container
+type VolumeMount struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// NetworkPolicies restricts which services of an environment can receive traffic from which other services, and where the
+// services of an environment can send traffic to. the services and egress are opt-in, anything not declared is not restricted
+type NetworkPolicies struct {
+	Services map[string]ServiceNetworkPolicy `json:"services,omitempty"`
+	Ingress  *IngressNetworkPolicy           `json:"ingress,omitempty"`
+	Egress   *EgressNetworkPolicy            `json:"egress,omitempty"`
+}
+
+// ServiceNetworkPolicy is the other services of the environment that can send traffic to a service
+type ServiceNetworkPolicy struct {
+	From []string `json:"from"`
+}
+
+// IngressNetworkPolicy is the other environments of the same project that the isolation network policy allows traffic from
+type IngressNetworkPolicy struct {
+	Environments []string `json:"environments,omitempty"`
+}
+
+// EgressNetworkPolicy is the destinations outside of the environment that its services can send traffic to
+// dns is allowed unless it is set to false
+type EgressNetworkPolicy struct {
+	CIDRs        []string `json:"cidrs,omitempty"`
+	DNS          *bool    `json:"dns,omitempty"`
+	Environments []string `json:"environments,omitempty"`
 }
 
 type Build struct {
@@ -70,6 +168,7 @@ type YAML struct {
 	BackupSchedule       BackupSchedule               `json:"backup-schedule"`
 	EnvironmentVariables EnvironmentVariables         `json:"environment_variables,omitempty"`
 	ContainerRegistries  map[string]ContainerRegistry `json:"container-registries,omitempty"`
+	NetworkPolicies      *NetworkPolicies             `json:"network-policies,omitempty"`
 }
 
 type ContainerRegistry struct {
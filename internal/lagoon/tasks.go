@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -34,6 +37,22 @@ type Task struct {
 	ScaleWaitTime       int    `json:"scaleWaitTime"`
 	ScaleMaxIterations  int    `json:"scaleMaxIterations"`
 	RequiresEnvironment bool   `json:"requiresEnvironment"`
+	Mode                string `json:"mode"`
+}
+
+// the ways a task can be run, a task without a mode is run in a pod of the service
+const (
+	TaskModeExec = "exec"
+	TaskModeJob  = "job"
+)
+
+// ValidateTaskMode checks that the mode of a task is one of the supported modes
+func ValidateTaskMode(mode string) error {
+	switch mode {
+	case "", TaskModeExec, TaskModeJob:
+		return nil
+	}
+	return fmt.Errorf("the task mode %s is not valid, must be one of %s or %s", mode, TaskModeExec, TaskModeJob)
 }
 
 // NewTask .
@@ -62,6 +81,20 @@ func (e *PodScalingError) Error() string {
 	return e.ErrorText
 }
 
+// TaskJobFailedError is returned when the job of a task fails, the exit code is -1 if the task container never finished
+type TaskJobFailedError struct {
+	Job      string
+	ExitCode int32
+	Reason   string
+}
+
+func (e *TaskJobFailedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("task job %s failed with exit code %d: %s", e.Job, e.ExitCode, e.Reason)
+	}
+	return fmt.Sprintf("task job %s failed with exit code %d", e.Job, e.ExitCode)
+}
+
 func (t Task) String() string {
 	return fmt.Sprintf("{command: '%v', ns: '%v', service: '%v', shell:'%v'}", t.Command, t.Namespace, t.Service, t.Shell)
 }
@@ -76,7 +109,8 @@ func GetK8sClient(config *rest.Config) (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
-func getConfig() (*rest.Config, error) {
+// GetConfig returns the rest config from KUBECONFIG if it is set, otherwise the in cluster deployer token is used
+func GetConfig() (*rest.Config, error) {
 	var kubeconfig *string
 	kubeconfig = new(string)
 	*kubeconfig = helpers.GetEnv("KUBECONFIG", "", false)
@@ -137,6 +171,109 @@ func ExecuteTaskInEnvironment(task Task, prePost string) error {
 	return err
 }
 
+// ExecuteTaskJobInEnvironment runs a task as a job that was rendered from the pod template of the service of the task
+func ExecuteTaskJobInEnvironment(client kubernetes.Interface, task Task, job *batchv1.Job, prePost string) error {
+	fmt.Printf("##############################################\nBEGIN %s %s\n##############################################\n", prePost, task.Name)
+	st := time.Now()
+
+	err := RunTaskJob(context.TODO(), client, task, job, os.Stdout)
+	if err != nil {
+		fmt.Printf("Failed to execute task `%v` due to reason `%v`\n", task.Name, err.Error())
+	}
+
+	et := time.Now()
+	diff := time.Time{}.Add(et.Sub(st))
+	tz, _ := et.Zone()
+	fmt.Printf("##############################################\nSTEP %s %s: Completed at %s (%s) Duration %s Elapsed %s\n##############################################\n", prePost, task.Name, et.Format("2006-01-02 15:04:05"), tz, diff.Format("15:04:05"), diff.Format("15:04:05"))
+
+	return err
+}
+
+// RunTaskJob creates the job of a task, streams the logs of its pod to out, and waits for the job to finish.
+// the pod has ScaleMaxIterations attempts of ScaleWaitTime seconds to start, once it has started the task can take as long as
+// it needs. a job left over from an earlier run of the same task in the same build is replaced, and a job whose pod never
+// starts is removed, so that the task doesn't run once the build has already failed
+func RunTaskJob(ctx context.Context, client kubernetes.Interface, task Task, job *batchv1.Job, out io.Writer) error {
+	jobClient := client.BatchV1().Jobs(task.Namespace)
+	propagation := v1.DeletePropagationBackground
+	err := jobClient.Delete(ctx, job.Name, v1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to remove the existing task job %s: %v", job.Name, err)
+	}
+	if _, err := jobClient.Create(ctx, job, v1.CreateOptions{}); err != nil {
+		return fmt.Errorf("unable to create the task job %s: %v", job.Name, err)
+	}
+	if debug {
+		fmt.Printf("Executing task '%v' in job %v \n", task.Name, job.Name)
+	}
+
+	// wait for the pod of the job to start, or finish if it runs quickly
+	podClient := client.CoreV1().Pods(task.Namespace)
+	podSelector := v1.ListOptions{LabelSelector: "lagoon.sh/taskJob=" + job.Name}
+	var pod *corev1.Pod
+	for numIterations := 1; pod == nil; numIterations++ {
+		pods, err := podClient.List(ctx, podSelector)
+		if err != nil {
+			return err
+		}
+		for idx := range pods.Items {
+			if pods.Items[idx].Status.Phase != corev1.PodPending && pods.Items[idx].Status.Phase != "" {
+				pod = &pods.Items[idx]
+				break
+			}
+		}
+		if pod == nil {
+			if numIterations >= task.ScaleMaxIterations {
+				err := jobClient.Delete(ctx, job.Name, v1.DeleteOptions{PropagationPolicy: &propagation})
+				if err != nil && !apierrors.IsNotFound(err) {
+					fmt.Fprintf(out, "Unable to remove the task job %s: %v\n", job.Name, err)
+				}
+				return &PodScalingError{
+					ErrorText: "Unable to find a running Pod for task job: " + job.Name,
+				}
+			}
+			time.Sleep(time.Second * time.Duration(task.ScaleWaitTime))
+		}
+	}
+
+	// follow the logs of the task container until it exits, a problem with the logs doesn't fail the task
+	logs, err := podClient.GetLogs(pod.Name, &corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "Unable to stream the logs of task job %s: %v\n", job.Name, err)
+	} else {
+		_, err = io.Copy(out, logs)
+		logs.Close()
+		if err != nil {
+			fmt.Fprintf(out, "Unable to stream the logs of task job %s: %v\n", job.Name, err)
+		}
+		fmt.Fprintln(out)
+	}
+
+	// wait for the job to finish, and report the exit code of the task container
+	for {
+		current, err := jobClient.Get(ctx, job.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.Status.Succeeded > 0 || current.Status.Failed > 0 {
+			exitCode, reason := int32(-1), ""
+			if p, err := podClient.Get(ctx, pod.Name, v1.GetOptions{}); err == nil {
+				for _, status := range p.Status.ContainerStatuses {
+					if status.State.Terminated != nil {
+						exitCode, reason = status.State.Terminated.ExitCode, status.State.Terminated.Reason
+					}
+				}
+			}
+			if current.Status.Failed > 0 {
+				return &TaskJobFailedError{Job: job.Name, ExitCode: exitCode, Reason: reason}
+			}
+			fmt.Fprintf(out, "Task job %s completed with exit code %d\n", job.Name, exitCode)
+			return nil
+		}
+		time.Sleep(time.Second * time.Duration(task.ScaleWaitTime))
+	}
+}
+
 // ExecTaskInPod .
 func ExecTaskInPod(
 	task Task,
@@ -144,7 +281,7 @@ func ExecTaskInPod(
 	tty bool,
 ) error {
 
-	restCfg, err := getConfig()
+	restCfg, err := GetConfig()
 	if err != nil {
 		return err
 	}
@@ -310,7 +447,7 @@ func unidleReplicas(deploy appsv1.Deployment) int {
 var NamespaceUnidlingTimeoutError = errors.New("Unable to scale idled deployments due to timeout")
 
 func UnidleNamespace(ctx context.Context, namespace string, retries int, waitTime int) error {
-	restCfg, err := getConfig()
+	restCfg, err := GetConfig()
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,17 @@
 package lagoon
 
 import (
+	"bytes"
+	"context"
 	"reflect"
 	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestNewTask(t *testing.T) {
@@ -28,3 +37,105 @@ func TestNewTask(t *testing.T) {
 		})
 	}
 }
+
+func TestRunTaskJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		jobStatus  batchv1.JobStatus
+		podPhase   corev1.PodPhase
+		exitCode   int32
+		wantErr    error
+		wantOutput string
+		wantJob    bool
+	}{
+		{
+			name:       "test1 - task job succeeds",
+			jobStatus:  batchv1.JobStatus{Succeeded: 1},
+			podPhase:   corev1.PodSucceeded,
+			exitCode:   0,
+			wantOutput: "fake logs\nTask job pre-rollout-task-abcdefghij completed with exit code 0\n",
+			wantJob:    true,
+		},
+		{
+			name:      "test2 - task job fails",
+			jobStatus: batchv1.JobStatus{Failed: 1},
+			podPhase:  corev1.PodFailed,
+			exitCode:  2,
+			wantErr: &TaskJobFailedError{
+				Job:      "pre-rollout-task-abcdefghij",
+				ExitCode: 2,
+				Reason:   "Error",
+			},
+			wantOutput: "fake logs\n",
+			wantJob:    true,
+		},
+		{
+			name:      "test3 - task job pod never starts",
+			jobStatus: batchv1.JobStatus{},
+			podPhase:  corev1.PodPending,
+			wantErr: &PodScalingError{
+				ErrorText: "Unable to find a running Pod for task job: pre-rollout-task-abcdefghij",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{
+				Name:               "drush deploy",
+				Namespace:          "example-project-main",
+				ScaleMaxIterations: 2,
+				ScaleWaitTime:      0,
+			}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pre-rollout-task-abcdefghij",
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pre-rollout-task-abcdefghij-xxxxx",
+					Namespace: task.Namespace,
+					Labels: map[string]string{
+						"lagoon.sh/taskJob": job.Name,
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase: tt.podPhase,
+				},
+			}
+			if tt.podPhase != corev1.PodPending {
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+					{
+						Name: "cli",
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{
+								ExitCode: tt.exitCode,
+								Reason:   "Error",
+							},
+						},
+					},
+				}
+			}
+			client := fake.NewSimpleClientset(pod)
+			// the job controller doesn't run against the fake client, so the job is created already finished
+			client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				created := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+				created.Status = tt.jobStatus
+				return false, nil, nil
+			})
+			var out bytes.Buffer
+			err := RunTaskJob(context.Background(), client, task, job, &out)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("RunTaskJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.wantOutput {
+				t.Errorf("RunTaskJob() output = %q, want %q", out.String(), tt.wantOutput)
+			}
+			// a job whose pod never starts is removed
+			_, err = client.BatchV1().Jobs(task.Namespace).Get(context.Background(), job.Name, metav1.GetOptions{})
+			if (err == nil) != tt.wantJob {
+				t.Errorf("RunTaskJob() job exists = %v, want %v", err == nil, tt.wantJob)
+			}
+		})
+	}
+}
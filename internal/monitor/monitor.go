@@ -0,0 +1,378 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the number of log lines that are captured from each failing container
+const logTailLines int64 = 50
+
+// these container waiting reasons mean the container is not going to start without intervention
+var failingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"InvalidImageName":           true,
+	"RunContainerError":          true,
+}
+
+// the kinds of workloads that can be monitored
+const (
+	Deployment  = "Deployment"
+	StatefulSet = "StatefulSet"
+)
+
+// Workload is a deployment or statefulset that is rolled out by a build
+type Workload struct {
+	Kind string
+	Name string
+}
+
+// Status is the outcome of a workload rollout
+type Status string
+
+const (
+	Complete Status = "complete"
+	Failed   Status = "failed"
+	TimedOut Status = "timed out"
+)
+
+// Container is the state of a container that prevented a rollout from completing
+type Container struct {
+	Pod          string `json:"pod"`
+	Name         string `json:"name"`
+	Reason       string `json:"reason"`
+	Message      string `json:"message,omitempty"`
+	RestartCount int32  `json:"restartCount"`
+	Logs         string `json:"logs,omitempty"`
+}
+
+// PodCondition is a condition of a pod that isn't true, with the message kubernetes gave for it
+type PodCondition struct {
+	Pod     string `json:"pod"`
+	Phase   string `json:"phase"`
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+}
+
+// Result is the outcome of monitoring the rollout of a single workload
+type Result struct {
+	Kind       string         `json:"kind"`
+	Name       string         `json:"name"`
+	Status     Status         `json:"status"`
+	Message    string         `json:"message,omitempty"`
+	Containers []Container    `json:"containers,omitempty"`
+	Conditions []PodCondition `json:"conditions,omitempty"`
+	Events     []string       `json:"events,omitempty"`
+}
+
+func (r Result) String() string {
+	if r.Message != "" {
+		return fmt.Sprintf("%s/%s %s: %s", strings.ToLower(r.Kind), r.Name, r.Status, r.Message)
+	}
+	return fmt.Sprintf("%s/%s %s", strings.ToLower(r.Kind), r.Name, r.Status)
+}
+
+// Diagnosis returns a summary of why a rollout failed, including any pod conditions, events and container logs that were captured
+func (r Result) Diagnosis() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rollout for %s %s", r.Name, r.Status)
+	if r.Message != "" {
+		fmt.Fprintf(&b, ": %s", r.Message)
+	}
+	b.WriteString("\n")
+	for _, c := range r.Containers {
+		fmt.Fprintf(&b, "> container %s in pod %s is %s (restarts: %d)", c.Name, c.Pod, c.Reason, c.RestartCount)
+		if c.Message != "" {
+			fmt.Fprintf(&b, ": %s", c.Message)
+		}
+		b.WriteString("\n")
+	}
+	for _, c := range r.Conditions {
+		fmt.Fprintf(&b, "> pod %s (%s) condition %s is not met", c.Pod, c.Phase, c.Type)
+		if c.Message != "" {
+			fmt.Fprintf(&b, ": %s", c.Message)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Events) > 0 {
+		b.WriteString("Events:\n")
+		for _, e := range r.Events {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	for _, c := range r.Containers {
+		if c.Logs == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "======== %s/%s =========\n%s\n", c.Pod, c.Name, strings.TrimRight(c.Logs, "\n"))
+	}
+	if len(r.Containers) == 0 && len(r.Conditions) == 0 && len(r.Events) == 0 {
+		b.WriteString("There was no additional information available from the pods of this service\n")
+	}
+	return b.String()
+}
+
+// Monitor watches the rollout of deployments and statefulsets in a namespace
+type Monitor struct {
+	client       kubernetes.Interface
+	namespace    string
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// NewMonitor returns a monitor for the provided namespace, rollouts that don't complete within the timeout are failed
+func NewMonitor(client kubernetes.Interface, namespace string, timeout, pollInterval time.Duration) *Monitor {
+	return &Monitor{
+		client:       client,
+		namespace:    namespace,
+		timeout:      timeout,
+		pollInterval: pollInterval,
+	}
+}
+
+// Rollout waits for all the workloads to complete their rollout, any workload that fails or doesn't complete
+// within the timeout is diagnosed. The results are returned in the same order as the workloads were provided
+func (m *Monitor) Rollout(ctx context.Context, workloads ...Workload) ([]Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	results := make([]Result, len(workloads))
+	pending := map[int]bool{}
+	for idx, w := range workloads {
+		results[idx].Kind = w.Kind
+		results[idx].Name = w.Name
+		pending[idx] = true
+	}
+	for {
+		for idx := range pending {
+			status, message, selector, err := m.rolloutStatus(ctx, workloads[idx])
+			if err != nil {
+				// a request that is cut off by the timeout is the same as a rollout that didn't complete in time
+				if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+					return m.timedOut(workloads, results, pending)
+				}
+				return results, err
+			}
+			if status == "" {
+				continue
+			}
+			results[idx].Status = status
+			results[idx].Message = message
+			if status == Failed {
+				if err := m.diagnose(ctx, workloads[idx], selector, &results[idx]); err != nil {
+					return results, err
+				}
+			}
+			delete(pending, idx)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return m.timedOut(workloads, results, pending)
+		case <-time.After(m.pollInterval):
+		}
+	}
+	return results, failedError(results)
+}
+
+// timedOut marks all the pending workloads as timed out and diagnoses them, the diagnosis still needs to talk
+// to the api, so it can't use the expired context
+func (m *Monitor) timedOut(workloads []Workload, results []Result, pending map[int]bool) ([]Result, error) {
+	for idx := range pending {
+		results[idx].Status = TimedOut
+		results[idx].Message = fmt.Sprintf("rollout did not complete within %s", m.timeout)
+		_, _, selector, err := m.rolloutStatus(context.Background(), workloads[idx])
+		if err != nil {
+			return results, err
+		}
+		if err := m.diagnose(context.Background(), workloads[idx], selector, &results[idx]); err != nil {
+			return results, err
+		}
+	}
+	return results, failedError(results)
+}
+
+// rolloutStatus gets the current status of a workload, and the selector for its pods
+func (m *Monitor) rolloutStatus(ctx context.Context, w Workload) (Status, string, *metav1.LabelSelector, error) {
+	switch w.Kind {
+	case Deployment:
+		d, err := m.client.AppsV1().Deployments(m.namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", nil, fmt.Errorf("couldn't get deployment %s: %w", w.Name, err)
+		}
+		status, message := deploymentStatus(d)
+		return status, message, d.Spec.Selector, nil
+	case StatefulSet:
+		s, err := m.client.AppsV1().StatefulSets(m.namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", nil, fmt.Errorf("couldn't get statefulset %s: %w", w.Name, err)
+		}
+		return statefulSetStatus(s), "", s.Spec.Selector, nil
+	}
+	return "", "", nil, fmt.Errorf("unable to monitor %s %s, unsupported kind", w.Kind, w.Name)
+}
+
+// deploymentStatus checks the status of a deployment the same way `kubectl rollout status` does,
+// an empty status means the rollout is still in progress
+func deploymentStatus(d *appsv1.Deployment) (Status, string) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return "", ""
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return Failed, c.Message
+		}
+	}
+	if d.Spec.Replicas != nil && d.Status.UpdatedReplicas < *d.Spec.Replicas {
+		return "", ""
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return "", ""
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return "", ""
+	}
+	return Complete, ""
+}
+
+// statefulSetStatus checks the status of a statefulset the same way `kubectl rollout status` does,
+// statefulsets have no progress deadline so a rollout that never completes is only caught by the timeout
+func statefulSetStatus(s *appsv1.StatefulSet) Status {
+	// statefulsets that aren't rolling updates are only updated when their pods are deleted
+	if s.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return Complete
+	}
+	if s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration {
+		return ""
+	}
+	if s.Spec.Replicas != nil && s.Status.ReadyReplicas < *s.Spec.Replicas {
+		return ""
+	}
+	if s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		if s.Spec.Replicas != nil && s.Status.UpdatedReplicas < *s.Spec.Replicas-*s.Spec.UpdateStrategy.RollingUpdate.Partition {
+			return ""
+		}
+		return Complete
+	}
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return ""
+	}
+	return Complete
+}
+
+// diagnose collects the failing containers and their logs, the unmet pod conditions, and the warning events
+// for the pods of a workload
+func (m *Monitor) diagnose(ctx context.Context, w Workload, labelSelector *metav1.LabelSelector, result *Result) error {
+	kind := strings.ToLower(w.Kind)
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return fmt.Errorf("couldn't read selector for %s %s: %v", kind, w.Name, err)
+	}
+	pods, err := m.client.CoreV1().Pods(m.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't list pods for %s %s: %v", kind, w.Name, err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+	podNames := map[string]bool{}
+	for _, pod := range pods.Items {
+		podNames[pod.Name] = true
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.State.Waiting == nil || !failingReasons[cs.State.Waiting.Reason] {
+				continue
+			}
+			result.Containers = append(result.Containers, Container{
+				Pod:          pod.Name,
+				Name:         cs.Name,
+				Reason:       cs.State.Waiting.Reason,
+				Message:      cs.State.Waiting.Message,
+				RestartCount: cs.RestartCount,
+				// a crashlooping container is waiting to be restarted, so the logs are in the previous instance
+				Logs: m.containerLogs(ctx, pod.Name, cs.Name, cs.RestartCount > 0),
+			})
+		}
+		for _, c := range pod.Status.Conditions {
+			if c.Status == corev1.ConditionTrue {
+				continue
+			}
+			result.Conditions = append(result.Conditions, PodCondition{
+				Pod:     pod.Name,
+				Phase:   string(pod.Status.Phase),
+				Type:    string(c.Type),
+				Message: c.Message,
+			})
+		}
+	}
+	events, err := m.client.CoreV1().Events(m.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("couldn't list events for %s %s: %v", kind, w.Name, err)
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	for _, e := range events.Items {
+		if e.Type != corev1.EventTypeWarning {
+			continue
+		}
+		involved := e.InvolvedObject
+		if (involved.Kind == "Pod" && podNames[involved.Name]) || (involved.Kind == w.Kind && involved.Name == w.Name) {
+			result.Events = append(result.Events, fmt.Sprintf("%s/%s %s: %s", strings.ToLower(involved.Kind), involved.Name, e.Reason, e.Message))
+		}
+	}
+	return nil
+}
+
+// containerLogs returns the tail of the logs of a container, logs are only used to help with debugging
+// so if they can't be retrieved the reason is returned in place of the logs
+func (m *Monitor) containerLogs(ctx context.Context, pod, container string, previous bool) string {
+	tail := logTailLines
+	stream, err := m.client.CoreV1().Pods(m.namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		Timestamps: true,
+		TailLines:  &tail,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Sprintf("unable to retrieve logs: %v", err)
+	}
+	defer stream.Close()
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return fmt.Sprintf("unable to retrieve logs: %v", err)
+	}
+	return string(logs)
+}
+
+// failedError returns an error naming all the workloads that didn't complete their rollout
+func failedError(results []Result) error {
+	failed := []string{}
+	for _, r := range results {
+		if r.Status != Complete {
+			failed = append(failed, r.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("rollout failed for %s", strings.Join(failed, ", "))
+}
@@ -0,0 +1,385 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "example-project-main"
+
+func testDeployment(name string, replicas int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  testNamespace,
+			Generation: 2,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+		},
+		Status: status,
+	}
+}
+
+func testStatefulSet(name string, replicas int32, status appsv1.StatefulSetStatus) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  testNamespace,
+			Generation: 2,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+		},
+		Status: status,
+	}
+}
+
+func testPod(name, instance string, statuses []corev1.ContainerStatus, conditions []corev1.PodCondition) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/instance": instance,
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			Conditions:        conditions,
+			ContainerStatuses: statuses,
+		},
+	}
+}
+
+func TestMonitor_Rollout(t *testing.T) {
+	complete := appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           1,
+		UpdatedReplicas:    1,
+		AvailableReplicas:  1,
+	}
+	progressing := appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           2,
+		UpdatedReplicas:    1,
+		AvailableReplicas:  1,
+	}
+	deadlineExceeded := appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           2,
+		UpdatedReplicas:    1,
+		AvailableReplicas:  1,
+		Conditions: []appsv1.DeploymentCondition{
+			{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: `ReplicaSet "nginx-abcd1234" has timed out progressing.`,
+			},
+		},
+	}
+	crashLoop := []corev1.ContainerStatus{
+		{
+			Name:         "nginx",
+			RestartCount: 4,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "CrashLoopBackOff",
+					Message: "back-off 1m20s restarting failed container",
+				},
+			},
+		},
+		{
+			Name:  "php",
+			Ready: true,
+			State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{},
+			},
+		},
+	}
+	notReady := []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+		{Type: corev1.PodReady, Status: corev1.ConditionFalse, Message: "containers with unready status: [nginx]"},
+	}
+	tests := []struct {
+		name      string
+		existing  []runtime.Object
+		workloads []Workload
+		want      []Result
+		wantErr   bool
+	}{
+		{
+			name: "all complete",
+			existing: []runtime.Object{
+				testDeployment("cli", 1, complete),
+				testDeployment("nginx", 1, complete),
+			},
+			workloads: []Workload{{Kind: Deployment, Name: "nginx"}, {Kind: Deployment, Name: "cli"}},
+			want: []Result{
+				{Kind: Deployment, Name: "nginx", Status: Complete},
+				{Kind: Deployment, Name: "cli", Status: Complete},
+			},
+		},
+		{
+			name: "progress deadline exceeded with crashlooping container",
+			existing: []runtime.Object{
+				testDeployment("cli", 1, complete),
+				testDeployment("nginx", 1, deadlineExceeded),
+				testPod("nginx-abcd1234-x1y2z", "nginx", crashLoop, notReady),
+				testPod("cli-abcd1234-x1y2z", "cli", nil, nil),
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "nginx-abcd1234-x1y2z.1", Namespace: testNamespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "nginx-abcd1234-x1y2z"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "BackOff",
+					Message:        "Back-off restarting failed container nginx",
+				},
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "nginx-abcd1234-x1y2z.2", Namespace: testNamespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "nginx-abcd1234-x1y2z"},
+					Type:           corev1.EventTypeNormal,
+					Reason:         "Pulled",
+					Message:        "Container image already present on machine",
+				},
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "cli-abcd1234-x1y2z.1", Namespace: testNamespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "cli-abcd1234-x1y2z"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "BackOff",
+					Message:        "Back-off restarting failed container cli",
+				},
+			},
+			workloads: []Workload{{Kind: Deployment, Name: "cli"}, {Kind: Deployment, Name: "nginx"}},
+			want: []Result{
+				{Kind: Deployment, Name: "cli", Status: Complete},
+				{
+					Kind:    Deployment,
+					Name:    "nginx",
+					Status:  Failed,
+					Message: `ReplicaSet "nginx-abcd1234" has timed out progressing.`,
+					Containers: []Container{
+						{
+							Pod:          "nginx-abcd1234-x1y2z",
+							Name:         "nginx",
+							Reason:       "CrashLoopBackOff",
+							Message:      "back-off 1m20s restarting failed container",
+							RestartCount: 4,
+							Logs:         "fake logs",
+						},
+					},
+					Conditions: []PodCondition{
+						{
+							Pod:     "nginx-abcd1234-x1y2z",
+							Phase:   "Running",
+							Type:    "Ready",
+							Message: "containers with unready status: [nginx]",
+						},
+					},
+					Events: []string{
+						"pod/nginx-abcd1234-x1y2z BackOff: Back-off restarting failed container nginx",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "old replicas never terminate",
+			existing: []runtime.Object{
+				testDeployment("nginx", 1, progressing),
+			},
+			workloads: []Workload{{Kind: Deployment, Name: "nginx"}},
+			want: []Result{
+				{
+					Kind:    Deployment,
+					Name:    "nginx",
+					Status:  TimedOut,
+					Message: "rollout did not complete within 50ms",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "missing deployment",
+			workloads: []Workload{{Kind: Deployment, Name: "nginx"}},
+			want:      []Result{{Kind: Deployment, Name: "nginx"}},
+			wantErr:   true,
+		},
+		{
+			name: "statefulset complete",
+			existing: []runtime.Object{
+				testDeployment("cli", 1, complete),
+				testStatefulSet("mariadb", 1, appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					Replicas:           1,
+					ReadyReplicas:      1,
+					UpdatedReplicas:    1,
+					CurrentRevision:    "mariadb-abcd1234",
+					UpdateRevision:     "mariadb-abcd1234",
+				}),
+			},
+			workloads: []Workload{{Kind: Deployment, Name: "cli"}, {Kind: StatefulSet, Name: "mariadb"}},
+			want: []Result{
+				{Kind: Deployment, Name: "cli", Status: Complete},
+				{Kind: StatefulSet, Name: "mariadb", Status: Complete},
+			},
+		},
+		{
+			name: "statefulset pod never becomes ready",
+			existing: []runtime.Object{
+				testStatefulSet("mariadb", 1, appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					Replicas:           1,
+					UpdatedReplicas:    1,
+					CurrentRevision:    "mariadb-abcd1234",
+					UpdateRevision:     "mariadb-efgh5678",
+				}),
+				testPod("mariadb-0", "mariadb", nil, notReady),
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "mariadb.1", Namespace: testNamespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "StatefulSet", Name: "mariadb"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "FailedCreate",
+					Message:        "create Pod mariadb-0 in StatefulSet mariadb failed",
+				},
+			},
+			workloads: []Workload{{Kind: StatefulSet, Name: "mariadb"}},
+			want: []Result{
+				{
+					Kind:    StatefulSet,
+					Name:    "mariadb",
+					Status:  TimedOut,
+					Message: "rollout did not complete within 50ms",
+					Conditions: []PodCondition{
+						{
+							Pod:     "mariadb-0",
+							Phase:   "Running",
+							Type:    "Ready",
+							Message: "containers with unready status: [nginx]",
+						},
+					},
+					Events: []string{
+						"statefulset/mariadb FailedCreate: create Pod mariadb-0 in StatefulSet mariadb failed",
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.existing...)
+			m := NewMonitor(client, testNamespace, 50*time.Millisecond, time.Millisecond)
+			got, err := m.Rollout(context.TODO(), tt.workloads...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Rollout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rollout() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitor_RolloutCompletes(t *testing.T) {
+	client := fake.NewSimpleClientset(testDeployment("nginx", 1, appsv1.DeploymentStatus{ObservedGeneration: 1}))
+	// complete the rollout after the monitor has started polling
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		d := testDeployment("nginx", 1, appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		})
+		if _, err := client.AppsV1().Deployments(testNamespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{}); err != nil {
+			t.Errorf("%v", err)
+		}
+	}()
+	m := NewMonitor(client, testNamespace, 5*time.Second, time.Millisecond)
+	got, err := m.Rollout(context.TODO(), Workload{Kind: Deployment, Name: "nginx"})
+	if err != nil {
+		t.Errorf("Rollout() error = %v", err)
+	}
+	want := []Result{{Kind: Deployment, Name: "nginx", Status: Complete}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rollout() = %v, want %v", got, want)
+	}
+}
+
+func TestMonitor_RolloutDeadlineExceeded(t *testing.T) {
+	client := fake.NewSimpleClientset(testDeployment("nginx", 1, appsv1.DeploymentStatus{ObservedGeneration: 1}))
+	// the first request is cut off by the timeout, the same way the api client reports an expired context
+	var once sync.Once
+	client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		handled := false
+		once.Do(func() {
+			handled = true
+		})
+		if handled {
+			return true, nil, context.DeadlineExceeded
+		}
+		return false, nil, nil
+	})
+	m := NewMonitor(client, testNamespace, 50*time.Millisecond, time.Millisecond)
+	got, err := m.Rollout(context.TODO(), Workload{Kind: Deployment, Name: "nginx"})
+	if err == nil || !strings.Contains(err.Error(), "rollout failed for nginx") {
+		t.Errorf("Rollout() error = %v, want rollout failed for nginx", err)
+	}
+	want := []Result{{Kind: Deployment, Name: "nginx", Status: TimedOut, Message: "rollout did not complete within 50ms"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rollout() = %v, want %v", got, want)
+	}
+}
+
+func TestResult_Diagnosis(t *testing.T) {
+	r := Result{
+		Name:    "nginx",
+		Status:  Failed,
+		Message: "progress deadline exceeded",
+		Containers: []Container{
+			{Pod: "nginx-abc", Name: "nginx", Reason: "CrashLoopBackOff", RestartCount: 3, Logs: "line1\nline2\n"},
+		},
+		Events: []string{"pod/nginx-abc BackOff: Back-off restarting failed container nginx"},
+	}
+	want := `Rollout for nginx failed: progress deadline exceeded
+> container nginx in pod nginx-abc is CrashLoopBackOff (restarts: 3)
+Events:
+  pod/nginx-abc BackOff: Back-off restarting failed container nginx
+======== nginx-abc/nginx =========
+line1
+line2
+`
+	if got := r.Diagnosis(); got != want {
+		t.Errorf("Diagnosis() = %v, want %v", got, want)
+	}
+	empty := Result{Name: "cli", Status: TimedOut}
+	if got := empty.Diagnosis(); !strings.Contains(got, "no additional information") {
+		t.Errorf("Diagnosis() = %v, want no additional information", got)
+	}
+}
@@ -0,0 +1,300 @@
+package plandiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// the values of these kinds can contain secrets, so only the fact that a value changed is reported
+var redactedKinds = map[string]bool{
+	"Secret":    true,
+	"ConfigMap": true,
+}
+
+// the fields of these kinds that hold the redacted values
+var redactedFields = []string{"data", "stringData", "binaryData"}
+
+// RedactedValue is reported in place of any value that could contain a secret
+const RedactedValue = "**REDACTED**"
+
+// Change is the type of change made to an object or field
+type Change string
+
+const (
+	Added   Change = "added"
+	Removed Change = "removed"
+	Changed Change = "changed"
+)
+
+// Object is a templated kubernetes object
+type Object struct {
+	Kind    string
+	Name    string
+	Content map[string]interface{}
+}
+
+func (o Object) String() string {
+	return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+}
+
+// FieldDiff is a change to a single field of an object, values are encoded as JSON
+type FieldDiff struct {
+	Path   string `json:"path"`
+	Change Change `json:"change"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// ObjectDiff is a change to an object, and the fields that changed if the object exists in both plans
+type ObjectDiff struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Change Change      `json:"change"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// ReadObjects reads all the yaml documents from a reader, skipping any empty documents
+func ReadObjects(r io.Reader, source string) ([]Object, error) {
+	objects := []Object{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %v: %v", source, err)
+		}
+		content := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &content); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal %v: %v", source, err)
+		}
+		kind, _ := content["kind"].(string)
+		if kind == "" {
+			continue
+		}
+		name := ""
+		if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+			name, _ = metadata["name"].(string)
+			// this is always null in generated templates, and is set by the api server
+			delete(metadata, "creationTimestamp")
+		}
+		objects = append(objects, Object{Kind: kind, Name: name, Content: content})
+	}
+	return objects, nil
+}
+
+// Diff compares the objects of two build plans, the results are sorted by kind and then name
+func Diff(base, target []Object) []ObjectDiff {
+	baseObjects := map[string]Object{}
+	for _, o := range base {
+		baseObjects[o.String()] = o
+	}
+	targetObjects := map[string]Object{}
+	for _, o := range target {
+		targetObjects[o.String()] = o
+	}
+	diffs := []ObjectDiff{}
+	for key, b := range baseObjects {
+		t, ok := targetObjects[key]
+		if !ok {
+			diffs = append(diffs, ObjectDiff{Kind: b.Kind, Name: b.Name, Change: Removed})
+			continue
+		}
+		fields := redact(b.Kind, diffValues("", b.Content, t.Content))
+		if len(fields) > 0 {
+			diffs = append(diffs, ObjectDiff{Kind: b.Kind, Name: b.Name, Change: Changed, Fields: fields})
+		}
+	}
+	for key, t := range targetObjects {
+		if _, ok := baseObjects[key]; !ok {
+			diffs = append(diffs, ObjectDiff{Kind: t.Kind, Name: t.Name, Change: Added})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Kind != diffs[j].Kind {
+			return diffs[i].Kind < diffs[j].Kind
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// Format returns a human readable version of the differences between two build plans
+func Format(diffs []ObjectDiff) string {
+	if len(diffs) == 0 {
+		return "No changes to the build plan\n"
+	}
+	var b strings.Builder
+	symbols := map[Change]string{Added: "+", Removed: "-", Changed: "~"}
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "%s %s/%s\n", symbols[d.Change], d.Kind, d.Name)
+		for _, f := range d.Fields {
+			switch f.Change {
+			case Added:
+				fmt.Fprintf(&b, "    + %s: %s\n", f.Path, f.After)
+			case Removed:
+				fmt.Fprintf(&b, "    - %s: %s\n", f.Path, f.Before)
+			default:
+				fmt.Fprintf(&b, "    ~ %s: %s => %s\n", f.Path, f.Before, f.After)
+			}
+		}
+	}
+	return b.String()
+}
+
+// redact replaces the values of any fields that could contain secrets, the path of the field is still reported
+// so that it is known the value changed
+func redact(kind string, fields []FieldDiff) []FieldDiff {
+	if !redactedKinds[kind] {
+		return fields
+	}
+	for idx, f := range fields {
+		for _, field := range redactedFields {
+			if f.Path != field && !strings.HasPrefix(f.Path, field+".") {
+				continue
+			}
+			if f.Before != "" {
+				fields[idx].Before = RedactedValue
+			}
+			if f.After != "" {
+				fields[idx].After = RedactedValue
+			}
+		}
+	}
+	return fields
+}
+
+// diffValues recursively compares two values, maps are compared by key and lists of objects that have a name
+// are compared by name so that reordering or inserting containers, ports, or volumes is reported clearly
+func diffValues(path string, before, after interface{}) []FieldDiff {
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		diffs := []FieldDiff{}
+		for _, key := range unionKeys(b, a) {
+			bv, bok := b[key]
+			av, aok := a[key]
+			p := joinPath(path, key)
+			switch {
+			case !aok:
+				diffs = append(diffs, FieldDiff{Path: p, Change: Removed, Before: encode(bv)})
+			case !bok:
+				diffs = append(diffs, FieldDiff{Path: p, Change: Added, After: encode(av)})
+			default:
+				diffs = append(diffs, diffValues(p, bv, av)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok {
+			break
+		}
+		if named(b) && named(a) {
+			return diffNamedLists(path, b, a)
+		}
+		diffs := []FieldDiff{}
+		for i := 0; i < len(b) || i < len(a); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(a):
+				diffs = append(diffs, FieldDiff{Path: p, Change: Removed, Before: encode(b[i])})
+			case i >= len(b):
+				diffs = append(diffs, FieldDiff{Path: p, Change: Added, After: encode(a[i])})
+			default:
+				diffs = append(diffs, diffValues(p, b[i], a[i])...)
+			}
+		}
+		return diffs
+	}
+	return []FieldDiff{{Path: path, Change: Changed, Before: encode(before), After: encode(after)}}
+}
+
+// diffNamedLists compares two lists of objects that all have a unique name
+func diffNamedLists(path string, before, after []interface{}) []FieldDiff {
+	b := map[string]interface{}{}
+	for _, v := range before {
+		b[v.(map[string]interface{})["name"].(string)] = v
+	}
+	a := map[string]interface{}{}
+	for _, v := range after {
+		a[v.(map[string]interface{})["name"].(string)] = v
+	}
+	diffs := []FieldDiff{}
+	for _, name := range unionKeys(b, a) {
+		bv, bok := b[name]
+		av, aok := a[name]
+		p := fmt.Sprintf("%s[name=%s]", path, name)
+		switch {
+		case !aok:
+			diffs = append(diffs, FieldDiff{Path: p, Change: Removed, Before: encode(bv)})
+		case !bok:
+			diffs = append(diffs, FieldDiff{Path: p, Change: Added, After: encode(av)})
+		default:
+			diffs = append(diffs, diffValues(p, bv, av)...)
+		}
+	}
+	return diffs
+}
+
+// named checks if every item in a list is an object with a unique name
+func named(list []interface{}) bool {
+	names := map[string]bool{}
+	for _, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		name, ok := m["name"].(string)
+		if !ok || names[name] {
+			return false
+		}
+		names[name] = true
+	}
+	return len(list) > 0
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
@@ -0,0 +1,145 @@
+package plandiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const baseYAML = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  name: nginx
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1
+        ports:
+        - containerPort: 8080
+      - name: php
+        image: php:8.1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: registry
+stringData:
+  password: secret1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: solr
+`
+
+const targetYAML = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  name: nginx
+  labels:
+    app: nginx
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: php
+        image: php:8.2
+      - name: nginx
+        image: nginx:1
+        ports:
+        - containerPort: 8080
+        - containerPort: 8443
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: registry
+stringData:
+  password: secret2
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cronjob-cli-drush-cron
+`
+
+func TestDiff(t *testing.T) {
+	base, err := ReadObjects(strings.NewReader(baseYAML), "base")
+	if err != nil {
+		t.Fatalf("ReadObjects() error = %v", err)
+	}
+	target, err := ReadObjects(strings.NewReader(targetYAML), "target")
+	if err != nil {
+		t.Fatalf("ReadObjects() error = %v", err)
+	}
+	got := Diff(base, target)
+	want := []ObjectDiff{
+		{Kind: "CronJob", Name: "cronjob-cli-drush-cron", Change: Added},
+		{
+			Kind:   "Deployment",
+			Name:   "nginx",
+			Change: Changed,
+			Fields: []FieldDiff{
+				{Path: "metadata.labels", Change: Added, After: `{"app":"nginx"}`},
+				{Path: "spec.template.spec.containers[name=nginx].ports[1]", Change: Added, After: `{"containerPort":8443}`},
+				{Path: "spec.template.spec.containers[name=php].image", Change: Changed, Before: `"php:8.1"`, After: `"php:8.2"`},
+			},
+		},
+		{
+			Kind:   "Secret",
+			Name:   "registry",
+			Change: Changed,
+			Fields: []FieldDiff{
+				{
+					Path:   "stringData.password",
+					Change: Changed,
+					Before: RedactedValue,
+					After:  RedactedValue,
+				},
+			},
+		},
+		{Kind: "Service", Name: "solr", Change: Removed},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if strings.Contains(Format(got), "secret1") {
+		t.Errorf("Format() contains a secret value")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	diffs := []ObjectDiff{
+		{Kind: "CronJob", Name: "cronjob-cli-drush-cron", Change: Added},
+		{
+			Kind:   "Deployment",
+			Name:   "nginx",
+			Change: Changed,
+			Fields: []FieldDiff{
+				{Path: "metadata.labels", Change: Removed, Before: `{"app":"nginx"}`},
+				{Path: "spec.replicas", Change: Changed, Before: `1`, After: `2`},
+			},
+		},
+		{Kind: "Service", Name: "solr", Change: Removed},
+	}
+	want := `+ CronJob/cronjob-cli-drush-cron
+~ Deployment/nginx
+    - metadata.labels: {"app":"nginx"}
+    ~ spec.replicas: 1 => 2
+- Service/solr
+`
+	if got := Format(diffs); got != want {
+		t.Errorf("Format() = %v, want %v", got, want)
+	}
+	if got := Format(nil); got != "No changes to the build plan\n" {
+		t.Errorf("Format() = %v", got)
+	}
+}
@@ -0,0 +1,302 @@
+package podsecurity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Level is a pod security standards profile
+type Level string
+
+const (
+	LevelBaseline   Level = "baseline"
+	LevelRestricted Level = "restricted"
+)
+
+// ParseLevel returns the level with the given name, `none` returns an empty level
+func ParseLevel(level string) (Level, error) {
+	switch Level(level) {
+	case LevelBaseline, LevelRestricted:
+		return Level(level), nil
+	case "none":
+		return "", nil
+	}
+	return "", fmt.Errorf("the level %s is not valid, must be one of baseline, restricted, or none", level)
+}
+
+// Fails returns true if a violation at this level fails the given level. any baseline violation also fails restricted
+func (l Level) Fails(level Level) bool {
+	switch level {
+	case LevelBaseline:
+		return l == LevelBaseline
+	case LevelRestricted:
+		return l == LevelBaseline || l == LevelRestricted
+	}
+	return false
+}
+
+// Violation is a field of a pod spec that doesn't meet a pod security standards profile
+type Violation struct {
+	// Level is the lowest profile the violation fails
+	Level Level
+	// Check is the name of the check in the pod security standards
+	Check   string
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s): %s", v.Level, v.Field, v.Check, v.Message)
+}
+
+// the capabilities that the baseline profile allows containers to add
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE":      true,
+	"CHOWN":            true,
+	"DAC_OVERRIDE":     true,
+	"FOWNER":           true,
+	"FSETID":           true,
+	"KILL":             true,
+	"MKNOD":            true,
+	"NET_BIND_SERVICE": true,
+	"SETFCAP":          true,
+	"SETGID":           true,
+	"SETPCAP":          true,
+	"SETUID":           true,
+	"SYS_CHROOT":       true,
+}
+
+// the sysctls that the baseline profile allows
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+}
+
+// the selinux types that the baseline profile allows
+var selinuxTypes = map[string]bool{
+	"":                 true,
+	"container_t":      true,
+	"container_init_t": true,
+	"container_kvm_t":  true,
+}
+
+// the prefix of the annotations that set the apparmor profile of a container
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// container is a container of a pod spec and the path to it
+type container struct {
+	corev1.Container
+	path *field.Path
+}
+
+// CheckPodTemplate evaluates a pod template against the baseline and restricted profiles of the pod security standards
+// path is the path to the pod template in the object that contains it, for example `spec.template` of a deployment
+func CheckPodTemplate(template corev1.PodTemplateSpec, path *field.Path) []Violation {
+	violations := checkAppArmor(template.ObjectMeta.Annotations, path.Child("metadata", "annotations"))
+	return append(violations, CheckPodSpec(template.Spec, path.Child("spec"))...)
+}
+
+// CheckPodSpec evaluates a pod spec against the baseline and restricted profiles of the pod security standards
+func CheckPodSpec(spec corev1.PodSpec, path *field.Path) []Violation {
+	v := &violations{}
+	containers := []container{}
+	for idx, c := range spec.InitContainers {
+		containers = append(containers, container{c, path.Child("initContainers").Index(idx)})
+	}
+	for idx, c := range spec.Containers {
+		containers = append(containers, container{c, path.Child("containers").Index(idx)})
+	}
+	for idx, c := range spec.EphemeralContainers {
+		containers = append(containers, container{corev1.Container(c.EphemeralContainerCommon), path.Child("ephemeralContainers").Index(idx)})
+	}
+	podSC := spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+	scPath := path.Child("securityContext")
+
+	// baseline
+	if spec.HostNetwork {
+		v.add(LevelBaseline, "host namespaces", path.Child("hostNetwork"), "must not be set to true")
+	}
+	if spec.HostPID {
+		v.add(LevelBaseline, "host namespaces", path.Child("hostPID"), "must not be set to true")
+	}
+	if spec.HostIPC {
+		v.add(LevelBaseline, "host namespaces", path.Child("hostIPC"), "must not be set to true")
+	}
+	if podSC.WindowsOptions != nil && podSC.WindowsOptions.HostProcess != nil && *podSC.WindowsOptions.HostProcess {
+		v.add(LevelBaseline, "host process", scPath.Child("windowsOptions", "hostProcess"), "must not be set to true")
+	}
+	checkSELinux(v, podSC.SELinuxOptions, scPath.Child("seLinuxOptions"))
+	if podSC.SeccompProfile != nil && podSC.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		v.add(LevelBaseline, "seccomp", scPath.Child("seccompProfile", "type"), "must not be set to Unconfined")
+	}
+	for idx, sysctl := range podSC.Sysctls {
+		if !safeSysctls[sysctl.Name] {
+			v.add(LevelBaseline, "sysctls", scPath.Child("sysctls").Index(idx).Child("name"), fmt.Sprintf("the sysctl %s is not allowed", sysctl.Name))
+		}
+	}
+	for idx, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			v.add(LevelBaseline, "host path volumes", path.Child("volumes").Index(idx).Child("hostPath"), fmt.Sprintf("the volume %s must not use a host path", volume.Name))
+		}
+	}
+	for _, c := range containers {
+		csPath := c.path.Child("securityContext")
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			v.add(LevelBaseline, "host process", csPath.Child("windowsOptions", "hostProcess"), "must not be set to true")
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			v.add(LevelBaseline, "privileged containers", csPath.Child("privileged"), "must not be set to true")
+		}
+		if sc.Capabilities != nil {
+			for idx, capability := range sc.Capabilities.Add {
+				if !baselineCapabilities[capability] {
+					v.add(LevelBaseline, "capabilities", csPath.Child("capabilities", "add").Index(idx), fmt.Sprintf("the capability %s must not be added", capability))
+				}
+			}
+		}
+		for idx, port := range c.Ports {
+			if port.HostPort != 0 {
+				v.add(LevelBaseline, "host ports", c.path.Child("ports").Index(idx).Child("hostPort"), "must not be set")
+			}
+		}
+		checkSELinux(v, sc.SELinuxOptions, csPath.Child("seLinuxOptions"))
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			v.add(LevelBaseline, "/proc mount type", csPath.Child("procMount"), "must be unset or set to Default")
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			v.add(LevelBaseline, "seccomp", csPath.Child("seccompProfile", "type"), "must not be set to Unconfined")
+		}
+	}
+
+	// restricted
+	for idx, volume := range spec.Volumes {
+		if volume.HostPath == nil && !restrictedVolume(volume.VolumeSource) {
+			v.add(LevelRestricted, "volume types", path.Child("volumes").Index(idx), fmt.Sprintf("the volume %s must be a configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected, or secret volume", volume.Name))
+		}
+	}
+	podNonRoot := podSC.RunAsNonRoot != nil && *podSC.RunAsNonRoot
+	if podSC.RunAsUser != nil && *podSC.RunAsUser == 0 {
+		v.add(LevelRestricted, "running as non-root user", scPath.Child("runAsUser"), "must not be set to 0")
+	}
+	podSeccomp := podSC.SeccompProfile != nil && allowedSeccomp(podSC.SeccompProfile.Type)
+	for _, c := range containers {
+		csPath := c.path.Child("securityContext")
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			v.add(LevelRestricted, "privilege escalation", csPath.Child("allowPrivilegeEscalation"), "must be set to false")
+		}
+		if sc.RunAsNonRoot != nil {
+			if !*sc.RunAsNonRoot {
+				v.add(LevelRestricted, "running as non-root", csPath.Child("runAsNonRoot"), "must not be set to false")
+			}
+		} else if !podNonRoot {
+			v.add(LevelRestricted, "running as non-root", csPath.Child("runAsNonRoot"), "must be set to true on the container or the pod")
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			v.add(LevelRestricted, "running as non-root user", csPath.Child("runAsUser"), "must not be set to 0")
+		}
+		if sc.SeccompProfile != nil {
+			if !allowedSeccomp(sc.SeccompProfile.Type) && sc.SeccompProfile.Type != corev1.SeccompProfileTypeUnconfined {
+				v.add(LevelRestricted, "seccomp", csPath.Child("seccompProfile", "type"), "must be set to RuntimeDefault or Localhost")
+			}
+		} else if !podSeccomp {
+			v.add(LevelRestricted, "seccomp", csPath.Child("seccompProfile", "type"), "must be set to RuntimeDefault or Localhost on the container or the pod")
+		}
+		dropAll := false
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Drop {
+				if capability == "ALL" {
+					dropAll = true
+				}
+			}
+			for idx, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" && baselineCapabilities[capability] {
+					v.add(LevelRestricted, "capabilities", csPath.Child("capabilities", "add").Index(idx), fmt.Sprintf("the capability %s must not be added, only NET_BIND_SERVICE is allowed", capability))
+				}
+			}
+		}
+		if !dropAll {
+			v.add(LevelRestricted, "capabilities", csPath.Child("capabilities", "drop"), "must include ALL")
+		}
+	}
+	return v.list
+}
+
+// violations collects the violations of a pod spec in the order they are found
+type violations struct {
+	list []Violation
+}
+
+func (v *violations) add(level Level, check string, path *field.Path, message string) {
+	v.list = append(v.list, Violation{
+		Level:   level,
+		Check:   check,
+		Field:   path.String(),
+		Message: message,
+	})
+}
+
+func checkSELinux(v *violations, opts *corev1.SELinuxOptions, path *field.Path) {
+	if opts == nil {
+		return
+	}
+	if !selinuxTypes[opts.Type] {
+		v.add(LevelBaseline, "selinux", path.Child("type"), fmt.Sprintf("the selinux type %s is not allowed", opts.Type))
+	}
+	if opts.User != "" {
+		v.add(LevelBaseline, "selinux", path.Child("user"), "must not be set")
+	}
+	if opts.Role != "" {
+		v.add(LevelBaseline, "selinux", path.Child("role"), "must not be set")
+	}
+}
+
+func checkAppArmor(annotations map[string]string, path *field.Path) []Violation {
+	v := &violations{}
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := annotations[key]
+		if strings.HasPrefix(key, appArmorAnnotationPrefix) && value != "runtime/default" && !strings.HasPrefix(value, "localhost/") {
+			v.add(LevelBaseline, "apparmor", path.Key(key), fmt.Sprintf("the apparmor profile %s is not allowed", value))
+		}
+	}
+	return v.list
+}
+
+func allowedSeccomp(profile corev1.SeccompProfileType) bool {
+	return profile == corev1.SeccompProfileTypeRuntimeDefault || profile == corev1.SeccompProfileTypeLocalhost
+}
+
+// restrictedVolume returns true if the volume source is one the restricted profile allows
+func restrictedVolume(source corev1.VolumeSource) bool {
+	return source.ConfigMap != nil ||
+		source.CSI != nil ||
+		source.DownwardAPI != nil ||
+		source.EmptyDir != nil ||
+		source.Ephemeral != nil ||
+		source.PersistentVolumeClaim != nil ||
+		source.Projected != nil ||
+		source.Secret != nil
+}
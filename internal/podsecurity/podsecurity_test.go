@@ -0,0 +1,186 @@
+package podsecurity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestCheckPodTemplate(t *testing.T) {
+	restricted := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: helpers.BoolPtr(false),
+		RunAsNonRoot:             helpers.BoolPtr(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+	tests := []struct {
+		name     string
+		template corev1.PodTemplateSpec
+		want     []Violation
+	}{
+		{
+			name: "test1 - restricted container",
+			template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "nginx", SecurityContext: restricted},
+					},
+					Volumes: []corev1.Volume{
+						{Name: "nginx", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "nginx"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "test2 - restricted at the pod level",
+			template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: helpers.BoolPtr(true),
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name: "nginx",
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: helpers.BoolPtr(false),
+								Capabilities: &corev1.Capabilities{
+									Add:  []corev1.Capability{"NET_BIND_SERVICE"},
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test3 - default container only fails restricted",
+			template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "nginx"},
+					},
+				},
+			},
+			want: []Violation{
+				{Level: LevelRestricted, Check: "privilege escalation", Field: "spec.template.spec.containers[0].securityContext.allowPrivilegeEscalation", Message: "must be set to false"},
+				{Level: LevelRestricted, Check: "running as non-root", Field: "spec.template.spec.containers[0].securityContext.runAsNonRoot", Message: "must be set to true on the container or the pod"},
+				{Level: LevelRestricted, Check: "seccomp", Field: "spec.template.spec.containers[0].securityContext.seccompProfile.type", Message: "must be set to RuntimeDefault or Localhost on the container or the pod"},
+				{Level: LevelRestricted, Check: "capabilities", Field: "spec.template.spec.containers[0].securityContext.capabilities.drop", Message: "must include ALL"},
+			},
+		},
+		{
+			name: "test4 - privileged root init container",
+			template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name: "set-max-map-count",
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: helpers.BoolPtr(true),
+								RunAsUser:  helpers.Int64Ptr(0),
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{Name: "opensearch", SecurityContext: restricted},
+					},
+				},
+			},
+			want: []Violation{
+				{Level: LevelBaseline, Check: "privileged containers", Field: "spec.template.spec.initContainers[0].securityContext.privileged", Message: "must not be set to true"},
+				{Level: LevelRestricted, Check: "privilege escalation", Field: "spec.template.spec.initContainers[0].securityContext.allowPrivilegeEscalation", Message: "must be set to false"},
+				{Level: LevelRestricted, Check: "running as non-root", Field: "spec.template.spec.initContainers[0].securityContext.runAsNonRoot", Message: "must be set to true on the container or the pod"},
+				{Level: LevelRestricted, Check: "running as non-root user", Field: "spec.template.spec.initContainers[0].securityContext.runAsUser", Message: "must not be set to 0"},
+				{Level: LevelRestricted, Check: "seccomp", Field: "spec.template.spec.initContainers[0].securityContext.seccompProfile.type", Message: "must be set to RuntimeDefault or Localhost on the container or the pod"},
+				{Level: LevelRestricted, Check: "capabilities", Field: "spec.template.spec.initContainers[0].securityContext.capabilities.drop", Message: "must include ALL"},
+			},
+		},
+		{
+			name: "test5 - host namespaces, host paths, host ports, capabilities, and apparmor",
+			template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"container.apparmor.security.beta.kubernetes.io/nginx": "unconfined",
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostNetwork: true,
+					Containers: []corev1.Container{
+						{
+							Name: "nginx",
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: 8080, HostPort: 80},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: helpers.BoolPtr(false),
+								RunAsNonRoot:             helpers.BoolPtr(true),
+								Capabilities: &corev1.Capabilities{
+									Add:  []corev1.Capability{"SYS_ADMIN", "CHOWN"},
+									Drop: []corev1.Capability{"ALL"},
+								},
+								SeccompProfile: &corev1.SeccompProfile{
+									Type: corev1.SeccompProfileTypeRuntimeDefault,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{Name: "docker", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}},
+						{Name: "nfs", VolumeSource: corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/"}}},
+					},
+				},
+			},
+			want: []Violation{
+				{Level: LevelBaseline, Check: "apparmor", Field: "spec.template.metadata.annotations[container.apparmor.security.beta.kubernetes.io/nginx]", Message: "the apparmor profile unconfined is not allowed"},
+				{Level: LevelBaseline, Check: "host namespaces", Field: "spec.template.spec.hostNetwork", Message: "must not be set to true"},
+				{Level: LevelBaseline, Check: "host path volumes", Field: "spec.template.spec.volumes[0].hostPath", Message: "the volume docker must not use a host path"},
+				{Level: LevelBaseline, Check: "capabilities", Field: "spec.template.spec.containers[0].securityContext.capabilities.add[0]", Message: "the capability SYS_ADMIN must not be added"},
+				{Level: LevelBaseline, Check: "host ports", Field: "spec.template.spec.containers[0].ports[0].hostPort", Message: "must not be set"},
+				{Level: LevelRestricted, Check: "volume types", Field: "spec.template.spec.volumes[1]", Message: "the volume nfs must be a configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected, or secret volume"},
+				{Level: LevelRestricted, Check: "capabilities", Field: "spec.template.spec.containers[0].securityContext.capabilities.add[1]", Message: "the capability CHOWN must not be added, only NET_BIND_SERVICE is allowed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckPodTemplate(tt.template, field.NewPath("spec", "template"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPodTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevel_Fails(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		fail  Level
+		want  bool
+	}{
+		{name: "test1 - baseline fails baseline", level: LevelBaseline, fail: LevelBaseline, want: true},
+		{name: "test2 - baseline fails restricted", level: LevelBaseline, fail: LevelRestricted, want: true},
+		{name: "test3 - restricted passes baseline", level: LevelRestricted, fail: LevelBaseline, want: false},
+		{name: "test4 - restricted fails restricted", level: LevelRestricted, fail: LevelRestricted, want: true},
+		{name: "test5 - nothing fails none", level: LevelBaseline, fail: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.Fails(tt.fail); got != tt.want {
+				t.Errorf("Level.Fails() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -69,6 +69,7 @@ var basic = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 // contains all the persistent type overrides that the basic service doesn't have
@@ -84,4 +85,6 @@ var basicPersistent = ServiceType{
 		PersistentVolumeType: corev1.ReadWriteMany,
 		Backup:               true,
 	},
+	SupportsAutogeneratedRoutes: true,
+	RequiresBackups:             true,
 }
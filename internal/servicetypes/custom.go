@@ -0,0 +1,127 @@
+package servicetypes
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// the service types that are compiled into the tool, these can't be replaced by custom service types
+var builtinServiceTypes = func() map[string]bool {
+	builtin := map[string]bool{}
+	for name := range ServiceTypes {
+		builtin[name] = true
+	}
+	return builtin
+}()
+
+// LoadCustomServiceTypes reads additional service type definitions from a yaml or json file, keyed by the name of the
+// service type, and merges them into the service types once they are validated
+func LoadCustomServiceTypes(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("couldn't read custom service types file %s: %v", file, err)
+	}
+	customTypes := map[string]ServiceType{}
+	if err := yaml.Unmarshal(data, &customTypes); err != nil {
+		return fmt.Errorf("couldn't unmarshal custom service types file %s: %v", file, err)
+	}
+	// validate them all before any are added, so a bad file doesn't leave only some of the types available
+	names := []string{}
+	for name, serviceType := range customTypes {
+		if err := ValidateCustomServiceType(name, &serviceType); err != nil {
+			return fmt.Errorf("custom service type %s in %s is not valid: %v", name, file, err)
+		}
+		customTypes[name] = serviceType
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ServiceTypes[name] = customTypes[name]
+	}
+	return nil
+}
+
+// ValidateCustomServiceType checks that a custom service type can be used to generate services, and sets any defaults
+// that the definition doesn't provide
+func ValidateCustomServiceType(name string, serviceType *ServiceType) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) != 0 {
+		return fmt.Errorf("the name is not valid: %v", errs)
+	}
+	if builtinServiceTypes[name] {
+		return fmt.Errorf("a built in service type with this name already exists")
+	}
+	if serviceType.Name == "" {
+		serviceType.Name = name
+	}
+	if serviceType.Name != name {
+		return fmt.Errorf("the name %s doesn't match the service type %s", serviceType.Name, name)
+	}
+	if serviceType.PrimaryContainer.Name == "" {
+		return fmt.Errorf("the primary container requires a name")
+	}
+
+	// the first port is the one used by any ingress, so it must be named http
+	portNames := map[string]bool{}
+	for idx, port := range serviceType.Ports.Ports {
+		if idx == 0 && port.Name != "http" {
+			return fmt.Errorf("the first port must be named http, not %s", port.Name)
+		}
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("port %s has an invalid port %d", port.Name, port.Port)
+		}
+		if port.Name == "" || portNames[port.Name] {
+			return fmt.Errorf("every port requires a unique name")
+		}
+		portNames[port.Name] = true
+		if port.Protocol == "" {
+			serviceType.Ports.Ports[idx].Protocol = corev1.ProtocolTCP
+		}
+	}
+	if serviceType.SupportsAutogeneratedRoutes && len(serviceType.Ports.Ports) == 0 {
+		return fmt.Errorf("autogenerated routes require a port named http")
+	}
+	if serviceType.TerminationGracePeriodSeconds < 0 {
+		return fmt.Errorf("the termination grace period can't be negative")
+	}
+
+	// check the volume defaults
+	volumes := &serviceType.Volumes
+	if volumes.PersistentVolumeSize != "" {
+		if _, err := resource.ParseQuantity(volumes.PersistentVolumeSize); err != nil {
+			return fmt.Errorf("the persistent volume size %s is not a valid resource quantity", volumes.PersistentVolumeSize)
+		}
+		if volumes.PersistentVolumeType == "" {
+			volumes.PersistentVolumeType = corev1.ReadWriteOnce
+		}
+	}
+	switch volumes.PersistentVolumeType {
+	case "", corev1.ReadWriteOnce, corev1.ReadWriteMany, corev1.ReadOnlyMany:
+	default:
+		return fmt.Errorf("the persistent volume type %s is not valid", volumes.PersistentVolumeType)
+	}
+	if volumes.PersistentVolumePath != "" && !path.IsAbs(volumes.PersistentVolumePath) {
+		return fmt.Errorf("the persistent volume path %s must be absolute", volumes.PersistentVolumePath)
+	}
+	if volumes.Backup && volumes.PersistentVolumeSize == "" {
+		return fmt.Errorf("the persistent volume can only be backed up if it has a size")
+	}
+	if serviceType.SupportsStatefulSet && volumes.PersistentVolumeSize == "" {
+		return fmt.Errorf("statefulsets are only supported for service types with a persistent volume")
+	}
+
+	// check the backup configuration
+	if volumes.BackupConfiguration.Command != "" && volumes.BackupConfiguration.FileExtension == "" {
+		return fmt.Errorf("the backup configuration requires a file extension for the backup command")
+	}
+	if serviceType.RequiresBackups && !volumes.Backup && volumes.BackupConfiguration.Command == "" {
+		return fmt.Errorf("backups require either the persistent volume to be backed up or a backup command")
+	}
+	return nil
+}
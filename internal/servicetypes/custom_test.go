@@ -0,0 +1,232 @@
+package servicetypes
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	// changes the testing to source from root so paths to test resources must be defined from repo root
+	_ "github.com/uselagoon/build-deploy-tool/internal/testing"
+)
+
+func TestLoadCustomServiceTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "test1 - memcached and clamav service types",
+			file: "internal/testdata/customservicetypes/service-types.yml",
+			want: []string{"clamav", "memcached"},
+		},
+		{
+			name:    "test2 - first port isn't named http",
+			file:    "internal/testdata/customservicetypes/invalid-service-types.yml",
+			wantErr: true,
+		},
+		{
+			name:    "test3 - replacing a built in service type",
+			file:    "internal/testdata/customservicetypes/builtin-service-types.yml",
+			wantErr: true,
+		},
+		{
+			name:    "test4 - missing service types file",
+			file:    "internal/testdata/customservicetypes/does-not-exist.yml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := ServiceTypes["redis"]
+			defer func() {
+				for _, name := range tt.want {
+					delete(ServiceTypes, name)
+				}
+				ServiceTypes["redis"] = redis
+			}()
+			err := LoadCustomServiceTypes(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadCustomServiceTypes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if _, ok := ServiceTypes["memcached"]; ok {
+					t.Errorf("LoadCustomServiceTypes() added an invalid service type")
+				}
+				if ServiceTypes["redis"].PrimaryContainer.Name != redis.PrimaryContainer.Name {
+					t.Errorf("LoadCustomServiceTypes() replaced a built in service type")
+				}
+				return
+			}
+			for _, name := range tt.want {
+				serviceType, ok := ServiceTypes[name]
+				if !ok {
+					t.Errorf("LoadCustomServiceTypes() didn't add service type %s", name)
+					continue
+				}
+				if serviceType.Name != name {
+					t.Errorf("LoadCustomServiceTypes() service type %s has name %v", name, serviceType.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCustomServiceType(t *testing.T) {
+	httpPort := ServicePorts{
+		Ports: []corev1.ServicePort{
+			{Name: "http", Port: 8080},
+		},
+	}
+	tests := []struct {
+		name        string
+		serviceType string
+		definition  ServiceType
+		want        ServiceType
+		wantErr     bool
+	}{
+		{
+			name:        "test1 - defaults are set",
+			serviceType: "memcached",
+			definition: ServiceType{
+				Ports: ServicePorts{
+					Ports: []corev1.ServicePort{
+						{Name: "http", Port: 8080},
+					},
+				},
+				PrimaryContainer: ServiceContainer{Name: "memcached"},
+				Volumes: ServiceVolume{
+					PersistentVolumeSize: "1Gi",
+				},
+			},
+			want: ServiceType{
+				Name: "memcached",
+				Ports: ServicePorts{
+					Ports: []corev1.ServicePort{
+						{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP},
+					},
+				},
+				PrimaryContainer: ServiceContainer{Name: "memcached"},
+				Volumes: ServiceVolume{
+					PersistentVolumeSize: "1Gi",
+					PersistentVolumeType: corev1.ReadWriteOnce,
+				},
+			},
+		},
+		{
+			name:        "test2 - invalid name",
+			serviceType: "Memcached",
+			definition: ServiceType{
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "memcached"},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test3 - name doesn't match the service type",
+			serviceType: "memcached",
+			definition: ServiceType{
+				Name:             "memcache",
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "memcached"},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test4 - missing primary container name",
+			serviceType: "memcached",
+			definition: ServiceType{
+				Ports: httpPort,
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test5 - autogenerated routes without a port",
+			serviceType: "memcached",
+			definition: ServiceType{
+				PrimaryContainer:            ServiceContainer{Name: "memcached"},
+				SupportsAutogeneratedRoutes: true,
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test6 - invalid volume size",
+			serviceType: "clamav",
+			definition: ServiceType{
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "clamav"},
+				Volumes: ServiceVolume{
+					PersistentVolumeSize: "1 gig",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test7 - invalid volume type",
+			serviceType: "clamav",
+			definition: ServiceType{
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "clamav"},
+				Volumes: ServiceVolume{
+					PersistentVolumeSize: "1Gi",
+					PersistentVolumeType: "ReadWriteSometimes",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test8 - backup command without a file extension",
+			serviceType: "clamav",
+			definition: ServiceType{
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "clamav"},
+				Volumes: ServiceVolume{
+					BackupConfiguration: BackupConfiguration{
+						Command: "tar -cf - /var/lib/clamav",
+					},
+				},
+				RequiresBackups: true,
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test9 - requires backups without anything to back up",
+			serviceType: "clamav",
+			definition: ServiceType{
+				Ports:            httpPort,
+				PrimaryContainer: ServiceContainer{Name: "clamav"},
+				RequiresBackups:  true,
+			},
+			wantErr: true,
+		},
+		{
+			name:        "test10 - negative termination grace period",
+			serviceType: "clamav",
+			definition: ServiceType{
+				Ports:                         httpPort,
+				PrimaryContainer:              ServiceContainer{Name: "clamav"},
+				TerminationGracePeriodSeconds: -1,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCustomServiceType(tt.serviceType, &tt.definition)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCustomServiceType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.definition.Name != tt.want.Name ||
+				tt.definition.Volumes != tt.want.Volumes ||
+				tt.definition.Ports.Ports[0].Protocol != tt.want.Ports.Ports[0].Protocol {
+				t.Errorf("ValidateCustomServiceType() = %v, want %v", tt.definition, tt.want)
+			}
+		})
+	}
+}
@@ -109,4 +109,6 @@ fi`,
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.tar",
 		},
 	},
+	RequiresBackups:     true,
+	SupportsStatefulSet: true,
 }
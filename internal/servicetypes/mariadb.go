@@ -87,4 +87,8 @@ var mariadbSingle = ServiceType{
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.sql",
 		},
 	},
+	RequiresBackups:     true,
+	SupportsStatefulSet: true,
+	// give the database time to flush to disk before it is killed
+	TerminationGracePeriodSeconds: 60,
 }
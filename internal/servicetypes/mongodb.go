@@ -87,4 +87,8 @@ var mongodbSingle = ServiceType{
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.tar",
 		},
 	},
+	RequiresBackups:     true,
+	SupportsStatefulSet: true,
+	// give the database time to flush to disk before it is killed
+	TerminationGracePeriodSeconds: 60,
 }
@@ -30,6 +30,7 @@ var nginx = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -72,6 +73,7 @@ var nginx = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 var nginxPHP = ServiceType{
@@ -82,6 +84,7 @@ var nginxPHP = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -135,6 +138,7 @@ var nginxPHP = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -180,6 +184,7 @@ var nginxPHP = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 var nginxPHPPersistent = ServiceType{
@@ -190,6 +195,9 @@ var nginxPHPPersistent = ServiceType{
 		PersistentVolumeType: corev1.ReadWriteMany,
 		Backup:               true,
 	},
+	// the storage permissions only need fixing once when an environment moves to rootless workloads, the restricted
+	// security profile can only be used once rootless workloads are enabled
+	RestrictedSkipInitContainers: []string{"fix-storage-permissions"},
 	InitContainer: ServiceContainer{
 		Name: "fix-storage-permissions",
 		FeatureFlags: map[string]bool{
@@ -244,4 +252,6 @@ fi`,
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
+	RequiresBackups:             true,
 }
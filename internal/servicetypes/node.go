@@ -28,6 +28,7 @@ var node = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -67,6 +68,7 @@ var node = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 var nodePersistent = ServiceType{
@@ -81,4 +83,6 @@ var nodePersistent = ServiceType{
 		PersistentVolumeType: corev1.ReadWriteMany,
 		Backup:               true,
 	},
+	SupportsAutogeneratedRoutes: true,
+	RequiresBackups:             true,
 }
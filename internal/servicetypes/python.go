@@ -28,6 +28,7 @@ var python = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -67,6 +68,7 @@ var python = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 var pythonPersistent = ServiceType{
@@ -81,4 +83,6 @@ var pythonPersistent = ServiceType{
 		PersistentVolumeType: corev1.ReadWriteMany,
 		Backup:               true,
 	},
+	SupportsAutogeneratedRoutes: true,
+	RequiresBackups:             true,
 }
@@ -103,4 +103,6 @@ var rabbitmq = ServiceType{
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.tar",
 		},
 	},
+	RequiresBackups:     true,
+	SupportsStatefulSet: true,
 }
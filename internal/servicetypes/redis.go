@@ -91,4 +91,5 @@ var redisPersistent = ServiceType{
 	Strategy: appsv1.DeploymentStrategy{
 		Type: appsv1.RecreateDeploymentStrategyType,
 	},
+	RequiresBackups: true,
 }
@@ -87,4 +87,6 @@ var solr = ServiceType{
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.tar",
 		},
 	},
+	RequiresBackups:     true,
+	SupportsStatefulSet: true,
 }
@@ -5,53 +5,83 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+// ServiceType is the definition of a lagoon service type, custom service types can be loaded from a file using the json
+// names of the fields
 type ServiceType struct {
-	Name               string
-	Ports              ServicePorts
-	Volumes            ServiceVolume
-	Strategy           appsv1.DeploymentStrategy
-	PrimaryContainer   ServiceContainer
-	InitContainer      ServiceContainer
-	SecondaryContainer ServiceContainer
-	PodSecurityContext ServicePodSecurityContext
-	EnableServiceLinks bool
+	Name               string                    `json:"name"`
+	Ports              ServicePorts              `json:"ports,omitempty"`
+	Volumes            ServiceVolume             `json:"volumes,omitempty"`
+	Strategy           appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+	PrimaryContainer   ServiceContainer          `json:"primaryContainer"`
+	InitContainer      ServiceContainer          `json:"initContainer,omitempty"`
+	SecondaryContainer ServiceContainer          `json:"secondaryContainer,omitempty"`
+	// any additional init containers or sidecars that the service type always runs
+	InitContainers     []ServiceContainer        `json:"initContainers,omitempty"`
+	Sidecars           []ServiceContainer        `json:"sidecars,omitempty"`
+	PodSecurityContext ServicePodSecurityContext `json:"podSecurityContext,omitempty"`
+	EnableServiceLinks bool                      `json:"enableServiceLinks,omitempty"`
+	// if the service type can have autogenerated routes created for it, the first port of the service type must be named `http`
+	SupportsAutogeneratedRoutes bool `json:"supportsAutogeneratedRoutes,omitempty"`
+	// if the service type has resources that require backups
+	RequiresBackups bool `json:"requiresBackups,omitempty"`
+	// if the service type can be run as a statefulset when statefulsets are enabled, the service type must have a persistent volume
+	SupportsStatefulSet bool `json:"supportsStatefulSet,omitempty"`
+	// how long the pods of the service type are given to stop before they are killed, the kubernetes default of 30 seconds is used if unset
+	TerminationGracePeriodSeconds int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// the names of the init containers of the service type that run as root or privileged, but can be left out of the pods
+	// when the restricted security profile is used. any other container that runs as root or privileged fails the build
+	RestrictedSkipInitContainers []string `json:"restrictedSkipInitContainers,omitempty"`
+	// if the primary, secondary and sidecar containers of the service type can run with a read only root filesystem when the
+	// restricted security profile is used
+	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
 }
 
 type ServicePodSecurityContext struct {
-	HasDefault bool
-	FSGroup    int64
+	HasDefault bool  `json:"hasDefault,omitempty"`
+	FSGroup    int64 `json:"fsGroup,omitempty"`
 }
 
 type ServiceContainer struct {
-	Name            string
-	ImagePullPolicy corev1.PullPolicy
-	Container       corev1.Container
+	Name            string            `json:"name"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	Container       corev1.Container  `json:"container"`
 	// define additional volumes here, can leverage 'go template' with generator.ServiceValues
-	Volumes      []corev1.Volume
-	VolumeMounts []corev1.VolumeMount
-	Command      []string
-	FeatureFlags map[string]bool
+	Volumes      []corev1.Volume      `json:"volumes,omitempty"`
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	Command      []string             `json:"command,omitempty"`
+	FeatureFlags map[string]bool      `json:"featureFlags,omitempty"`
 }
 
 type ServiceVolume struct {
-	PersistentVolumeSize   string
-	PersistentVolumePath   string
-	PersistentVolumeType   corev1.PersistentVolumeAccessMode
-	SourceFromOtherService string
-	Backup                 bool
-	BackupConfiguration    BackupConfiguration
+	PersistentVolumeSize   string                            `json:"persistentVolumeSize,omitempty"`
+	PersistentVolumePath   string                            `json:"persistentVolumePath,omitempty"`
+	PersistentVolumeType   corev1.PersistentVolumeAccessMode `json:"persistentVolumeType,omitempty"`
+	SourceFromOtherService string                            `json:"sourceFromOtherService,omitempty"`
+	Backup                 bool                              `json:"backup,omitempty"`
+	BackupConfiguration    BackupConfiguration               `json:"backupConfiguration,omitempty"`
 }
 
 type BackupConfiguration struct {
-	Command       string
-	FileExtension string
+	Command       string `json:"command,omitempty"`
+	FileExtension string `json:"fileExtension,omitempty"`
 }
 
 // when defining default ServicePorts for a service, the first port in the list should be the port that could be associated to an ingress
 // the name of this port must be `http`
 type ServicePorts struct {
-	CanChangePort bool
-	Ports         []corev1.ServicePort
+	CanChangePort bool                 `json:"canChangePort,omitempty"`
+	Ports         []corev1.ServicePort `json:"ports,omitempty"`
+}
+
+// the pre stop hook used by service types that serve requests, the pod is removed from the service endpoints when it starts
+// terminating but the ingress controllers can take a few seconds to stop sending requests to it, so the containers keep running
+// for a short time to complete any in-flight requests before they are sent the termination signal
+var defaultPreStopSleep = &corev1.Lifecycle{
+	PreStop: &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", "sleep 5"},
+		},
+	},
 }
 
 // this is a map that maps the lagoon service-type that can be provided in the `lagoon.type` label to the default values for that service
@@ -39,6 +39,7 @@ var varnish = ServiceType{
 		Container: corev1.Container{
 			ImagePullPolicy: corev1.PullAlways,
 			SecurityContext: &corev1.SecurityContext{},
+			Lifecycle:       defaultPreStopSleep,
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "http",
@@ -83,6 +84,7 @@ var varnish = ServiceType{
 			},
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
 }
 
 var varnishPersistent = ServiceType{
@@ -101,4 +103,6 @@ var varnishPersistent = ServiceType{
 			FileExtension: ".{{ .ServiceValues.OverrideName }}.tar",
 		},
 	},
+	SupportsAutogeneratedRoutes: true,
+	RequiresBackups:             true,
 }
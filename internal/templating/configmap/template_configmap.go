@@ -0,0 +1,94 @@
+package configmap
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GenerateLagoonEnvConfigMap generates the `lagoon-env` configmap from the `runtime` and `global` scoped variables
+// of the build, this includes the LAGOON_X variables that are calculated by the generator unless the project or
+// environment defines them
+func GenerateLagoonEnvConfigMap(
+	buildValues generator.BuildValues,
+) (corev1.ConfigMap, error) {
+	// add the default labels
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+		"app.kubernetes.io/instance":   "lagoon-env",
+		"app.kubernetes.io/name":       "lagoon-env",
+		"lagoon.sh/template":           "lagoon-env-0.1.0",
+		"lagoon.sh/project":            buildValues.Project,
+		"lagoon.sh/environment":        buildValues.Environment,
+		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
+		"lagoon.sh/buildType":          buildValues.BuildType,
+	}
+
+	// add the default annotations
+	annotations := map[string]string{
+		"lagoon.sh/version": buildValues.LagoonVersion,
+	}
+
+	// add any additional labels
+	if buildValues.BuildType == "branch" {
+		annotations["lagoon.sh/branch"] = buildValues.Branch
+	} else if buildValues.BuildType == "pullrequest" {
+		annotations["lagoon.sh/prNumber"] = buildValues.PRNumber
+		annotations["lagoon.sh/prHeadBranch"] = buildValues.PRHeadBranch
+		annotations["lagoon.sh/prBaseBranch"] = buildValues.PRBaseBranch
+	}
+
+	data := map[string]string{}
+	for _, envVar := range buildValues.EnvironmentVariables {
+		if helpers.Contains([]string{"runtime", "global"}, envVar.Scope) {
+			data[envVar.Name] = envVar.Value
+		}
+	}
+	// the build variables are collected before the routes are calculated by the generator
+	// so make sure the configmap gets the calculated values here
+	data["LAGOON_ROUTE"] = buildValues.Route
+	data["LAGOON_ROUTES"] = strings.Join(buildValues.Routes, ",")
+	data["LAGOON_AUTOGENERATED_ROUTES"] = strings.Join(buildValues.AutogeneratedRoutes, ",")
+	// the legacy build patched the variables of the project and environment into the configmap after the build variables,
+	// so any build variable that the project or environment defines is replaced by the defined value
+	for _, envVar := range buildValues.APIVariables {
+		if helpers.Contains([]string{"runtime", "global"}, envVar.Scope) {
+			data[envVar.Name] = envVar.Value
+		}
+	}
+	// the credentials of any dbaas consumers are only known once the operator has provisioned them
+	for _, envVar := range buildValues.DBaaSVariables {
+		data[envVar.Name] = envVar.Value
+	}
+
+	cm := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "lagoon-env",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Data: data,
+	}
+	return cm, nil
+}
+
+// GetConfigMapSha calculates the sha256 of the data in the configmap, the keys are sorted so the result is deterministic
+// this is used on the deployments to trigger a rollout if only the configmap has changed
+func GetConfigMapSha(cm corev1.ConfigMap) (string, error) {
+	// json marshalling a map sorts the keys
+	dataBytes, err := json.Marshal(cm.Data)
+	if err != nil {
+		return "", fmt.Errorf("couldn't calculate configmap sha: %v", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(dataBytes)), nil
+}
@@ -0,0 +1,188 @@
+package configmap
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateLagoonEnvConfigMap(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - branch",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					Route:           "https://example.com",
+					Routes:          []string{"https://example.com", "https://www.example.com"},
+					AutogeneratedRoutes: []string{
+						"https://nginx-example-project-environment-name.example.com",
+					},
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_PROJECT", Value: "example-project", Scope: "runtime"},
+						{Name: "LAGOON_ENVIRONMENT", Value: "environment-name", Scope: "runtime"},
+						{Name: "LAGOON_ROUTE", Value: "", Scope: "runtime"},
+						{Name: "MY_GLOBAL_VAR", Value: "global", Scope: "global"},
+						{Name: "MY_RUNTIME_VAR", Value: "runtime", Scope: "runtime"},
+						{Name: "MY_BUILD_VAR", Value: "build", Scope: "build"},
+						{Name: "REGISTRY_PASSWORD", Value: "secret", Scope: "container_registry"},
+						{Name: "LAGOON_SYSTEM_CORE_VERSION", Value: "v2.x.x", Scope: "internal_system"},
+					},
+				},
+			},
+			want: "test-resources/result-lagoon-env-1.yaml",
+		},
+		{
+			name: "test2 - pullrequest",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "pr-123",
+					EnvironmentType: "development",
+					Namespace:       "myexample-project-pr-123",
+					BuildType:       "pullrequest",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					PRNumber:        "123",
+					PRHeadBranch:    "pr-head",
+					PRBaseBranch:    "pr-base",
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_PROJECT", Value: "example-project", Scope: "runtime"},
+						{Name: "LAGOON_ENVIRONMENT", Value: "pr-123", Scope: "runtime"},
+						{Name: "LAGOON_PR_HEAD_BRANCH", Value: "pr-head", Scope: "runtime"},
+						{Name: "LAGOON_PR_BASE_BRANCH", Value: "pr-base", Scope: "runtime"},
+						{Name: "LAGOON_PR_TITLE", Value: "my pr title", Scope: "runtime"},
+						{Name: "LAGOON_PR_NUMBER", Value: "123", Scope: "runtime"},
+					},
+				},
+			},
+			want: "test-resources/result-lagoon-env-2.yaml",
+		},
+		{
+			name: "test3 - build variables defined by the project and environment",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					Route:           "https://example.com",
+					Routes:          []string{"https://example.com", "https://www.example.com"},
+					AutogeneratedRoutes: []string{
+						"https://nginx-example-project-environment-name.example.com",
+					},
+					EnvironmentVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_PROJECT", Value: "example-project", Scope: "runtime"},
+						{Name: "LAGOON_ENVIRONMENT", Value: "environment-name", Scope: "runtime"},
+						{Name: "LAGOON_ENVIRONMENT_TYPE", Value: "production", Scope: "runtime"},
+						{Name: "LAGOON_ROUTE", Value: "", Scope: "runtime"},
+						{Name: "MY_RUNTIME_VAR", Value: "runtime", Scope: "runtime"},
+					},
+					APIVariables: []lagoon.EnvironmentVariable{
+						{Name: "LAGOON_ROUTE", Value: "https://www.example.com", Scope: "runtime"},
+						{Name: "LAGOON_ENVIRONMENT_TYPE", Value: "staging", Scope: "global"},
+						{Name: "LAGOON_PROJECT", Value: "build-only", Scope: "build"},
+						{Name: "MY_RUNTIME_VAR", Value: "runtime", Scope: "runtime"},
+					},
+				},
+			},
+			want: "test-resources/result-lagoon-env-3.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateLagoonEnvConfigMap(tt.args.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateLagoonEnvConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			cmBytes, err := yaml.Marshal(got)
+			if err != nil {
+				t.Errorf("couldn't generate template  %v", err)
+			}
+			restoreResult := append(separator[:], cmBytes[:]...)
+			result = append(result, restoreResult[:]...)
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GenerateLagoonEnvConfigMap() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
+
+func TestGetConfigMapSha(t *testing.T) {
+	tests := []struct {
+		name    string
+		cm      corev1.ConfigMap
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - sha of data",
+			cm: corev1.ConfigMap{
+				Data: map[string]string{
+					"LAGOON_PROJECT":     "example-project",
+					"LAGOON_ENVIRONMENT": "main",
+				},
+			},
+			want: "1d5199def8c76ad4dc126222a3f65922b49829d6cd33805751e0e9b0b1d1c837",
+		},
+		{
+			name: "test2 - sha of data is the same regardless of key order",
+			cm: corev1.ConfigMap{
+				Data: map[string]string{
+					"LAGOON_ENVIRONMENT": "main",
+					"LAGOON_PROJECT":     "example-project",
+				},
+			},
+			want: "1d5199def8c76ad4dc126222a3f65922b49829d6cd33805751e0e9b0b1d1c837",
+		},
+		{
+			name: "test3 - empty data",
+			cm:   corev1.ConfigMap{},
+			want: "74234e98afe7498fb5daf1f36ac2d78acc339464f950703b8c019892f982b90b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetConfigMapSha(tt.cm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetConfigMapSha() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetConfigMapSha() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,213 @@
 package networkpolicy
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // GenerateNetworkPolicy generates the lagoon template to apply.
 func GenerateNetworkPolicy(
 	buildValues generator.BuildValues,
 ) (networkv1.NetworkPolicy, error) {
+	labels, annotations := policyLabels(buildValues, "isolation-network-policy", "isolation-network-policy")
+	// services with their own network policy are left out of the isolation policy, as network policies add to each other the
+	// isolation policy would otherwise allow traffic from every pod in the namespace to them
+	podSelector := metav1.LabelSelector{}
+	if buildValues.NetworkPolicies != nil && len(buildValues.NetworkPolicies.Services) > 0 {
+		podSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{
+				Key:      "lagoon.sh/service",
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   policyServices(buildValues),
+			},
+		}
+	}
+	from := []networkv1.NetworkPolicyPeer{
+		{
+			PodSelector: &metav1.LabelSelector{},
+		},
+		nonEnvironmentNamespaces(),
+	}
+	// other environments of the same project can be allowed as peers
+	if buildValues.NetworkPolicies != nil && buildValues.NetworkPolicies.Ingress != nil && len(buildValues.NetworkPolicies.Ingress.Environments) > 0 {
+		from = append(from, projectEnvironments(buildValues, buildValues.NetworkPolicies.Ingress.Environments))
+	}
+	np := networkv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "isolation-network-policy",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: networkv1.NetworkPolicySpec{
+			PodSelector: podSelector,
+			Ingress: []networkv1.NetworkPolicyIngressRule{
+				{
+					From: from,
+				},
+			},
+		},
+	}
+	return np, nil
+}
+
+// GenerateServiceNetworkPolicies generates a network policy for each service that only allows traffic from the services
+// declared in the .lagoon.yml, from the namespaces that aren't lagoon environments like the ingress controller, and from
+// the environments of the same project allowed by the ingress network policy
+func GenerateServiceNetworkPolicies(
+	buildValues generator.BuildValues,
+) ([]networkv1.NetworkPolicy, error) {
+	nps := []networkv1.NetworkPolicy{}
+	if buildValues.NetworkPolicies == nil {
+		return nps, nil
+	}
+	for _, service := range policyServices(buildValues) {
+		name := fmt.Sprintf("service-network-policy-%s", service)
+		labels, annotations := policyLabels(buildValues, name, "service-network-policy")
+		labels["lagoon.sh/service"] = service
+		from := []networkv1.NetworkPolicyPeer{}
+		if allowed := buildValues.NetworkPolicies.Services[service].From; len(allowed) > 0 {
+			from = append(from, networkv1.NetworkPolicyPeer{
+				PodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "lagoon.sh/service",
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   allowed,
+						},
+					},
+				},
+			})
+		}
+		from = append(from, nonEnvironmentNamespaces())
+		// the services with their own network policy are left out of the isolation policy, so the environments of the same
+		// project that the isolation policy allows are added here too
+		if buildValues.NetworkPolicies.Ingress != nil && len(buildValues.NetworkPolicies.Ingress.Environments) > 0 {
+			from = append(from, projectEnvironments(buildValues, buildValues.NetworkPolicies.Ingress.Environments))
+		}
+		nps = append(nps, networkv1.NetworkPolicy{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "NetworkPolicy",
+				APIVersion: "networking.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Spec: networkv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"lagoon.sh/service": service,
+					},
+				},
+				PolicyTypes: []networkv1.PolicyType{networkv1.PolicyTypeIngress},
+				Ingress: []networkv1.NetworkPolicyIngressRule{
+					{
+						From: from,
+					},
+				},
+			},
+		})
+	}
+	return nps, nil
+}
+
+// GenerateEgressNetworkPolicy generates a network policy that only allows the services of the environment to send traffic
+// to each other, to dns, and to the cidrs and environments of the same project declared in the .lagoon.yml
+func GenerateEgressNetworkPolicy(
+	buildValues generator.BuildValues,
+) (networkv1.NetworkPolicy, error) {
+	if buildValues.NetworkPolicies == nil || buildValues.NetworkPolicies.Egress == nil {
+		return networkv1.NetworkPolicy{}, fmt.Errorf("no egress network policy is declared")
+	}
+	egress := buildValues.NetworkPolicies.Egress
+	labels, annotations := policyLabels(buildValues, "egress-network-policy", "egress-network-policy")
+	rules := []networkv1.NetworkPolicyEgressRule{
+		{
+			To: []networkv1.NetworkPolicyPeer{
+				{
+					PodSelector: &metav1.LabelSelector{},
+				},
+			},
+		},
+	}
+	if egress.DNS != nil && *egress.DNS {
+		udp := corev1.ProtocolUDP
+		tcp := corev1.ProtocolTCP
+		dnsPort := intstr.FromInt(53)
+		rules = append(rules, networkv1.NetworkPolicyEgressRule{
+			To: []networkv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+				},
+			},
+			Ports: []networkv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		})
+	}
+	if len(egress.CIDRs) > 0 {
+		to := []networkv1.NetworkPolicyPeer{}
+		for _, cidr := range egress.CIDRs {
+			to = append(to, networkv1.NetworkPolicyPeer{
+				IPBlock: &networkv1.IPBlock{
+					CIDR: cidr,
+				},
+			})
+		}
+		rules = append(rules, networkv1.NetworkPolicyEgressRule{To: to})
+	}
+	if len(egress.Environments) > 0 {
+		rules = append(rules, networkv1.NetworkPolicyEgressRule{
+			To: []networkv1.NetworkPolicyPeer{
+				projectEnvironments(buildValues, egress.Environments),
+			},
+		})
+	}
+	np := networkv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "egress-network-policy",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: networkv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkv1.PolicyType{networkv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+	return np, nil
+}
+
+// policyLabels returns the default labels and annotations of a network policy
+func policyLabels(buildValues generator.BuildValues, name, policyType string) (map[string]string, map[string]string) {
 	// add the default labels
 	labels := map[string]string{
 		"app.kubernetes.io/managed-by": "build-deploy-tool",
-		"app.kubernetes.io/instance":   "isolation-network-policy",
-		"app.kubernetes.io/name":       "isolation-network-policy",
-		"lagoon.sh/template":           "isolation-network-policy-0.1.0",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/name":       policyType,
+		"lagoon.sh/template":           fmt.Sprintf("%s-0.1.0", policyType),
 		"lagoon.sh/project":            buildValues.Project,
 		"lagoon.sh/environment":        buildValues.Environment,
 		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
 		"lagoon.sh/buildType":          buildValues.BuildType,
-		"lagoon.sh/service":            "isolation-network-policy",
-		"lagoon.sh/service-type":       "isolation-network-policy",
+		"lagoon.sh/service":            name,
+		"lagoon.sh/service-type":       policyType,
 	}
 
 	// add the default annotations
@@ -37,38 +223,48 @@ func GenerateNetworkPolicy(
 		annotations["lagoon.sh/prHeadBranch"] = buildValues.PRHeadBranch
 		annotations["lagoon.sh/prBaseBranch"] = buildValues.PRBaseBranch
 	}
-	np := networkv1.NetworkPolicy{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "NetworkPolicy",
-			APIVersion: "networking.k8s.io/v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        "isolation-network-policy",
-			Labels:      labels,
-			Annotations: annotations,
+	return labels, annotations
+}
+
+// policyServices returns the sorted names of the services that have their own network policy
+func policyServices(buildValues generator.BuildValues) []string {
+	services := []string{}
+	for service := range buildValues.NetworkPolicies.Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// nonEnvironmentNamespaces selects the namespaces that aren't lagoon environments, such as the ingress controller
+func nonEnvironmentNamespaces() networkv1.NetworkPolicyPeer {
+	return networkv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{
+					Key:      "lagoon.sh/environment",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				},
+			},
 		},
-		Spec: networkv1.NetworkPolicySpec{
-			PodSelector: metav1.LabelSelector{},
-			Ingress: []networkv1.NetworkPolicyIngressRule{
+	}
+}
+
+// projectEnvironments selects the namespaces of the named environments, the project label means the environments can only
+// ever be environments of the same project
+func projectEnvironments(buildValues generator.BuildValues, environments []string) networkv1.NetworkPolicyPeer {
+	return networkv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"lagoon.sh/project": buildValues.Project,
+			},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
 				{
-					From: []networkv1.NetworkPolicyPeer{
-						{
-							PodSelector: &metav1.LabelSelector{},
-						},
-						{
-							NamespaceSelector: &metav1.LabelSelector{
-								MatchExpressions: []metav1.LabelSelectorRequirement{
-									{
-										Key:      "lagoon.sh/environment",
-										Operator: metav1.LabelSelectorOpDoesNotExist,
-									},
-								},
-							},
-						},
-					},
+					Key:      "lagoon.sh/environment",
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   environments,
 				},
 			},
 		},
 	}
-	return np, nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/andreyvit/diff"
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"sigs.k8s.io/yaml"
 )
@@ -111,6 +112,49 @@ func TestGenerateNetworkPolicy(t *testing.T) {
 		},
 		want: "test-resources/result-np-1.yaml",
 	},
+		{
+			name: "test2 - services with their own network policy",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"cli", "nginx"}},
+							"solr":    {From: []string{"nginx"}},
+						},
+					},
+				},
+			},
+			want: "test-resources/result-np-2.yaml",
+		},
+		{
+			name: "test3 - environments of the same project as peers",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"staging", "develop"},
+						},
+					},
+				},
+			},
+			want: "test-resources/result-np-3.yaml",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -137,3 +181,198 @@ func TestGenerateNetworkPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateServiceNetworkPolicies(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - service network policies",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"cli", "nginx"}},
+							"solr":    {},
+						},
+					},
+				},
+			},
+			want: "test-resources/result-service-np-1.yaml",
+		},
+		{
+			name: "test3 - service network policies with environments of the same project as peers",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Services: map[string]lagoon.ServiceNetworkPolicy{
+							"mariadb": {From: []string{"cli", "nginx"}},
+						},
+						Ingress: &lagoon.IngressNetworkPolicy{
+							Environments: []string{"staging", "develop"},
+						},
+					},
+				},
+			},
+			want: "test-resources/result-service-np-3.yaml",
+		},
+		{
+			name: "test2 - no network policies",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+				},
+			},
+			want: "test-resources/result-service-np-2.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateServiceNetworkPolicies(tt.args.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateServiceNetworkPolicies() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			for _, np := range got {
+				npBytes, err := yaml.Marshal(np)
+				if err != nil {
+					t.Errorf("couldn't generate template  %v", err)
+				}
+				restoreResult := append(separator[:], npBytes[:]...)
+				result = append(result, restoreResult[:]...)
+			}
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GenerateServiceNetworkPolicies() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
+
+func TestGenerateEgressNetworkPolicy(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - egress to cidrs, dns, and environments of the project",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Egress: &lagoon.EgressNetworkPolicy{
+							CIDRs:        []string{"10.10.0.0/16", "192.0.2.10/32"},
+							DNS:          helpers.BoolPtr(true),
+							Environments: []string{"main", "develop"},
+						},
+					},
+				},
+			},
+			want: "test-resources/result-egress-np-1.yaml",
+		},
+		{
+			name: "test2 - egress without dns",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					NetworkPolicies: &lagoon.NetworkPolicies{
+						Egress: &lagoon.EgressNetworkPolicy{
+							DNS: helpers.BoolPtr(false),
+						},
+					},
+				},
+			},
+			want: "test-resources/result-egress-np-2.yaml",
+		},
+		{
+			name: "test3 - no egress network policy",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:     "example-project",
+					Environment: "environment-name",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateEgressNetworkPolicy(tt.args.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateEgressNetworkPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			npBytes, err := yaml.Marshal(got)
+			if err != nil {
+				t.Errorf("couldn't generate template  %v", err)
+			}
+			restoreResult := append(separator[:], npBytes[:]...)
+			result = append(result, restoreResult[:]...)
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GenerateEgressNetworkPolicy() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
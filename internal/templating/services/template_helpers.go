@@ -1,8 +1,17 @@
 package services
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
 	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // LinkedServiceCalculator checks the provided services to see if there are any linked services
@@ -49,3 +58,320 @@ func LinkedServiceCalculator(services []generator.ServiceValues) []generator.Ser
 	}
 	return retServices
 }
+
+// setServiceResources sets any cpu and memory requests or limits defined for a service on the container
+// these are set after any cluster wide limits so that a service can lower them, if a limit is lower than the
+// request the servicetype defines, the request is lowered to the limit so the container can still be scheduled
+func setServiceResources(container *corev1.Container, resources *lagoon.Resources) {
+	if resources == nil {
+		return
+	}
+	for _, r := range []struct {
+		name    corev1.ResourceName
+		request string
+		limit   string
+	}{
+		{corev1.ResourceCPU, resources.Requests.CPU, resources.Limits.CPU},
+		{corev1.ResourceMemory, resources.Requests.Memory, resources.Limits.Memory},
+	} {
+		if r.request != "" {
+			if container.Resources.Requests == nil {
+				container.Resources.Requests = corev1.ResourceList{}
+			}
+			container.Resources.Requests[r.name] = resource.MustParse(r.request)
+		}
+		if r.limit != "" {
+			if container.Resources.Limits == nil {
+				container.Resources.Limits = corev1.ResourceList{}
+			}
+			limit := resource.MustParse(r.limit)
+			container.Resources.Limits[r.name] = limit
+			if request, ok := container.Resources.Requests[r.name]; ok && request.Cmp(limit) > 0 {
+				container.Resources.Requests[r.name] = limit
+			}
+		}
+	}
+}
+
+// setServiceProbes changes the default probes of the container to any defined for the service, a startup probe uses the
+// (possibly changed) readiness probe of the container as the basis for how it checks the container if no path or port is provided
+func setServiceProbes(container *corev1.Container, probes *lagoon.Probes) error {
+	if probes == nil {
+		return nil
+	}
+	for _, p := range []struct {
+		name     string
+		probe    **corev1.Probe
+		base     **corev1.Probe
+		override *lagoon.Probe
+	}{
+		{"liveness", &container.LivenessProbe, &container.LivenessProbe, probes.Liveness},
+		{"readiness", &container.ReadinessProbe, &container.ReadinessProbe, probes.Readiness},
+		{"startup", &container.StartupProbe, &container.ReadinessProbe, probes.Startup},
+	} {
+		if p.override == nil {
+			continue
+		}
+		probe := &corev1.Probe{}
+		if base := *p.base; base != nil {
+			if p.name == "startup" {
+				// the startup probe only uses how the readiness probe checks the container, not the timings
+				probe.ProbeHandler = *base.ProbeHandler.DeepCopy()
+			} else {
+				probe = base.DeepCopy()
+			}
+		}
+		port := intstr.FromInt(int(p.override.Port))
+		if p.override.Port == 0 {
+			switch {
+			case probe.HTTPGet != nil:
+				port = probe.HTTPGet.Port
+			case probe.TCPSocket != nil:
+				port = probe.TCPSocket.Port
+			}
+		}
+		switch {
+		case p.override.Path != "":
+			if port.IntVal == 0 && port.StrVal == "" {
+				return fmt.Errorf("the %s probe path %s requires a port, as the service type has no default port to use", p.name, p.override.Path)
+			}
+			probe.ProbeHandler = corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: p.override.Path,
+					Port: port,
+				},
+			}
+		case p.override.Port != 0:
+			if probe.HTTPGet != nil {
+				probe.HTTPGet.Port = port
+			} else {
+				probe.ProbeHandler = corev1.ProbeHandler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: port,
+					},
+				}
+			}
+		}
+		if probe.ProbeHandler == (corev1.ProbeHandler{}) {
+			return fmt.Errorf("the %s probe requires a path or a port, as the service type has no default %s probe to use", p.name, p.name)
+		}
+		for _, v := range []struct {
+			override int32
+			value    *int32
+		}{
+			{p.override.InitialDelaySeconds, &probe.InitialDelaySeconds},
+			{p.override.PeriodSeconds, &probe.PeriodSeconds},
+			{p.override.TimeoutSeconds, &probe.TimeoutSeconds},
+			{p.override.SuccessThreshold, &probe.SuccessThreshold},
+			{p.override.FailureThreshold, &probe.FailureThreshold},
+		} {
+			if v.override != 0 {
+				*v.value = v.override
+			}
+		}
+		*p.probe = probe
+	}
+	return nil
+}
+
+// setServiceLifecycle changes the default pre stop hook of the container to any defined for the service, the hook sleeps
+// and then runs the command if one is provided. if neither is provided the default hook of the service type is kept
+func setServiceLifecycle(container *corev1.Container, lifecycle *lagoon.Lifecycle) {
+	if lifecycle == nil || (lifecycle.PreStopSleepSeconds == nil && lifecycle.PreStopCommand == "") {
+		return
+	}
+	commands := []string{}
+	if lifecycle.PreStopSleepSeconds != nil && *lifecycle.PreStopSleepSeconds > 0 {
+		commands = append(commands, fmt.Sprintf("sleep %d", *lifecycle.PreStopSleepSeconds))
+	}
+	if lifecycle.PreStopCommand != "" {
+		commands = append(commands, lifecycle.PreStopCommand)
+	}
+	if len(commands) == 0 {
+		// a sleep of 0 without a command removes the pre stop hook
+		container.Lifecycle = nil
+		return
+	}
+	container.Lifecycle = &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", strings.Join(commands, " && ")},
+			},
+		},
+	}
+}
+
+// terminationGracePeriod returns the termination grace period for the pods of a service, the service can change the
+// grace period of the service type. nil is returned if neither set one so the kubernetes default is used
+func terminationGracePeriod(serviceType *servicetypes.ServiceType, lifecycle *lagoon.Lifecycle) *int64 {
+	if lifecycle != nil && lifecycle.TerminationGracePeriodSeconds != nil {
+		return helpers.Int64Ptr(*lifecycle.TerminationGracePeriodSeconds)
+	}
+	if serviceType.TerminationGracePeriodSeconds != 0 {
+		return helpers.Int64Ptr(serviceType.TerminationGracePeriodSeconds)
+	}
+	return nil
+}
+
+// priorityClassName returns the priority class the priority class policy assigned, or the default lagoon priority class
+// of the environment type if the policy didn't assign one
+func priorityClassName(buildValues generator.BuildValues, priorityClass string) string {
+	if priorityClass != "" {
+		return priorityClass
+	}
+	return fmt.Sprintf("lagoon-priority-%s", buildValues.EnvironmentType)
+}
+
+// setReplicaSpread spreads the replicas of a service across zones and nodes, so that a single node or zone going away doesn't
+// take every replica of the service with it. a preferred spread only influences where the scheduler places the pods, a
+// required spread won't schedule a pod onto a node that already runs more replicas of the service than other nodes.
+// pods are always only preferred to spread across zones, as not every cluster has nodes in more than one zone
+func setReplicaSpread(podSpec *corev1.PodSpec, selector *metav1.LabelSelector, spread string) {
+	if spread != generator.ReplicaSpreadPreferred && spread != generator.ReplicaSpreadRequired {
+		return
+	}
+	nodeSpread := corev1.ScheduleAnyway
+	if spread == generator.ReplicaSpreadRequired {
+		nodeSpread = corev1.DoNotSchedule
+	}
+	podSpec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     selector.DeepCopy(),
+		},
+		{
+			MaxSkew:           1,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: nodeSpread,
+			LabelSelector:     selector.DeepCopy(),
+		},
+	}
+	// the spot affinity may already be set, so only add to it
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	podSpec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector.DeepCopy(),
+					TopologyKey:   "kubernetes.io/hostname",
+				},
+			},
+		},
+	}
+}
+
+// generateAdditionalContainer generates a sidecar or init container defined for a service, the container consumes the
+// same environment as the primary container of the service
+func generateAdditionalContainer(c lagoon.Container, envFrom []corev1.EnvFromSource) corev1.Container {
+	container := corev1.Container{
+		Name:            c.Name,
+		Image:           c.Image,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         c.Command,
+		Args:            c.Args,
+		EnvFrom:         envFrom,
+		SecurityContext: &corev1.SecurityContext{},
+	}
+	for _, p := range c.Ports {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			Name:          fmt.Sprintf("tcp-%d", p),
+			ContainerPort: p,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+	for _, vm := range c.VolumeMounts {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      vm.Name,
+			MountPath: vm.Path,
+			ReadOnly:  vm.ReadOnly,
+		})
+	}
+	setServiceResources(&container, c.Resources)
+	return container
+}
+
+// checkAdditionalContainers checks that every container in the pod has a unique name, and that any volumes the
+// containers mount exist in the pod
+func checkAdditionalContainers(spec corev1.PodSpec) error {
+	volumes := map[string]bool{}
+	for _, v := range spec.Volumes {
+		volumes[v.Name] = true
+	}
+	names := map[string]bool{}
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		if names[c.Name] {
+			return fmt.Errorf("more than one container is named %s", c.Name)
+		}
+		names[c.Name] = true
+		for _, vm := range c.VolumeMounts {
+			if !volumes[vm.Name] {
+				return fmt.Errorf("container %s mounts volume %s which doesn't exist", c.Name, vm.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// setRestrictedSecurityContext hardens every container in the pod so that it passes the restricted pod security standard.
+// containers that run as root or privileged can't pass the standard, the init containers the service type allows to be
+// skipped are removed, any other returns an error. the containers the service type defines are given a read only root
+// filesystem if the service type declares it safe
+func setRestrictedSecurityContext(spec *corev1.PodSpec, serviceType *servicetypes.ServiceType) error {
+	readOnly := map[string]bool{}
+	if serviceType.ReadOnlyRootFilesystem {
+		for _, c := range append([]servicetypes.ServiceContainer{serviceType.PrimaryContainer, serviceType.SecondaryContainer}, serviceType.Sidecars...) {
+			if c.Name != "" {
+				readOnly[c.Name] = true
+			}
+		}
+	}
+	harden := func(containers []corev1.Container, skippable []string) ([]corev1.Container, error) {
+		hardened := []corev1.Container{}
+		for _, c := range containers {
+			if sc := c.SecurityContext; sc != nil &&
+				((sc.Privileged != nil && *sc.Privileged) || (sc.RunAsUser != nil && *sc.RunAsUser == 0)) {
+				if helpers.Contains(skippable, c.Name) {
+					continue
+				}
+				return nil, fmt.Errorf("the container %s runs as root or privileged, which the restricted security profile doesn't allow", c.Name)
+			}
+			if c.SecurityContext == nil {
+				c.SecurityContext = &corev1.SecurityContext{}
+			} else {
+				c.SecurityContext = c.SecurityContext.DeepCopy()
+			}
+			c.SecurityContext.AllowPrivilegeEscalation = helpers.BoolPtr(false)
+			c.SecurityContext.RunAsNonRoot = helpers.BoolPtr(true)
+			c.SecurityContext.Capabilities = &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			}
+			c.SecurityContext.SeccompProfile = &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			}
+			if readOnly[c.Name] {
+				c.SecurityContext.ReadOnlyRootFilesystem = helpers.BoolPtr(true)
+			}
+			hardened = append(hardened, c)
+		}
+		return hardened, nil
+	}
+	initContainers, err := harden(spec.InitContainers, serviceType.RestrictedSkipInitContainers)
+	if err != nil {
+		return err
+	}
+	containers, err := harden(spec.Containers, nil)
+	if err != nil {
+		return err
+	}
+	spec.InitContainers = initContainers
+	spec.Containers = containers
+	if len(spec.InitContainers) == 0 {
+		spec.InitContainers = nil
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_setRestrictedSecurityContext(t *testing.T) {
+	restricted := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: helpers.BoolPtr(false),
+		RunAsNonRoot:             helpers.BoolPtr(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+	readOnly := restricted.DeepCopy()
+	readOnly.ReadOnlyRootFilesystem = helpers.BoolPtr(true)
+	tests := []struct {
+		name        string
+		spec        corev1.PodSpec
+		serviceType servicetypes.ServiceType
+		want        corev1.PodSpec
+		wantErr     bool
+	}{
+		{
+			name: "test1 - privileged init container fails",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{
+						Name: "set-max-map-count",
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: helpers.BoolPtr(true),
+							RunAsUser:  helpers.Int64Ptr(0),
+						},
+					},
+				},
+				Containers: []corev1.Container{
+					{Name: "opensearch", SecurityContext: &corev1.SecurityContext{}},
+				},
+			},
+			serviceType: servicetypes.ServiceType{
+				PrimaryContainer: servicetypes.ServiceContainer{Name: "opensearch"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test2 - root init container the service type allows to be skipped is removed",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{
+						Name: "fix-storage-permissions",
+						SecurityContext: &corev1.SecurityContext{
+							RunAsUser: helpers.Int64Ptr(0),
+						},
+					},
+				},
+				Containers: []corev1.Container{
+					{Name: "nginx"},
+					{Name: "php"},
+				},
+			},
+			serviceType: servicetypes.ServiceType{
+				PrimaryContainer:             servicetypes.ServiceContainer{Name: "nginx"},
+				RestrictedSkipInitContainers: []string{"fix-storage-permissions"},
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "nginx", SecurityContext: restricted},
+					{Name: "php", SecurityContext: restricted},
+				},
+			},
+		},
+		{
+			name: "test3 - root container can't be skipped",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "fix-storage-permissions",
+						SecurityContext: &corev1.SecurityContext{
+							RunAsUser: helpers.Int64Ptr(0),
+						},
+					},
+				},
+			},
+			serviceType: servicetypes.ServiceType{
+				RestrictedSkipInitContainers: []string{"fix-storage-permissions"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 - read only root filesystem for the containers of the service type",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "nginx"},
+					{Name: "php"},
+					{Name: "exporter"},
+					{Name: "custom-sidecar"},
+				},
+			},
+			serviceType: servicetypes.ServiceType{
+				PrimaryContainer:       servicetypes.ServiceContainer{Name: "nginx"},
+				SecondaryContainer:     servicetypes.ServiceContainer{Name: "php"},
+				Sidecars:               []servicetypes.ServiceContainer{{Name: "exporter"}},
+				ReadOnlyRootFilesystem: true,
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "nginx", SecurityContext: readOnly},
+					{Name: "php", SecurityContext: readOnly},
+					{Name: "exporter", SecurityContext: readOnly},
+					{Name: "custom-sidecar", SecurityContext: restricted},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setRestrictedSecurityContext(&tt.spec, &tt.serviceType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setRestrictedSecurityContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(tt.spec, tt.want) {
+				t.Errorf("setRestrictedSecurityContext() = %v, want %v", tt.spec, tt.want)
+			}
+		})
+	}
+}
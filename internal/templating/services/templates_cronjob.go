@@ -203,7 +203,9 @@ func GenerateCronjobTemplate(
 				// added to the containers
 				cronjob.Spec.JobTemplate.Spec.Template.Spec.EnableServiceLinks = helpers.BoolPtr(false)
 				// set the priority class
-				cronjob.Spec.JobTemplate.Spec.Template.Spec.PriorityClassName = fmt.Sprintf("lagoon-priority-%s", buildValues.EnvironmentType)
+				cronjob.Spec.JobTemplate.Spec.Template.Spec.PriorityClassName = priorityClassName(buildValues, serviceValues.CronjobPriorityClassName)
+				// give the pods as long as the service type or service requires to stop
+				cronjob.Spec.JobTemplate.Spec.Template.Spec.TerminationGracePeriodSeconds = terminationGracePeriod(serviceTypeValues, serviceValues.Lifecycle)
 
 				// handle the podescurity from rootless workloads
 				if buildValues.PodSecurityContext.RunAsUser != 0 {
@@ -411,6 +413,12 @@ func GenerateCronjobTemplate(
 					cronjob.Spec.JobTemplate.Spec.Template.Spec.Volumes = append(cronjob.Spec.JobTemplate.Spec.Template.Spec.Volumes, volume)
 				}
 
+				if buildValues.Resources.Limits.CPU != "" {
+					if container.Container.Resources.Limits == nil {
+						container.Container.Resources.Limits = corev1.ResourceList{}
+					}
+					container.Container.Resources.Limits[corev1.ResourceCPU] = resource.MustParse(buildValues.Resources.Limits.CPU)
+				}
 				if buildValues.Resources.Limits.Memory != "" {
 					if container.Container.Resources.Limits == nil {
 						container.Container.Resources.Limits = corev1.ResourceList{}
@@ -429,16 +437,27 @@ func GenerateCronjobTemplate(
 					}
 					container.Container.Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse(buildValues.Resources.Requests.EphemeralStorage)
 				}
+				// set any resources defined for this service
+				setServiceResources(&container.Container, serviceValues.Resources)
 
 				// strip ports from the cronjobs
 				container.Container.Ports = nil
 				container.Container.ReadinessProbe = nil
 				container.Container.LivenessProbe = nil
+				// cronjobs don't serve requests, so the default pre stop hook isn't needed, but any defined for the service is used
+				container.Container.Lifecycle = nil
+				setServiceLifecycle(&container.Container, serviceValues.Lifecycle)
 
 				container.Container.Command = []string{"/lagoon/cronjob.sh", nCronjob.Command}
 
 				// append the final defined container to the spec
 				cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers = append(cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers, container.Container)
+				// harden the containers if the restricted security profile is used
+				if buildValues.SecurityProfile == generator.SecurityProfileRestricted {
+					if err := setRestrictedSecurityContext(&cronjob.Spec.JobTemplate.Spec.Template.Spec, serviceTypeValues); err != nil {
+						return nil, fmt.Errorf("the service %s can't use the %s security profile: %v", serviceValues.OverrideName, generator.SecurityProfileRestricted, err)
+					}
+				}
 
 				// end cronjob template
 				result = append(result, *cronjob)
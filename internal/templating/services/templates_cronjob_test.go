@@ -7,6 +7,7 @@ import (
 
 	"github.com/andreyvit/diff"
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"sigs.k8s.io/yaml"
 )
@@ -81,6 +82,172 @@ func TestGenerateCronjobTemplate(t *testing.T) {
 			},
 			want: "test-resources/cronjob/result-cli-1.yaml",
 		},
+		{
+			name: "test1b - cli with service resources",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"myservice": "harbor.example.com/example-project/environment-name/myservice@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "cli",
+							DBaaSEnvironment: "production",
+							Resources: &lagoon.Resources{
+								Requests: lagoon.ResourceValues{CPU: "250m", Memory: "128Mi"},
+								Limits:   lagoon.ResourceValues{Memory: "512Mi"},
+							},
+							NativeCronjobs: []lagoon.Cronjob{
+								{
+									Name:     "cronjob-myservice-my-cronjobbb",
+									Service:  "myservice",
+									Command:  "sleep 300",
+									Schedule: "5 2 * * *",
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/cronjob/result-cli-resources-1.yaml",
+		},
+		{
+			name: "test1c - cli with a longer grace period",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"myservice": "harbor.example.com/example-project/environment-name/myservice@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "cli",
+							DBaaSEnvironment: "production",
+							Lifecycle: &lagoon.Lifecycle{
+								TerminationGracePeriodSeconds: helpers.Int64Ptr(300),
+							},
+							NativeCronjobs: []lagoon.Cronjob{
+								{
+									Name:     "cronjob-myservice-my-cronjobbb",
+									Service:  "myservice",
+									Command:  "sleep 300",
+									Schedule: "5 2 * * *",
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/cronjob/result-cli-lifecycle-1.yaml",
+		},
+		{
+			name: "test1d - cli with a priority class from the policy",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"myservice": "harbor.example.com/example-project/environment-name/myservice@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:                     "myservice",
+							OverrideName:             "myservice",
+							Type:                     "cli",
+							DBaaSEnvironment:         "production",
+							PriorityClassName:        "lagoon-priority-critical",
+							CronjobPriorityClassName: "lagoon-priority-batch",
+							NativeCronjobs: []lagoon.Cronjob{
+								{
+									Name:     "cronjob-myservice-my-cronjobbb",
+									Service:  "myservice",
+									Command:  "sleep 300",
+									Schedule: "5 2 * * *",
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/cronjob/result-cli-priority-class-1.yaml",
+		},
+		{
+			name: "test1e - cli with the restricted security profile",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					FeatureFlags: map[string]bool{
+						"rootlessworkloads": true,
+					},
+					PodSecurityContext: generator.PodSecurityContext{
+						RunAsGroup: 0,
+						RunAsUser:  10000,
+						FsGroup:    10001,
+					},
+					SecurityProfile: "restricted",
+					ImageReferences: map[string]string{
+						"myservice": "harbor.example.com/example-project/environment-name/myservice@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "cli",
+							DBaaSEnvironment: "production",
+							NativeCronjobs: []lagoon.Cronjob{
+								{
+									Name:     "cronjob-myservice-my-cronjobbb",
+									Service:  "myservice",
+									Command:  "sleep 300",
+									Schedule: "5 2 * * *",
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/cronjob/result-cli-restricted-1.yaml",
+		},
 		{
 			name: "test2 - cli - security context",
 			args: args{
@@ -7,6 +7,7 @@ import (
 
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
 	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
 	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -20,6 +21,15 @@ import (
 // GenerateDeploymentTemplate generates the lagoon template to apply.
 func GenerateDeploymentTemplate(
 	buildValues generator.BuildValues,
+) ([]appsv1.Deployment, error) {
+	return generateDeployments(buildValues, false)
+}
+
+// generateDeployments generates the deployments for either the services that are statefulsets, or the services that aren't.
+// the deployments of the statefulset services are converted to statefulsets by GenerateStatefulSetTemplate
+func generateDeployments(
+	buildValues generator.BuildValues,
+	statefulSets bool,
 ) ([]appsv1.Deployment, error) {
 	var deployments []appsv1.Deployment
 
@@ -29,6 +39,9 @@ func GenerateDeploymentTemplate(
 	// for all the services that the build values generated
 	// iterate over them and generate any kubernetes deployments
 	for _, serviceValues := range checkedServices {
+		if serviceValues.StatefulSet != statefulSets {
+			continue
+		}
 		if val, ok := servicetypes.ServiceTypes[serviceValues.Type]; ok {
 			serviceTypeValues := &servicetypes.ServiceType{}
 			helpers.DeepCopy(val, serviceTypeValues)
@@ -179,9 +192,13 @@ func GenerateDeploymentTemplate(
 			for key, value := range templateAnnotations {
 				deployment.Spec.Template.ObjectMeta.Annotations[key] = value
 			}
-			deployment.Spec.Replicas = helpers.Int32Ptr(1)
-			if serviceValues.Replicas != 0 {
-				deployment.Spec.Replicas = helpers.Int32Ptr(serviceValues.Replicas)
+			// if the service is autoscaled, the replicas are owned by the horizontal pod autoscaler
+			// so they are left unset, otherwise every build would reset the replicas the autoscaler has set
+			if serviceValues.Autoscaling == nil {
+				deployment.Spec.Replicas = helpers.Int32Ptr(1)
+				if serviceValues.Replicas != 0 {
+					deployment.Spec.Replicas = helpers.Int32Ptr(serviceValues.Replicas)
+				}
 			}
 			deployment.Spec.Selector = &metav1.LabelSelector{
 				MatchLabels: map[string]string{
@@ -189,6 +206,14 @@ func GenerateDeploymentTemplate(
 					"app.kubernetes.io/instance": serviceValues.OverrideName,
 				},
 			}
+			// spread the replicas of the service across zones and nodes if it can run more than one
+			replicas := serviceValues.Replicas
+			if serviceValues.Autoscaling != nil {
+				replicas = serviceValues.Autoscaling.Max
+			}
+			if replicas > 1 {
+				setReplicaSpread(&deployment.Spec.Template.Spec, deployment.Spec.Selector, serviceValues.ReplicaSpread)
+			}
 			deployment.Spec.Strategy = serviceTypeValues.Strategy
 
 			// disable service links, this prevents some environment variables that confuse lagoon services being
@@ -198,7 +223,9 @@ func GenerateDeploymentTemplate(
 				deployment.Spec.Template.Spec.EnableServiceLinks = helpers.BoolPtr(true)
 			}
 			// set the priority class
-			deployment.Spec.Template.Spec.PriorityClassName = fmt.Sprintf("lagoon-priority-%s", buildValues.EnvironmentType)
+			deployment.Spec.Template.Spec.PriorityClassName = priorityClassName(buildValues, serviceValues.PriorityClassName)
+			// give the pods as long as the service type or service requires to stop
+			deployment.Spec.Template.Spec.TerminationGracePeriodSeconds = terminationGracePeriod(serviceTypeValues, serviceValues.Lifecycle)
 
 			// handle the podescurity from rootless workloads
 			if buildValues.PodSecurityContext.RunAsUser != 0 {
@@ -293,10 +320,13 @@ func GenerateDeploymentTemplate(
 			deployment.Spec.Template.Spec.ImagePullSecrets = pullsecrets
 
 			// start working out the containers to add
-			// add any init container that the service may have
-			if serviceTypeValues.InitContainer.Name != "" {
+			// add any init containers that the service type may have
+			initContainers := append([]servicetypes.ServiceContainer{serviceTypeValues.InitContainer}, serviceTypeValues.InitContainers...)
+			for _, init := range initContainers {
+				if init.Name == "" {
+					continue
+				}
 				enableInit := false
-				init := serviceTypeValues.InitContainer
 				// check if the init container has any flags required to add it
 				for k, v := range buildValues.FeatureFlags {
 					if init.FeatureFlags[k] == v {
@@ -306,7 +336,7 @@ func GenerateDeploymentTemplate(
 				// otherwise if there are no flags
 				if enableInit || init.FeatureFlags == nil {
 					// add any volume mounts to the init container as required
-					for _, svm := range serviceTypeValues.InitContainer.VolumeMounts {
+					for _, svm := range init.VolumeMounts {
 						volumeMount := corev1.VolumeMount{}
 						helpers.TemplateThings(tpld, svm, &volumeMount)
 						init.Container.VolumeMounts = append(init.Container.VolumeMounts, volumeMount)
@@ -354,7 +384,7 @@ func GenerateDeploymentTemplate(
 										},
 									},
 								},
-								InitialDelaySeconds: 1, // @TODO make the timeout/delays configurable?
+								InitialDelaySeconds: 1,
 								TimeoutSeconds:      1,
 							}
 							container.Container.LivenessProbe = &corev1.Probe{
@@ -366,7 +396,7 @@ func GenerateDeploymentTemplate(
 										},
 									},
 								},
-								InitialDelaySeconds: 60, // @TODO make the timeout/delays configurable?
+								InitialDelaySeconds: 60,
 								TimeoutSeconds:      10,
 							}
 						default:
@@ -393,6 +423,11 @@ func GenerateDeploymentTemplate(
 				}
 			}
 
+			// change any of the probes that the service defines, the delays and timeouts of the probes can be changed here too
+			if err := setServiceProbes(&container.Container, serviceValues.Probes); err != nil {
+				return nil, fmt.Errorf("the probes for service %s are not valid: %v", serviceValues.OverrideName, err)
+			}
+
 			// handle setting the rest of the containers specs with values from the service or build values
 			container.Container.Name = container.Name
 			if val, ok := buildValues.ImageReferences[serviceValues.Name]; ok {
@@ -487,6 +522,12 @@ func GenerateDeploymentTemplate(
 			}
 
 			// set the resource limit overrides if htey are provided
+			if buildValues.Resources.Limits.CPU != "" {
+				if container.Container.Resources.Limits == nil {
+					container.Container.Resources.Limits = corev1.ResourceList{}
+				}
+				container.Container.Resources.Limits[corev1.ResourceCPU] = resource.MustParse(buildValues.Resources.Limits.CPU)
+			}
 			if buildValues.Resources.Limits.Memory != "" {
 				if container.Container.Resources.Limits == nil {
 					container.Container.Resources.Limits = corev1.ResourceList{}
@@ -505,6 +546,10 @@ func GenerateDeploymentTemplate(
 				}
 				container.Container.Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse(buildValues.Resources.Requests.EphemeralStorage)
 			}
+			// set any resources defined for this service
+			setServiceResources(&container.Container, serviceValues.Resources)
+			// change the pre stop hook to any defined for this service
+			setServiceLifecycle(&container.Container, serviceValues.Lifecycle)
 
 			// append the final defined container to the spec
 			deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, container.Container)
@@ -576,9 +621,63 @@ func GenerateDeploymentTemplate(
 					helpers.TemplateThings(tpld, svm, &volumeMount)
 					linkedContainer.Container.VolumeMounts = append(linkedContainer.Container.VolumeMounts, volumeMount)
 				}
+				// set any resources defined for the linked service
+				setServiceResources(&linkedContainer.Container, serviceValues.LinkedService.Resources)
+				// change any of the probes that the linked service defines
+				if err := setServiceProbes(&linkedContainer.Container, serviceValues.LinkedService.Probes); err != nil {
+					return nil, fmt.Errorf("the probes for service %s are not valid: %v", serviceValues.LinkedService.Name, err)
+				}
+				// the linked container has to keep running for as long as the primary container drains requests, so it uses
+				// the pre stop sleep of the primary container unless the linked service defines its own
+				linkedLifecycle := serviceValues.LinkedService.Lifecycle
+				if linkedLifecycle == nil && serviceValues.Lifecycle != nil {
+					linkedLifecycle = &lagoon.Lifecycle{PreStopSleepSeconds: serviceValues.Lifecycle.PreStopSleepSeconds}
+				}
+				setServiceLifecycle(&linkedContainer.Container, linkedLifecycle)
 				deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, linkedContainer.Container)
 			}
 
+			// add any sidecars the service type always runs, these consume the same environment as the primary container
+			for _, sidecar := range serviceTypeValues.Sidecars {
+				for _, svm := range sidecar.VolumeMounts {
+					volumeMount := corev1.VolumeMount{}
+					helpers.TemplateThings(tpld, svm, &volumeMount)
+					sidecar.Container.VolumeMounts = append(sidecar.Container.VolumeMounts, volumeMount)
+				}
+				cmd := []string{}
+				for _, c := range sidecar.Command {
+					var c2 string
+					helpers.TemplateThings(tpld, c, &c2)
+					cmd = append(cmd, c2)
+				}
+				sidecar.Container.Command = cmd
+				sidecar.Container.Name = sidecar.Name
+				if buildValues.ImageCache != "" {
+					sidecar.Container.Image = fmt.Sprintf("%s%s", buildValues.ImageCache, sidecar.Container.Image)
+				}
+				sidecar.Container.EnvFrom = container.Container.EnvFrom
+				deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, sidecar.Container)
+			}
+			// then add any sidecars or init containers defined for the service in the .lagoon.yml file
+			// the images of these have already been worked out by the generator to use the imagecache if required
+			for _, sidecar := range serviceValues.Sidecars {
+				deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers,
+					generateAdditionalContainer(sidecar, container.Container.EnvFrom))
+			}
+			for _, init := range serviceValues.InitContainers {
+				deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers,
+					generateAdditionalContainer(init, container.Container.EnvFrom))
+			}
+			// harden the containers if the restricted security profile is used
+			if buildValues.SecurityProfile == generator.SecurityProfileRestricted {
+				if err := setRestrictedSecurityContext(&deployment.Spec.Template.Spec, serviceTypeValues); err != nil {
+					return nil, fmt.Errorf("the service %s can't use the %s security profile: %v", serviceValues.OverrideName, generator.SecurityProfileRestricted, err)
+				}
+			}
+			if err := checkAdditionalContainers(deployment.Spec.Template.Spec); err != nil {
+				return nil, fmt.Errorf("the containers for service %s are not valid: %v", serviceValues.OverrideName, err)
+			}
+
 			// end deployment template
 			deployments = append(deployments, *deployment)
 		}
@@ -9,7 +9,9 @@ import (
 	"github.com/andreyvit/diff"
 	"github.com/compose-spec/compose-go/types"
 	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
 	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
 	"sigs.k8s.io/yaml"
 )
 
@@ -18,10 +20,11 @@ func TestGenerateDeploymentTemplate(t *testing.T) {
 		buildValues generator.BuildValues
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    string
-		wantErr bool
+		name               string
+		args               args
+		customServiceTypes string
+		want               string
+		wantErr            bool
 	}{
 		{
 			name: "test1 - basic",
@@ -856,14 +859,624 @@ func TestGenerateDeploymentTemplate(t *testing.T) {
 			},
 			want: "test-resources/deployment/result-postgres-1.yaml",
 		},
+		{
+			name: "test20 - nginx-php autoscaled",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Autoscaling:      &lagoon.Autoscaling{Min: 2, Max: 5, CPU: 80},
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Autoscaling:      &lagoon.Autoscaling{Min: 2, Max: 5, CPU: 80},
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-autoscaling-1.yaml",
+		},
+		{
+			name: "test21 - nginx-php with service resources",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Resources: generator.Resources{
+						Limits: generator.ResourceLimits{
+							CPU:    "4",
+							Memory: "16Gi",
+						},
+					},
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Resources: &lagoon.Resources{
+								Requests: lagoon.ResourceValues{CPU: "100m"},
+								Limits:   lagoon.ResourceValues{Memory: "512Mi"},
+							},
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Resources: &lagoon.Resources{
+								Requests: lagoon.ResourceValues{CPU: "500m", Memory: "256Mi"},
+								Limits:   lagoon.ResourceValues{CPU: "2", Memory: "1Gi"},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-resources-1.yaml",
+		},
+		{
+			name: "test22 - node with changed probes",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"node": "harbor.example.com/example-project/environment-name/node@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+							Probes: &lagoon.Probes{
+								Liveness: &lagoon.Probe{
+									InitialDelaySeconds: 120,
+								},
+								Readiness: &lagoon.Probe{
+									Path:             "/healthz",
+									FailureThreshold: 5,
+								},
+								Startup: &lagoon.Probe{
+									PeriodSeconds:    10,
+									FailureThreshold: 30,
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-node-probes-1.yaml",
+		},
+		{
+			name: "test24 - nginx-php with sidecars and an init container",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					DynamicDBaaSSecrets: []string{
+						"mariadb-dbaas-secret",
+					},
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:                 "nginx",
+							OverrideName:         "nginx",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "production",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx",
+							PersistentVolumeSize: "10Gi",
+							Sidecars: []lagoon.Container{
+								{
+									Name:  "log-shipper",
+									Image: "imagecache.example.com/library/fluent-bit:2.2",
+									VolumeMounts: []lagoon.VolumeMount{
+										{Name: "nginx", Path: "/var/log/app", ReadOnly: true},
+									},
+								},
+								{
+									Name:  "metrics",
+									Image: "quay.io/prometheus/nginx-exporter:1.0",
+									Args:  []string{"--nginx.scrape-uri=http://localhost:8080/stub_status"},
+									Ports: []int32{9113},
+									Resources: &lagoon.Resources{
+										Requests: lagoon.ResourceValues{CPU: "10m", Memory: "32Mi"},
+										Limits:   lagoon.ResourceValues{Memory: "64Mi"},
+									},
+								},
+							},
+							InitContainers: []lagoon.Container{
+								{
+									Name:    "wait-for-db",
+									Image:   "imagecache.example.com/library/busybox:1.36",
+									Command: []string{"sh", "-c", "until nc -z mariadb 3306; do sleep 2; done"},
+								},
+							},
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php-persistent",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-sidecars-1.yaml",
+		},
+		{
+			name: "test25 - sidecar mounting a volume that doesn't exist",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					DynamicDBaaSSecrets: []string{
+						"mariadb-dbaas-secret",
+					},
+					ImageReferences: map[string]string{
+						"node": "harbor.example.com/example-project/environment-name/node@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+							Sidecars: []lagoon.Container{
+								{
+									Name:  "log-shipper",
+									Image: "fluent/fluent-bit:2.2",
+									VolumeMounts: []lagoon.VolumeMount{
+										{Name: "logs", Path: "/var/log/app"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test26 - sidecar with the same name as the primary container",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					DynamicDBaaSSecrets: []string{
+						"mariadb-dbaas-secret",
+					},
+					ImageReferences: map[string]string{
+						"node": "harbor.example.com/example-project/environment-name/node@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+							Sidecars: []lagoon.Container{
+								{
+									Name:  "node",
+									Image: "fluent/fluent-bit:2.2",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test23 - worker with a startup probe and no port",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"worker": "harbor.example.com/example-project/environment-name/worker@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "worker",
+							OverrideName:     "worker",
+							Type:             "worker",
+							DBaaSEnvironment: "production",
+							Probes: &lagoon.Probes{
+								Startup: &lagoon.Probe{
+									Path: "/healthz",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test27 - nginx-php with a longer drain and grace period",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Lifecycle: &lagoon.Lifecycle{
+								TerminationGracePeriodSeconds: helpers.Int64Ptr(60),
+								PreStopSleepSeconds:           helpers.Int64Ptr(15),
+								PreStopCommand:                "nginx -s quit",
+							},
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-lifecycle-1.yaml",
+		},
+		{
+			name: "test28 - replicated services spread across zones and nodes",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"nginx":  "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":    "harbor.example.com/example-project/environment-name/php@latest",
+						"node":   "harbor.example.com/example-project/environment-name/node@latest",
+						"worker": "harbor.example.com/example-project/environment-name/worker@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							UseSpotInstances: true,
+							Replicas:         2,
+							ReplicaSpread:    "preferred",
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							UseSpotInstances: true,
+							Replicas:         2,
+							ReplicaSpread:    "preferred",
+						},
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+							Autoscaling: &lagoon.Autoscaling{
+								Min: 2,
+								Max: 4,
+								CPU: 80,
+							},
+							ReplicaSpread: "required",
+						},
+						{
+							Name:             "worker",
+							OverrideName:     "worker",
+							Type:             "worker",
+							DBaaSEnvironment: "production",
+							ReplicaSpread:    "required", // only a single replica so there is nothing to spread
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-replica-spread-1.yaml",
+		},
+		{
+			name: "test29 - nginx-php-persistent with the restricted security profile",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					FeatureFlags: map[string]bool{
+						"rootlessworkloads": true,
+					},
+					PodSecurityContext: generator.PodSecurityContext{
+						RunAsGroup: 0,
+						RunAsUser:  10000,
+						FsGroup:    10001,
+					},
+					SecurityProfile: "restricted",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:                 "nginx",
+							OverrideName:         "nginx",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "production",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx",
+							PersistentVolumeSize: "10Gi",
+							InitContainers: []lagoon.Container{
+								{
+									Name:    "wait-for-db",
+									Image:   "imagecache.example.com/library/busybox:1.36",
+									Command: []string{"sh", "-c", "until nc -z mariadb 3306; do sleep 2; done"},
+								},
+							},
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php-persistent",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-restricted-1.yaml",
+		},
+		{
+			name: "test30 - nginx-php with changed probes on the php container",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Probes: &lagoon.Probes{
+								Liveness: &lagoon.Probe{
+									InitialDelaySeconds: 120,
+								},
+								Startup: &lagoon.Probe{
+									PeriodSeconds:    10,
+									FailureThreshold: 30,
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "test-resources/deployment/result-nginx-php-probes-1.yaml",
+		},
+		{
+			name: "test31 - opensearch can't use the restricted security profile",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					FeatureFlags: map[string]bool{
+						"rootlessworkloads": true,
+					},
+					SecurityProfile: "restricted",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"opensearch": "harbor.example.com/example-project/environment-name/opensearch@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:                 "opensearch",
+							OverrideName:         "opensearch",
+							Type:                 "opensearch",
+							DBaaSEnvironment:     "production",
+							PersistentVolumeName: "opensearch",
+							PersistentVolumeSize: "5Gi",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test32 - custom memcached service type with a read only root filesystem",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					FeatureFlags: map[string]bool{
+						"rootlessworkloads": true,
+					},
+					PodSecurityContext: generator.PodSecurityContext{
+						RunAsGroup: 0,
+						RunAsUser:  10000,
+						FsGroup:    10001,
+					},
+					SecurityProfile: "restricted",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					ImageReferences: map[string]string{
+						"memcached": "harbor.example.com/example-project/environment-name/memcached@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "memcached",
+							OverrideName:     "memcached",
+							Type:             "memcached",
+							DBaaSEnvironment: "production",
+							Sidecars: []lagoon.Container{
+								{
+									Name:  "memcached-exporter",
+									Image: "imagecache.example.com/prom/memcached-exporter:v0.14.2",
+								},
+							},
+						},
+					},
+				},
+			},
+			customServiceTypes: "../../testdata/customservicetypes/service-types.yml",
+			want:               "test-resources/deployment/result-memcached-restricted-1.yaml",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.customServiceTypes != "" {
+				builtin := map[string]servicetypes.ServiceType{}
+				for name, serviceType := range servicetypes.ServiceTypes {
+					builtin[name] = serviceType
+				}
+				defer func() {
+					servicetypes.ServiceTypes = builtin
+				}()
+				if err := servicetypes.LoadCustomServiceTypes(tt.customServiceTypes); err != nil {
+					t.Errorf("%v", err)
+				}
+			}
 			got, err := GenerateDeploymentTemplate(tt.args.buildValues)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GenerateDeploymentTemplate() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			r1, err := os.ReadFile(tt.want)
 			if err != nil {
 				t.Errorf("couldn't read file %v: %v", tt.want, err)
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metavalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+)
+
+// GenerateHPATemplate generates the horizontal pod autoscalers for any services that have autoscaling configured.
+func GenerateHPATemplate(
+	buildValues generator.BuildValues,
+) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	var result []autoscalingv2.HorizontalPodAutoscaler
+
+	// add the default labels
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+		"lagoon.sh/project":            buildValues.Project,
+		"lagoon.sh/environment":        buildValues.Environment,
+		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
+		"lagoon.sh/buildType":          buildValues.BuildType,
+	}
+
+	// add the default annotations
+	annotations := map[string]string{
+		"lagoon.sh/version": buildValues.LagoonVersion,
+	}
+
+	// add any additional labels
+	if buildValues.BuildType == "branch" {
+		annotations["lagoon.sh/branch"] = buildValues.Branch
+	} else if buildValues.BuildType == "pullrequest" {
+		annotations["lagoon.sh/prNumber"] = buildValues.PRNumber
+		annotations["lagoon.sh/prHeadBranch"] = buildValues.PRHeadBranch
+		annotations["lagoon.sh/prBaseBranch"] = buildValues.PRBaseBranch
+	}
+
+	// check linked services
+	checkedServices := LinkedServiceCalculator(buildValues.Services)
+
+	// for all the services that the build values generated
+	// iterate over them and generate an autoscaler for any deployment that is autoscaled
+	for _, serviceValues := range checkedServices {
+		if serviceValues.Autoscaling == nil {
+			continue
+		}
+		if val, ok := servicetypes.ServiceTypes[serviceValues.Type]; ok {
+			serviceType := &servicetypes.ServiceType{}
+			helpers.DeepCopy(val, serviceType)
+
+			additionalLabels := map[string]string{}
+			additionalLabels["app.kubernetes.io/name"] = serviceType.Name
+			additionalLabels["app.kubernetes.io/instance"] = serviceValues.OverrideName
+			additionalLabels["lagoon.sh/template"] = fmt.Sprintf("%s-%s", serviceType.Name, "0.1.0")
+			additionalLabels["lagoon.sh/service"] = serviceValues.OverrideName
+			additionalLabels["lagoon.sh/service-type"] = serviceType.Name
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "HorizontalPodAutoscaler",
+					APIVersion: autoscalingv2.SchemeGroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serviceValues.OverrideName,
+				},
+			}
+
+			labelsCopy := &map[string]string{}
+			helpers.DeepCopy(labels, labelsCopy)
+			annotationsCopy := &map[string]string{}
+			helpers.DeepCopy(annotations, annotationsCopy)
+
+			for key, value := range additionalLabels {
+				(*labelsCopy)[key] = value
+			}
+			hpa.ObjectMeta.Labels = *labelsCopy
+			hpa.ObjectMeta.Annotations = *annotationsCopy
+			// validate any annotations
+			if err := apivalidation.ValidateAnnotations(hpa.ObjectMeta.Annotations, nil); err != nil {
+				if len(err) != 0 {
+					return nil, fmt.Errorf("the annotations for %s are not valid: %v", serviceValues.OverrideName, err)
+				}
+			}
+			// validate any labels
+			if err := metavalidation.ValidateLabels(hpa.ObjectMeta.Labels, nil); err != nil {
+				if len(err) != 0 {
+					return nil, fmt.Errorf("the labels for %s are not valid: %v", serviceValues.OverrideName, err)
+				}
+			}
+			// check length of labels
+			err := helpers.CheckLabelLength(hpa.ObjectMeta.Labels)
+			if err != nil {
+				return nil, err
+			}
+
+			// the autoscaler targets the deployment or statefulset of the same name, and owns the replicas of it
+			scaleTargetKind := "Deployment"
+			if serviceValues.StatefulSet {
+				scaleTargetKind = "StatefulSet"
+			}
+			hpa.Spec = autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+					Kind:       scaleTargetKind,
+					Name:       serviceValues.OverrideName,
+					APIVersion: appsv1.SchemeGroupVersion.String(),
+				},
+				MinReplicas: helpers.Int32Ptr(serviceValues.Autoscaling.Min),
+				MaxReplicas: serviceValues.Autoscaling.Max,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: helpers.Int32Ptr(serviceValues.Autoscaling.CPU),
+							},
+						},
+					},
+				},
+			}
+			result = append(result, *hpa)
+		}
+	}
+	return result, nil
+}
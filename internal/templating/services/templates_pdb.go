@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	policyv1 "k8s.io/api/policy/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metavalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// GeneratePDBTemplate generates the pod disruption budgets for any services that run more than one replica.
+func GeneratePDBTemplate(
+	buildValues generator.BuildValues,
+) ([]policyv1.PodDisruptionBudget, error) {
+	var result []policyv1.PodDisruptionBudget
+
+	// add the default labels
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+		"lagoon.sh/project":            buildValues.Project,
+		"lagoon.sh/environment":        buildValues.Environment,
+		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
+		"lagoon.sh/buildType":          buildValues.BuildType,
+	}
+
+	// add the default annotations
+	annotations := map[string]string{
+		"lagoon.sh/version": buildValues.LagoonVersion,
+	}
+
+	// add any additional labels
+	if buildValues.BuildType == "branch" {
+		annotations["lagoon.sh/branch"] = buildValues.Branch
+	} else if buildValues.BuildType == "pullrequest" {
+		annotations["lagoon.sh/prNumber"] = buildValues.PRNumber
+		annotations["lagoon.sh/prHeadBranch"] = buildValues.PRHeadBranch
+		annotations["lagoon.sh/prBaseBranch"] = buildValues.PRBaseBranch
+	}
+
+	// check linked services
+	checkedServices := LinkedServiceCalculator(buildValues.Services)
+
+	// for all the services that the build values generated
+	// iterate over them and generate a disruption budget for any deployment that runs more than one replica
+	for _, serviceValues := range checkedServices {
+		replicas := serviceValues.Replicas
+		if serviceValues.Autoscaling != nil {
+			replicas = serviceValues.Autoscaling.Min
+		}
+		if replicas <= 1 {
+			continue
+		}
+		if val, ok := servicetypes.ServiceTypes[serviceValues.Type]; ok {
+			serviceType := &servicetypes.ServiceType{}
+			helpers.DeepCopy(val, serviceType)
+
+			additionalLabels := map[string]string{}
+			additionalLabels["app.kubernetes.io/name"] = serviceType.Name
+			additionalLabels["app.kubernetes.io/instance"] = serviceValues.OverrideName
+			additionalLabels["lagoon.sh/template"] = fmt.Sprintf("%s-%s", serviceType.Name, "0.1.0")
+			additionalLabels["lagoon.sh/service"] = serviceValues.OverrideName
+			additionalLabels["lagoon.sh/service-type"] = serviceType.Name
+
+			pdb := &policyv1.PodDisruptionBudget{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "PodDisruptionBudget",
+					APIVersion: policyv1.SchemeGroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serviceValues.OverrideName,
+				},
+			}
+
+			labelsCopy := &map[string]string{}
+			helpers.DeepCopy(labels, labelsCopy)
+			annotationsCopy := &map[string]string{}
+			helpers.DeepCopy(annotations, annotationsCopy)
+
+			for key, value := range additionalLabels {
+				(*labelsCopy)[key] = value
+			}
+			pdb.ObjectMeta.Labels = *labelsCopy
+			pdb.ObjectMeta.Annotations = *annotationsCopy
+			// validate any annotations
+			if err := apivalidation.ValidateAnnotations(pdb.ObjectMeta.Annotations, nil); err != nil {
+				if len(err) != 0 {
+					return nil, fmt.Errorf("the annotations for %s are not valid: %v", serviceValues.OverrideName, err)
+				}
+			}
+			// validate any labels
+			if err := metavalidation.ValidateLabels(pdb.ObjectMeta.Labels, nil); err != nil {
+				if len(err) != 0 {
+					return nil, fmt.Errorf("the labels for %s are not valid: %v", serviceValues.OverrideName, err)
+				}
+			}
+			// check length of labels
+			err := helpers.CheckLabelLength(pdb.ObjectMeta.Labels)
+			if err != nil {
+				return nil, err
+			}
+
+			// only allow one pod of the deployment to be disrupted at a time, this uses the same selector as the deployment
+			// so that a node drain can't evict all the replicas at once
+			maxUnavailable := intstr.FromInt(1)
+			pdb.Spec = policyv1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app.kubernetes.io/name":     serviceType.Name,
+						"app.kubernetes.io/instance": serviceValues.OverrideName,
+					},
+				},
+			}
+			result = append(result, *pdb)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGeneratePDBTemplate(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - nginx-php with spot replicas and basic with a single replica",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "nginx",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Replicas:         2,
+						},
+						{
+							Name:             "php",
+							OverrideName:     "nginx",
+							Type:             "nginx-php",
+							DBaaSEnvironment: "production",
+							Replicas:         2,
+						},
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "basic",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/pdb/result-nginx-1.yaml",
+		},
+		{
+			name: "test2 - pullrequest node autoscaled",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "pr-123",
+					EnvironmentType: "development",
+					Namespace:       "myexample-project-pr-123",
+					BuildType:       "pullrequest",
+					PRNumber:        "123",
+					PRHeadBranch:    "feature",
+					PRBaseBranch:    "main",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "development",
+							Autoscaling:      &lagoon.Autoscaling{Min: 3, Max: 6, CPU: 60},
+						},
+					},
+				},
+			},
+			want: "test-resources/pdb/result-node-1.yaml",
+		},
+		{
+			name: "test3 - node autoscaled with a minimum of one replica",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					Services: []generator.ServiceValues{
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+							Replicas:         2,
+							Autoscaling:      &lagoon.Autoscaling{Min: 1, Max: 3, CPU: 60},
+						},
+					},
+				},
+			},
+			want: "test-resources/pdb/result-empty.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeneratePDBTemplate(tt.args.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GeneratePDBTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			for _, d := range got {
+				pdbBytes, err := yaml.Marshal(d)
+				if err != nil {
+					t.Errorf("couldn't generate template  %v", err)
+				}
+				restoreResult := append(separator[:], pdbBytes[:]...)
+				result = append(result, restoreResult[:]...)
+			}
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GeneratePDBTemplate() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
@@ -59,99 +59,117 @@ func GeneratePVCTemplate(
 						continue
 					}
 				}
-				serviceTypeValues := &servicetypes.ServiceType{}
-				helpers.DeepCopy(val, serviceTypeValues)
-				persistentVolumeSize := serviceTypeValues.Volumes.PersistentVolumeSize
-				if serviceValues.PersistentVolumeSize != "" {
-					persistentVolumeSize = serviceValues.PersistentVolumeSize
+				// a statefulset that isn't migrating creates its volume from a volume claim template instead
+				if serviceValues.StatefulSet && !buildValues.StatefulSetMigration {
+					continue
 				}
-				serviceType := &servicetypes.ServiceType{}
-				helpers.DeepCopy(val, serviceType)
-
-				additionalLabels := map[string]string{}
-				additionalAnnotations := map[string]string{}
-
-				additionalLabels["app.kubernetes.io/name"] = serviceType.Name
-				additionalLabels["app.kubernetes.io/instance"] = serviceValues.OverrideName
-				additionalLabels["lagoon.sh/template"] = fmt.Sprintf("%s-%s", serviceType.Name, "0.1.0")
-				additionalLabels["lagoon.sh/service"] = serviceValues.OverrideName
-				additionalLabels["lagoon.sh/service-type"] = serviceType.Name
-
-				// this does both k8up v1 and v2 support
-				additionalAnnotations["k8up.syn.tools/backup"] = strconv.FormatBool(serviceTypeValues.Volumes.Backup)
-				additionalAnnotations["k8up.io/backup"] = strconv.FormatBool(serviceTypeValues.Volumes.Backup)
-
-				pvc := &corev1.PersistentVolumeClaim{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "PersistentVolumeClaim",
-						APIVersion: corev1.SchemeGroupVersion.Version,
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: serviceValues.OverrideName,
-					},
-				}
-
-				labelsCopy := &map[string]string{}
-				helpers.DeepCopy(labels, labelsCopy)
-				annotationsCopy := &map[string]string{}
-				helpers.DeepCopy(annotations, annotationsCopy)
-
-				for key, value := range additionalLabels {
-					(*labelsCopy)[key] = value
-				}
-				// add any additional annotations
-				for key, value := range additionalAnnotations {
-					(*annotationsCopy)[key] = value
-				}
-				pvc.ObjectMeta.Labels = *labelsCopy
-				pvc.ObjectMeta.Annotations = *annotationsCopy
-				// validate any annotations
-				if err := apivalidation.ValidateAnnotations(pvc.ObjectMeta.Annotations, nil); err != nil {
-					if len(err) != 0 {
-						return nil, fmt.Errorf("the annotations for %s are not valid: %v", serviceValues.OverrideName, err)
-					}
-				}
-				// validate any labels
-				if err := metavalidation.ValidateLabels(pvc.ObjectMeta.Labels, nil); err != nil {
-					if len(err) != 0 {
-						return nil, fmt.Errorf("the labels for %s are not valid: %v", serviceValues.OverrideName, err)
-					}
-				}
-				// check length of labels
-				err := helpers.CheckLabelLength(pvc.ObjectMeta.Labels)
+				pvc, err := generatePVC(buildValues, serviceValues, val, labels, annotations)
 				if err != nil {
 					return nil, err
 				}
-
-				// start PVC template
-				q, err := resource.ParseQuantity(persistentVolumeSize)
-				if err != nil {
-					return nil, fmt.Errorf("provided persistent volume size is not valid: %v", err)
-				}
-				volumeSize, _ := q.AsInt64()
-				pvc.Spec = corev1.PersistentVolumeClaimSpec{
-					AccessModes: []corev1.PersistentVolumeAccessMode{
-						serviceTypeValues.Volumes.PersistentVolumeType,
-					},
-					Resources: corev1.ResourceRequirements{
-						Requests: corev1.ResourceList{
-							"storage": *resource.NewQuantity(volumeSize, resource.BinarySI),
-						},
-					},
-				}
-				if serviceTypeValues.Volumes.PersistentVolumeType == corev1.ReadWriteMany {
-					pvc.Spec.StorageClassName = helpers.StrPtr("bulk")
-				}
-				if buildValues.RWX2RWO || buildValues.IsCI {
-					// this should be a rwo volume in CI and if the rwx2rwo flag is enabled
-					pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{
-						corev1.ReadWriteOnce,
-					}
-				}
-				// end PVC template
 				result = append(result, *pvc)
 			}
 		}
 	}
 	return result, nil
 }
+
+// generatePVC generates the persistent volume claim for a service, this is also used for the volume claim templates of statefulsets
+func generatePVC(
+	buildValues generator.BuildValues,
+	serviceValues generator.ServiceValues,
+	val servicetypes.ServiceType,
+	labels, annotations map[string]string,
+) (*corev1.PersistentVolumeClaim, error) {
+	serviceTypeValues := &servicetypes.ServiceType{}
+	helpers.DeepCopy(val, serviceTypeValues)
+	persistentVolumeSize := serviceTypeValues.Volumes.PersistentVolumeSize
+	if serviceValues.PersistentVolumeSize != "" {
+		persistentVolumeSize = serviceValues.PersistentVolumeSize
+	}
+	serviceType := &servicetypes.ServiceType{}
+	helpers.DeepCopy(val, serviceType)
+
+	additionalLabels := map[string]string{}
+	additionalAnnotations := map[string]string{}
+
+	additionalLabels["app.kubernetes.io/name"] = serviceType.Name
+	additionalLabels["app.kubernetes.io/instance"] = serviceValues.OverrideName
+	additionalLabels["lagoon.sh/template"] = fmt.Sprintf("%s-%s", serviceType.Name, "0.1.0")
+	additionalLabels["lagoon.sh/service"] = serviceValues.OverrideName
+	additionalLabels["lagoon.sh/service-type"] = serviceType.Name
+
+	// this does both k8up v1 and v2 support
+	additionalAnnotations["k8up.syn.tools/backup"] = strconv.FormatBool(serviceTypeValues.Volumes.Backup)
+	additionalAnnotations["k8up.io/backup"] = strconv.FormatBool(serviceTypeValues.Volumes.Backup)
+
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: corev1.SchemeGroupVersion.Version,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: serviceValues.OverrideName,
+		},
+	}
+
+	labelsCopy := &map[string]string{}
+	helpers.DeepCopy(labels, labelsCopy)
+	annotationsCopy := &map[string]string{}
+	helpers.DeepCopy(annotations, annotationsCopy)
+
+	for key, value := range additionalLabels {
+		(*labelsCopy)[key] = value
+	}
+	// add any additional annotations
+	for key, value := range additionalAnnotations {
+		(*annotationsCopy)[key] = value
+	}
+	pvc.ObjectMeta.Labels = *labelsCopy
+	pvc.ObjectMeta.Annotations = *annotationsCopy
+	// validate any annotations
+	if err := apivalidation.ValidateAnnotations(pvc.ObjectMeta.Annotations, nil); err != nil {
+		if len(err) != 0 {
+			return nil, fmt.Errorf("the annotations for %s are not valid: %v", serviceValues.OverrideName, err)
+		}
+	}
+	// validate any labels
+	if err := metavalidation.ValidateLabels(pvc.ObjectMeta.Labels, nil); err != nil {
+		if len(err) != 0 {
+			return nil, fmt.Errorf("the labels for %s are not valid: %v", serviceValues.OverrideName, err)
+		}
+	}
+	// check length of labels
+	err := helpers.CheckLabelLength(pvc.ObjectMeta.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// start PVC template
+	q, err := resource.ParseQuantity(persistentVolumeSize)
+	if err != nil {
+		return nil, fmt.Errorf("provided persistent volume size is not valid: %v", err)
+	}
+	volumeSize, _ := q.AsInt64()
+	pvc.Spec = corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{
+			serviceTypeValues.Volumes.PersistentVolumeType,
+		},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				"storage": *resource.NewQuantity(volumeSize, resource.BinarySI),
+			},
+		},
+	}
+	if serviceTypeValues.Volumes.PersistentVolumeType == corev1.ReadWriteMany {
+		pvc.Spec.StorageClassName = helpers.StrPtr("bulk")
+	}
+	if buildValues.RWX2RWO || buildValues.IsCI {
+		// this should be a rwo volume in CI and if the rwx2rwo flag is enabled
+		pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{
+			corev1.ReadWriteOnce,
+		}
+	}
+	// end PVC template
+	return pvc, nil
+}
@@ -213,6 +213,59 @@ func TestGeneratePVCTemplate(t *testing.T) {
 			},
 			want: "test-resources/pvc/result-basic-3.yaml",
 		},
+		{
+			name: "test8 - postgres-single statefulset",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					StatefulSets:    true,
+					Services: []generator.ServiceValues{
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "postgres-single",
+							DBaaSEnvironment: "development",
+							StatefulSet:      true,
+						},
+					},
+				},
+			},
+			want: "test-resources/pvc/result-empty.yaml",
+		},
+		{
+			name: "test9 - postgres-single statefulset migration",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:              "example-project",
+					Environment:          "environment-name",
+					EnvironmentType:      "production",
+					Namespace:            "myexample-project-environment-name",
+					BuildType:            "branch",
+					LagoonVersion:        "v2.x.x",
+					Kubernetes:           "generator.local",
+					Branch:               "environment-name",
+					StatefulSets:         true,
+					StatefulSetMigration: true,
+					Services: []generator.ServiceValues{
+						{
+							Name:             "myservice",
+							OverrideName:     "myservice",
+							Type:             "postgres-single",
+							DBaaSEnvironment: "development",
+							StatefulSet:      true,
+						},
+					},
+				},
+			},
+			want: "test-resources/pvc/result-postgres-single-1.yaml",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
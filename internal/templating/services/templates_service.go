@@ -58,6 +58,10 @@ func GenerateServiceTemplate(
 			}
 			if service != nil {
 				services = append(services, *service)
+				// statefulsets also need a headless service
+				if serviceValues.StatefulSet {
+					services = append(services, *generateHeadlessService(service, serviceValues))
+				}
 			}
 		}
 	}
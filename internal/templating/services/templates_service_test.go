@@ -244,6 +244,32 @@ func TestGenerateServiceTemplate(t *testing.T) {
 			},
 			want: "test-resources/service/result-nginx-php-1.yaml",
 		},
+		{
+			name: "test7 - mariadb-single statefulset",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					StatefulSets:    true,
+					Services: []generator.ServiceValues{
+						{
+							Name:             "mariadb",
+							OverrideName:     "mariadb",
+							Type:             "mariadb-single",
+							DBaaSEnvironment: "production",
+							StatefulSet:      true,
+						},
+					},
+				},
+			},
+			want: "test-resources/service/result-mariadb-statefulset-1.yaml",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
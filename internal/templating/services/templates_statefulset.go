@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/servicetypes"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GenerateStatefulSetTemplate generates the lagoon template to apply.
+// the statefulsets are built from the same pod template as a deployment would be for the service. when migrating, the statefulset
+// mounts the existing persistent volume claim of the service so no data is lost, otherwise the persistent volume claim is replaced
+// with a volume claim template
+func GenerateStatefulSetTemplate(
+	buildValues generator.BuildValues,
+) ([]appsv1.StatefulSet, error) {
+	var result []appsv1.StatefulSet
+
+	deployments, err := generateDeployments(buildValues, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// add the default labels
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+		"lagoon.sh/project":            buildValues.Project,
+		"lagoon.sh/environment":        buildValues.Environment,
+		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
+		"lagoon.sh/buildType":          buildValues.BuildType,
+	}
+
+	// add the default annotations
+	annotations := map[string]string{
+		"lagoon.sh/version": buildValues.LagoonVersion,
+	}
+
+	// add any additional labels
+	if buildValues.BuildType == "branch" {
+		annotations["lagoon.sh/branch"] = buildValues.Branch
+	} else if buildValues.BuildType == "pullrequest" {
+		annotations["lagoon.sh/prNumber"] = buildValues.PRNumber
+		annotations["lagoon.sh/prHeadBranch"] = buildValues.PRHeadBranch
+		annotations["lagoon.sh/prBaseBranch"] = buildValues.PRBaseBranch
+	}
+
+	// check linked services
+	checkedServices := LinkedServiceCalculator(buildValues.Services)
+
+	for _, deployment := range deployments {
+		// the deployment has the same name as the service it was generated for
+		var serviceValues generator.ServiceValues
+		for _, s := range checkedServices {
+			if s.OverrideName == deployment.Name {
+				serviceValues = s
+			}
+		}
+		val := servicetypes.ServiceTypes[serviceValues.Type]
+
+		statefulset := &appsv1.StatefulSet{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "StatefulSet",
+				APIVersion: fmt.Sprintf("%s/%s", appsv1.SchemeGroupVersion.Group, appsv1.SchemeGroupVersion.Version),
+			},
+			ObjectMeta: deployment.ObjectMeta,
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:    deployment.Spec.Replicas,
+				Selector:    deployment.Spec.Selector,
+				Template:    deployment.Spec.Template,
+				ServiceName: statefulSetServiceName(serviceValues),
+				// the service types that support statefulsets use the recreate strategy as a deployment, a rolling update
+				// of a statefulset also replaces each pod before starting the new one
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type: appsv1.RollingUpdateStatefulSetStrategyType,
+				},
+			},
+		}
+
+		if !buildValues.StatefulSetMigration {
+			// swap the volume for the persistent volume claim of the service with a volume claim template of the same name
+			volumes := []corev1.Volume{}
+			for _, volume := range statefulset.Spec.Template.Spec.Volumes {
+				if volume.PersistentVolumeClaim == nil || volume.PersistentVolumeClaim.ClaimName != serviceValues.OverrideName {
+					volumes = append(volumes, volume)
+					continue
+				}
+				pvc, err := generatePVC(buildValues, serviceValues, val, labels, annotations)
+				if err != nil {
+					return nil, err
+				}
+				// the persistent volume claims created from the template belong to the statefulset, not the build-deploy-tool,
+				// so they aren't removed by any cleanup and are retained if the statefulset is removed
+				delete(pvc.ObjectMeta.Labels, "app.kubernetes.io/managed-by")
+				statefulset.Spec.VolumeClaimTemplates = append(statefulset.Spec.VolumeClaimTemplates, corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        volume.Name,
+						Labels:      pvc.ObjectMeta.Labels,
+						Annotations: pvc.ObjectMeta.Annotations,
+					},
+					Spec: pvc.Spec,
+				})
+			}
+			statefulset.Spec.Template.Spec.Volumes = volumes
+		}
+		result = append(result, *statefulset)
+	}
+	return result, nil
+}
+
+// statefulSetServiceName is the name of the headless service that governs the network identity of the statefulset pods
+func statefulSetServiceName(serviceValues generator.ServiceValues) string {
+	return fmt.Sprintf("%s-headless", serviceValues.OverrideName)
+}
+
+// generateHeadlessService generates the headless service for a statefulset from the service generated for it
+func generateHeadlessService(service *corev1.Service, serviceValues generator.ServiceValues) *corev1.Service {
+	headless := &corev1.Service{}
+	helpers.DeepCopy(service, headless)
+	headless.ObjectMeta.Name = statefulSetServiceName(serviceValues)
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+	return headless
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateStatefulSetTemplate(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test1 - mariadb-single with a volume claim template",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "example-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					GitSHA:          "0",
+					ConfigMapSha:    "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					StatefulSets:    true,
+					ImageReferences: map[string]string{
+						"mariadb": "harbor.example.com/example-project/environment-name/mariadb@latest",
+						"node":    "harbor.example.com/example-project/environment-name/node@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "mariadb",
+							OverrideName:     "mariadb",
+							Type:             "mariadb-single",
+							DBaaSEnvironment: "production",
+							StatefulSet:      true,
+						},
+						{
+							Name:             "node",
+							OverrideName:     "node",
+							Type:             "node",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/statefulset/result-mariadb-1.yaml",
+		},
+		{
+			name: "test2 - mariadb-single migrating from a deployment",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:              "example-project",
+					Environment:          "environment-name",
+					EnvironmentType:      "production",
+					Namespace:            "example-project-environment-name",
+					BuildType:            "branch",
+					LagoonVersion:        "v2.x.x",
+					Kubernetes:           "generator.local",
+					Branch:               "environment-name",
+					GitSHA:               "0",
+					ConfigMapSha:         "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					StatefulSets:         true,
+					StatefulSetMigration: true,
+					ImageReferences: map[string]string{
+						"mariadb": "harbor.example.com/example-project/environment-name/mariadb@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "mariadb",
+							OverrideName:     "mariadb",
+							Type:             "mariadb-single",
+							DBaaSEnvironment: "production",
+							StatefulSet:      true,
+						},
+					},
+				},
+			},
+			want: "test-resources/statefulset/result-mariadb-2.yaml",
+		},
+		{
+			name: "test3 - no statefulsets",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "example-project-environment-name",
+					BuildType:       "branch",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"mariadb": "harbor.example.com/example-project/environment-name/mariadb@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "mariadb",
+							OverrideName:     "mariadb",
+							Type:             "mariadb-single",
+							DBaaSEnvironment: "production",
+						},
+					},
+				},
+			},
+			want: "test-resources/statefulset/result-empty.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateStatefulSetTemplate(tt.args.buildValues)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateStatefulSetTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			for _, d := range got {
+				stsBytes, err := yaml.Marshal(d)
+				if err != nil {
+					t.Errorf("couldn't generate template  %v", err)
+				}
+				restoreResult := append(separator[:], stsBytes[:]...)
+				result = append(result, restoreResult[:]...)
+			}
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GenerateStatefulSetTemplate() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
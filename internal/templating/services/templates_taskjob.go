@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/helpers"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// how long a finished task job is kept, so that the job and the logs of its pod can be checked after the build
+const taskJobTTLSeconds = 7 * 24 * 60 * 60
+
+// GenerateTaskJobTemplate generates a job that runs a pre or post rollout task, the pod of the job is rendered from the deployment
+// that the build templates for the service the task runs in
+func GenerateTaskJobTemplate(
+	buildValues generator.BuildValues,
+	task lagoon.Task,
+	prePost string,
+) (*batchv1.Job, error) {
+	// the statefulset services have no deployment, so they are checked before the deployments are searched
+	if _, err := taskJobService(buildValues, task); err != nil {
+		return nil, err
+	}
+	deployments, err := GenerateDeploymentTemplate(buildValues)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		if d.Labels["lagoon.sh/service"] == task.Service {
+			return GenerateTaskJobFromDeployment(buildValues, task, prePost, d)
+		}
+	}
+	return nil, &lagoon.DeploymentMissingError{ErrorText: "No deployments found matching label: lagoon.sh/service=" + task.Service}
+}
+
+// GenerateTaskJobFromDeployment generates a job that runs a pre or post rollout task from the pod template of a deployment of the
+// service the task runs in, so it uses the same image, environment, and volumes, but only runs the container the task runs in,
+// and isn't selected by the service or the pod disruption budget of the deployment
+func GenerateTaskJobFromDeployment(
+	buildValues generator.BuildValues,
+	task lagoon.Task,
+	prePost string,
+	d appsv1.Deployment,
+) (*batchv1.Job, error) {
+	serviceValues, err := taskJobService(buildValues, task)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := corev1.PodSpec{}
+	helpers.DeepCopy(d.Spec.Template.Spec, &podSpec)
+
+	// the task runs in the container named in the task, or the first container of the pod as a task run in a pod would
+	var container *corev1.Container
+	for idx := range podSpec.Containers {
+		if task.Container == "" || podSpec.Containers[idx].Name == task.Container {
+			container = &podSpec.Containers[idx]
+			break
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("the container %s of the task %s doesn't exist in service %s", task.Container, task.Name, task.Service)
+	}
+	shell := task.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	container.Command = []string{shell, "-c", task.Command}
+	container.Args = nil
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+	container.StartupProbe = nil
+	container.Lifecycle = nil
+	podSpec.Containers = []corev1.Container{*container}
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.PriorityClassName = priorityClassName(buildValues, serviceValues.TaskPriorityClassName)
+
+	name := taskJobName(buildValues, task, prePost)
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "build-deploy-tool",
+		"lagoon.sh/project":            buildValues.Project,
+		"lagoon.sh/environment":        buildValues.Environment,
+		"lagoon.sh/environmentType":    buildValues.EnvironmentType,
+		"lagoon.sh/buildType":          buildValues.BuildType,
+		"lagoon.sh/service":            d.Labels["lagoon.sh/service"],
+		"lagoon.sh/service-type":       d.Labels["lagoon.sh/service-type"],
+		"lagoon.sh/task":               strings.ToLower(prePost),
+		"lagoon.sh/taskJob":            name,
+	}
+	if buildValues.BuildName != "" {
+		labels["lagoon.sh/buildName"] = buildValues.BuildName
+	}
+	annotations := map[string]string{
+		"lagoon.sh/version":     buildValues.LagoonVersion,
+		"lagoon.sh/taskName":    task.Name,
+		"lagoon.sh/taskCommand": task.Command,
+	}
+	podLabels := map[string]string{}
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			// a task isn't retried, if the pod fails or is removed the task has failed
+			BackoffLimit:            helpers.Int32Ptr(0),
+			TTLSecondsAfterFinished: helpers.Int32Ptr(taskJobTTLSeconds),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+	return job, nil
+}
+
+// taskJobService returns the values of the service a task job runs in. the volumes of a statefulset are only available to the
+// pods of the statefulset, so the tasks of a statefulset service can't run as a job
+func taskJobService(buildValues generator.BuildValues, task lagoon.Task) (generator.ServiceValues, error) {
+	for _, s := range buildValues.Services {
+		if s.OverrideName != task.Service {
+			continue
+		}
+		if s.StatefulSet {
+			return s, fmt.Errorf("the task %s can't run as a job in service %s, it is a statefulset, use the %s mode instead", task.Name, task.Service, lagoon.TaskModeExec)
+		}
+		return s, nil
+	}
+	return generator.ServiceValues{}, nil
+}
+
+// taskJobName returns the name of the job of a task, the same task in the same build always has the same name
+func taskJobName(buildValues generator.BuildValues, task lagoon.Task, prePost string) string {
+	hash := helpers.GetBase32EncodedLowercase(helpers.GetSha256Hash(
+		fmt.Sprintf("%s-%s-%s-%s-%s-%s", buildValues.BuildName, prePost, task.Name, task.Service, task.Container, task.Command),
+	))
+	return fmt.Sprintf("%s-task-%s", strings.ToLower(prePost), hash[:10])
+}
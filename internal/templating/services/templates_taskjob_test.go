@@ -0,0 +1,264 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/uselagoon/build-deploy-tool/internal/generator"
+	"github.com/uselagoon/build-deploy-tool/internal/lagoon"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateTaskJobTemplate(t *testing.T) {
+	type args struct {
+		buildValues generator.BuildValues
+		task        lagoon.Task
+		prePost     string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        string
+		wantErr     bool
+		wantMissing bool
+	}{
+		{
+			name: "test1 - cli-persistent pre-rollout task",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "production",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					BuildName:       "lagoon-build-abcdef",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"cli":   "harbor.example.com/example-project/environment-name/cli@latest",
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:                  "cli",
+							OverrideName:          "cli",
+							Type:                  "cli-persistent",
+							DBaaSEnvironment:      "production",
+							PersistentVolumePath:  "/app/docroot/sites/default/files/",
+							PersistentVolumeName:  "nginx-php",
+							TaskPriorityClassName: "lagoon-priority-batch",
+						},
+						{
+							Name:                 "nginx",
+							OverrideName:         "nginx-php",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "production",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx-php",
+							PersistentVolumeSize: "5Gi",
+						},
+						{
+							Name:                 "php",
+							OverrideName:         "nginx-php",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "production",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx-php",
+							PersistentVolumeSize: "5Gi",
+						},
+					},
+				},
+				task: lagoon.Task{
+					Name:    "drush deploy",
+					Command: "drush -y deploy",
+					Service: "cli",
+					Shell:   "bash",
+					Mode:    lagoon.TaskModeJob,
+				},
+				prePost: "Pre-Rollout",
+			},
+			want: "test-resources/taskjob/result-cli-1.yaml",
+		},
+		{
+			name: "test2 - nginx-php post-rollout task in the php container",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "development",
+					Namespace:       "myexample-project-environment-name",
+					BuildType:       "branch",
+					BuildName:       "lagoon-build-abcdef",
+					LagoonVersion:   "v2.x.x",
+					Kubernetes:      "generator.local",
+					Branch:          "environment-name",
+					ImageReferences: map[string]string{
+						"nginx": "harbor.example.com/example-project/environment-name/nginx@latest",
+						"php":   "harbor.example.com/example-project/environment-name/php@latest",
+					},
+					GitSHA:       "0",
+					ConfigMapSha: "32bf1359ac92178c8909f0ef938257b477708aa0d78a5a15ad7c2d7919adf273",
+					Services: []generator.ServiceValues{
+						{
+							Name:                 "nginx",
+							OverrideName:         "nginx-php",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "development",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx-php",
+							PersistentVolumeSize: "5Gi",
+						},
+						{
+							Name:                 "php",
+							OverrideName:         "nginx-php",
+							Type:                 "nginx-php-persistent",
+							DBaaSEnvironment:     "development",
+							PersistentVolumePath: "/app/docroot/sites/default/files/",
+							PersistentVolumeName: "nginx-php",
+							PersistentVolumeSize: "5Gi",
+						},
+					},
+				},
+				task: lagoon.Task{
+					Name:      "clear cache",
+					Command:   "php artisan cache:clear",
+					Service:   "nginx-php",
+					Container: "php",
+					Mode:      lagoon.TaskModeJob,
+				},
+				prePost: "Post-Rollout",
+			},
+			want: "test-resources/taskjob/result-nginx-php-1.yaml",
+		},
+		{
+			name: "test3 - container that doesn't exist",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "development",
+					BuildType:       "branch",
+					ImageReferences: map[string]string{
+						"cli": "harbor.example.com/example-project/environment-name/cli@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "cli",
+							OverrideName:     "cli",
+							Type:             "cli",
+							DBaaSEnvironment: "development",
+						},
+					},
+				},
+				task: lagoon.Task{
+					Name:      "drush deploy",
+					Command:   "drush -y deploy",
+					Service:   "cli",
+					Container: "php",
+					Mode:      lagoon.TaskModeJob,
+				},
+				prePost: "Post-Rollout",
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 - service that doesn't exist",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "development",
+					BuildType:       "branch",
+					ImageReferences: map[string]string{
+						"cli": "harbor.example.com/example-project/environment-name/cli@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "cli",
+							OverrideName:     "cli",
+							Type:             "cli",
+							DBaaSEnvironment: "development",
+						},
+					},
+				},
+				task: lagoon.Task{
+					Name:    "drush deploy",
+					Command: "drush -y deploy",
+					Service: "node",
+					Mode:    lagoon.TaskModeJob,
+				},
+				prePost: "Pre-Rollout",
+			},
+			wantErr:     true,
+			wantMissing: true,
+		},
+		{
+			name: "test5 - statefulset service",
+			args: args{
+				buildValues: generator.BuildValues{
+					Project:         "example-project",
+					Environment:     "environment-name",
+					EnvironmentType: "development",
+					BuildType:       "branch",
+					ImageReferences: map[string]string{
+						"mariadb": "harbor.example.com/example-project/environment-name/mariadb@latest",
+					},
+					Services: []generator.ServiceValues{
+						{
+							Name:             "mariadb",
+							OverrideName:     "mariadb",
+							Type:             "mariadb-single",
+							DBaaSEnvironment: "development",
+							StatefulSet:      true,
+						},
+					},
+				},
+				task: lagoon.Task{
+					Name:    "mysql check",
+					Command: "mysqladmin ping",
+					Service: "mariadb",
+					Mode:    lagoon.TaskModeJob,
+				},
+				prePost: "Post-Rollout",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateTaskJobTemplate(tt.args.buildValues, tt.args.task, tt.args.prePost)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateTaskJobTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if _, ok := err.(*lagoon.DeploymentMissingError); ok != tt.wantMissing {
+				t.Errorf("GenerateTaskJobTemplate() error = %v, wantMissing %v", err, tt.wantMissing)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			r1, err := os.ReadFile(tt.want)
+			if err != nil {
+				t.Errorf("couldn't read file %v: %v", tt.want, err)
+			}
+			separator := []byte("---\n")
+			var result []byte
+			jobBytes, err := yaml.Marshal(got)
+			if err != nil {
+				t.Errorf("couldn't generate template  %v", err)
+			}
+			restoreResult := append(separator[:], jobBytes[:]...)
+			result = append(result, restoreResult[:]...)
+			if !reflect.DeepEqual(string(result), string(r1)) {
+				t.Errorf("GenerateTaskJobTemplate() = \n%v", diff.LineDiff(string(r1), string(result)))
+			}
+		})
+	}
+}
@@ -733,27 +733,8 @@ if [ "${SCC_CHECK}" != "false" ]; then
   yq3 write -i -- /kubectl-build-deploy/values.yaml 'podSecurityContext.fsGroup' $OPENSHIFT_SUPPLEMENTAL_GROUP
 fi
 
-echo -e "\
-LAGOON_PROJECT=${PROJECT}\n\
-LAGOON_ENVIRONMENT=${ENVIRONMENT}\n\
-LAGOON_ENVIRONMENT_TYPE=${ENVIRONMENT_TYPE}\n\
-LAGOON_GIT_SHA=${LAGOON_GIT_SHA}\n\
-LAGOON_KUBERNETES=${KUBERNETES}\n\
-" >> /kubectl-build-deploy/values.env
-
-# DEPRECATED: will be removed with Lagoon 3.0.0
-# LAGOON_GIT_SAFE_BRANCH is pointing to the enviornment name, therefore also is filled if this environment
-# is created by a PR or Promote workflow. This technically wrong, therefore will be removed
-echo -e "\
-LAGOON_GIT_SAFE_BRANCH=${ENVIRONMENT}\n\
-" >> /kubectl-build-deploy/values.env
-
 if [ "$BUILD_TYPE" == "branch" ]; then
   yq3 write -i -- /kubectl-build-deploy/values.yaml 'branch' $BRANCH
-
-  echo -e "\
-LAGOON_GIT_BRANCH=${BRANCH}\n\
-" >> /kubectl-build-deploy/values.env
 fi
 
 if [ "$BUILD_TYPE" == "pullrequest" ]; then
@@ -761,13 +742,6 @@ if [ "$BUILD_TYPE" == "pullrequest" ]; then
   yq3 write -i -- /kubectl-build-deploy/values.yaml 'prBaseBranch' "$PR_BASE_BRANCH"
   yq3 write -i -- /kubectl-build-deploy/values.yaml 'prTitle' "$PR_TITLE"
   yq3 write -i -- /kubectl-build-deploy/values.yaml 'prNumber' "$PR_NUMBER"
-
-  echo -e "\
-LAGOON_PR_HEAD_BRANCH=${PR_HEAD_BRANCH}\n\
-LAGOON_PR_BASE_BRANCH=${PR_BASE_BRANCH}\n\
-LAGOON_PR_TITLE=${PR_TITLE}\n\
-LAGOON_PR_NUMBER=${PR_NUMBER}\n\
-" >> /kubectl-build-deploy/values.env
 fi
 
 currentStepEnd="$(date +"%Y-%m-%d %H:%M:%S")"
@@ -916,6 +890,11 @@ if [ ! -z "$LAGOON_ENVIRONMENT_VARIABLES" ]; then
   fi
 fi
 
+# the templates are still generated one family at a time rather than with `build-deploy-tool template all`, each family is
+# applied at a different step of the build: the ingress is applied before the routes are checked, the dbaas consumers
+# must be provisioned before lagoon-env can include their credentials, and the services need the images that are only
+# pushed later in the build. switching to `template all` requires those steps to be reordered first
+
 # generate the autogenerated ingress
 if [ ! "$AUTOGEN_ROUTES_DISABLED" == true ]; then
   LAGOON_AUTOGEN_YAML_FOLDER="/kubectl-build-deploy/lagoon/autogen-routes"
@@ -1191,65 +1170,22 @@ yq3 write -i -- /kubectl-build-deploy/values.yaml 'autogeneratedRoutes' "$AUTOGE
 # Add in Lagoon core api and ssh-portal details, if available
 if [ ! -z "$LAGOON_CONFIG_API_HOST" ]; then
   BUILD_ARGS+=(--build-arg LAGOON_CONFIG_API_HOST="${LAGOON_CONFIG_API_HOST}")
-  echo -e "LAGOON_CONFIG_API_HOST=${LAGOON_CONFIG_API_HOST}\n" >> /kubectl-build-deploy/values.env
 fi
 
 if [ ! -z "$LAGOON_CONFIG_TOKEN_HOST" ]; then
   BUILD_ARGS+=(--build-arg LAGOON_CONFIG_TOKEN_HOST="${LAGOON_CONFIG_TOKEN_HOST}")
-  echo -e "LAGOON_CONFIG_TOKEN_HOST=${LAGOON_CONFIG_TOKEN_HOST}\n" >> /kubectl-build-deploy/values.env
 fi
 
 if [ ! -z "$LAGOON_CONFIG_TOKEN_PORT" ]; then
   BUILD_ARGS+=(--build-arg LAGOON_CONFIG_TOKEN_PORT="${LAGOON_CONFIG_TOKEN_PORT}")
-  echo -e "LAGOON_CONFIG_TOKEN_PORT=${LAGOON_CONFIG_TOKEN_PORT}\n" >> /kubectl-build-deploy/values.env
 fi
 
 if [ ! -z "$LAGOON_CONFIG_SSH_HOST" ]; then
   BUILD_ARGS+=(--build-arg LAGOON_CONFIG_SSH_HOST="${LAGOON_CONFIG_SSH_HOST}")
-  echo -e "LAGOON_CONFIG_SSH_HOST=${LAGOON_CONFIG_SSH_HOST}\n" >> /kubectl-build-deploy/values.env
 fi
 
 if [ ! -z "$LAGOON_CONFIG_SSH_PORT" ]; then
   BUILD_ARGS+=(--build-arg LAGOON_CONFIG_SSH_PORT="${LAGOON_CONFIG_SSH_PORT}")
-  echo -e "LAGOON_CONFIG_SSH_PORT=${LAGOON_CONFIG_SSH_PORT}\n" >> /kubectl-build-deploy/values.env
-fi
-
-echo -e "\
-LAGOON_ROUTE=${ROUTE}\n\
-LAGOON_ROUTES=${ROUTES}\n\
-LAGOON_AUTOGENERATED_ROUTES=${AUTOGENERATED_ROUTES}\n\
-" >> /kubectl-build-deploy/values.env
-
-# Generate a Config Map with project wide env variables
-kubectl -n ${NAMESPACE} create configmap lagoon-env -o yaml --dry-run=client --from-env-file=/kubectl-build-deploy/values.env | kubectl apply -n ${NAMESPACE} -f -
-
-# Add environment variables from lagoon API
-if [ ! -z "$LAGOON_PROJECT_VARIABLES" ]; then
-  HAS_PROJECT_RUNTIME_VARS=$(echo $LAGOON_PROJECT_VARIABLES | jq -r 'map( select(.scope == "runtime" or .scope == "global") )')
-
-  if [ ! "$HAS_PROJECT_RUNTIME_VARS" = "[]" ]; then
-    kubectl patch \
-      -n ${NAMESPACE} \
-      configmap lagoon-env \
-      -p "{\"data\":$(echo $LAGOON_PROJECT_VARIABLES | jq -r 'map( select(.scope == "runtime" or .scope == "global") ) | map( { (.name) : .value } ) | add | tostring')}"
-  fi
-fi
-if [ ! -z "$LAGOON_ENVIRONMENT_VARIABLES" ]; then
-  HAS_ENVIRONMENT_RUNTIME_VARS=$(echo $LAGOON_ENVIRONMENT_VARIABLES | jq -r 'map( select(.scope == "runtime" or .scope == "global") )')
-
-  if [ ! "$HAS_ENVIRONMENT_RUNTIME_VARS" = "[]" ]; then
-    kubectl patch \
-      -n ${NAMESPACE} \
-      configmap lagoon-env \
-      -p "{\"data\":$(echo $LAGOON_ENVIRONMENT_VARIABLES | jq -r 'map( select(.scope == "runtime" or .scope == "global") ) | map( { (.name) : .value } ) | add | tostring')}"
-  fi
-fi
-
-if [ "$BUILD_TYPE" == "pullrequest" ]; then
-  kubectl patch \
-    -n ${NAMESPACE} \
-    configmap lagoon-env \
-    -p "{\"data\":{\"LAGOON_PR_HEAD_BRANCH\":\"${PR_HEAD_BRANCH}\", \"LAGOON_PR_BASE_BRANCH\":\"${PR_BASE_BRANCH}\", \"LAGOON_PR_TITLE\":$(echo $PR_TITLE | jq -R)}}"
 fi
 
 # loop through created DBAAS templates
@@ -1294,6 +1230,12 @@ do
   esac
 done
 
+# Generate the lagoon-env configmap with the project wide env variables and the credentials of any dbaas consumers
+LAGOON_ENV_YAML_FOLDER="/kubectl-build-deploy/lagoon/lagoon-env"
+mkdir -p $LAGOON_ENV_YAML_FOLDER
+build-deploy-tool template lagoon-env --dbaas-credentials --saved-templates-path ${LAGOON_ENV_YAML_FOLDER}
+kubectl apply -n ${NAMESPACE} -f ${LAGOON_ENV_YAML_FOLDER}/lagoon-env.yaml
+
 currentStepEnd="$(date +"%Y-%m-%d %H:%M:%S")"
 patchBuildStep "${buildStartTime}" "${previousStepEnd}" "${currentStepEnd}" "${NAMESPACE}" "updateConfigmapComplete" "Update Configmap" "false"
 previousStepEnd=${currentStepEnd}
@@ -1303,7 +1245,7 @@ beginBuildStep "Image Push to Registry" "pushingImages"
 ### REDEPLOY DEPLOYMENTS IF CONFIG MAP CHANGES
 ##############################################
 
-CONFIG_MAP_SHA=$(kubectl -n ${NAMESPACE} get configmap lagoon-env -o yaml | shyaml get-value data | sha256sum | awk '{print $1}')
+CONFIG_MAP_SHA=$(build-deploy-tool identify configmap-sha --dbaas-credentials)
 export CONFIG_MAP_SHA
 # write the configmap to the values file so when we `exec-kubectl-resources-with-images.sh` the deployments will get the value of the config map
 # which will cause a change in the deployment and trigger a rollout if only the configmap has changed
@@ -1498,6 +1440,26 @@ if [ "$(ls -A $LAGOON_SERVICES_YAML_FOLDER/)" ]; then
   # cat $LAGOON_SERVICES_YAML_FOLDER/cronjobs.yaml
   if [ -n "$(ls -A $LAGOON_SERVICES_YAML_FOLDER/ 2>/dev/null)" ]; then
     find $LAGOON_SERVICES_YAML_FOLDER -type f -exec cat {} \;
+    # a statefulset with volume claim templates creates new empty volumes, refuse to apply it while the persistent volume claim
+    # of the existing deployment still holds the data, existing environments need to use `migrate` for the statefulsets feature flag
+    for STATEFULSET_FILE in $(find $LAGOON_SERVICES_YAML_FOLDER -type f -name 'statefulset-*.yaml'); do
+      STATEFULSET_NAME=$(basename ${STATEFULSET_FILE} .yaml)
+      STATEFULSET_NAME=${STATEFULSET_NAME#statefulset-}
+      if grep -q "volumeClaimTemplates:" ${STATEFULSET_FILE} && kubectl -n ${NAMESPACE} get pvc ${STATEFULSET_NAME} &> /dev/null; then
+        echo ">> The persistent volume claim '${STATEFULSET_NAME}' already exists, the statefulset would not use it. Set LAGOON_FEATURE_FLAG_STATEFULSETS to migrate for existing environments"
+        exit 1
+      fi
+    done
+    # a statefulset replaces the deployment of the same name, the deployment has to be removed first as when migrating
+    # they both mount the same persistent volume claim and the statefulset pods can't start while the deployment holds it
+    for STATEFULSET_FILE in $(find $LAGOON_SERVICES_YAML_FOLDER -type f -name 'statefulset-*.yaml'); do
+      STATEFULSET_NAME=$(basename ${STATEFULSET_FILE} .yaml)
+      STATEFULSET_NAME=${STATEFULSET_NAME#statefulset-}
+      if kubectl -n ${NAMESPACE} get deployment ${STATEFULSET_NAME} &> /dev/null; then
+        echo ">> Removing deployment '${STATEFULSET_NAME}', it is replaced by a statefulset"
+        kubectl -n ${NAMESPACE} delete deployment ${STATEFULSET_NAME} --cascade=foreground --wait=true
+      fi
+    done
     kubectl apply -n ${NAMESPACE} -f $LAGOON_SERVICES_YAML_FOLDER/
   fi
 fi
@@ -1595,7 +1557,7 @@ beginBuildStep "Post-Rollout Tasks" "runningPostRolloutTasks"
 
 # if we have LAGOON_POSTROLLOUT_DISABLED set, don't try to run any pre-rollout tasks
 if [ "${LAGOON_POSTROLLOUT_DISABLED}" != "true" ]; then
-  build-deploy-tool tasks post-rollout
+  build-deploy-tool tasks post-rollout --images /kubectl-build-deploy/images.yaml
 else
   echo "post-rollout tasks are currently disabled LAGOON_POSTROLLOUT_DISABLED is set to true"
   currentStepEnd="$(date +"%Y-%m-%d %H:%M:%S")"
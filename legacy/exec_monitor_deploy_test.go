@@ -0,0 +1,69 @@
+package legacy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// kubectlStub records the rollout status checks that the script runs, any other command returns no output
+const kubectlStub = `#!/bin/bash
+if [ "$1" == "rollout" ]; then
+  echo "$@" >> "${KUBECTL_LOG}"
+  exit 0
+fi
+sleep 0.1
+`
+
+func TestExecMonitorDeploy(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		want    string
+	}{
+		{
+			name:    "test1 deployment service",
+			service: "redis-6",
+			want:    "rollout -n example-project-main status deployment redis-6 --watch --timeout=1200s",
+		},
+		{
+			name:    "test2 statefulset service",
+			service: "mariadb-10-5",
+			want:    "rollout -n example-project-main status statefulset mariadb-10-5 --watch --timeout=1200s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			kubectlLog := filepath.Join(dir, "kubectl.log")
+			if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(kubectlStub), 0755); err != nil {
+				t.Fatalf("couldn't write kubectl stub: %v", err)
+			}
+			cmd := exec.Command("bash", "-c", ". scripts/exec-monitor-deploy.sh")
+			cmd.Env = append(os.Environ(),
+				"PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"),
+				"KUBECTL_LOG="+kubectlLog,
+				"NAMESPACE=example-project-main",
+				"SERVICE_NAME="+tt.service,
+				"LAGOON_SERVICES_YAML_FOLDER=testdata/service-deployments",
+			)
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+			err := cmd.Run()
+			// the log streaming runs in the background until the build ends, so it is stopped with the rest of the process group
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			if err != nil {
+				t.Errorf("exec-monitor-deploy.sh error = %v", err)
+			}
+			got, err := os.ReadFile(kubectlLog)
+			if err != nil {
+				t.Fatalf("couldn't read kubectl log: %v", err)
+			}
+			if strings.TrimSpace(string(got)) != tt.want {
+				t.Errorf("exec-monitor-deploy.sh ran %q, want %q", strings.TrimSpace(string(got)), tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,6 @@
 #!/bin/bash
 
-# while the rollout of a new deployment is running we gather the logs of the new generated pods and save them in a known location
+# while the rollout of a new deployment or statefulset is running we gather the logs of the new generated pods and save them in a known location
 # in case this rollout fails, we show the logs of the new containers to the user as they might contain information about why
 # the rollout has failed
 stream_logs_deployment() {
@@ -33,11 +33,17 @@ set +x # reduce noise in build logs
 stream_logs_deployment &
 STREAM_LOGS_PID=$!
 
+# services that are templated as a statefulset have no deployment, the deployment is removed when the statefulset replaces it
+ROLLOUT_KIND=deployment
+if [ -f "${LAGOON_SERVICES_YAML_FOLDER}/statefulset-${SERVICE_NAME}.yaml" ]; then
+  ROLLOUT_KIND=statefulset
+fi
+
 ret=0
 # default progressDeadlineSeconds is 600, doubling that here for a timeout on the status check for 1200s (20m) as a fallback for exceeding the progressdeadline
 # when there may be another issue with the rollout failing, the progresdeadline doesn't always work
 # (eg, existing pod in previous replicaset fails to terminate properly)
-kubectl rollout -n ${NAMESPACE} status deployment ${SERVICE_NAME} --watch --timeout=1200s || ret=$?
+kubectl rollout -n ${NAMESPACE} status ${ROLLOUT_KIND} ${SERVICE_NAME} --watch --timeout=1200s || ret=$?
 
 if [[ $ret -ne 0 ]]; then
   # stop all running stream logs